@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/pitabwire/frame"
 	"github.com/pitabwire/frame/config"
@@ -15,6 +16,7 @@ import (
 	"github.com/voicetyped/voicetyped/pkg/dialog"
 	"github.com/voicetyped/voicetyped/pkg/events"
 	"github.com/voicetyped/voicetyped/pkg/hooks"
+	"github.com/voicetyped/voicetyped/pkg/llm"
 )
 
 func main() {
@@ -32,6 +34,7 @@ func main() {
 		frame.WithConfig(&cfg),
 		frame.WithName("voicetyped-dialog"),
 		frame.WithRegisterServerOauth2Client(),
+		frame.WithDatastore(),
 		frame.WithRegisterPublisher(eventRef, eventURL),
 	)
 	defer srv.Stop(ctx)
@@ -45,13 +48,48 @@ func main() {
 
 	pub := events.NewPublisher(srv.QueueManager(), "dialog", eventRef)
 	hookExec := hooks.NewExecutor(pub)
-
-	loader := dialog.NewLoader(cfg.DialogDir)
+	hookExec.SetLimits(hooks.Limits{
+		FailureThreshold:     cfg.HookCBFailThreshold,
+		ResetTimeout:         time.Duration(cfg.HookCBResetTimeoutSec) * time.Second,
+		MaxConcurrentPerHost: cfg.HookMaxConcurrentPerHost,
+	})
+
+	repo := dialog.NewRepository(srv.DatastoreManager().GetPool(ctx, "__default__pool_name__"))
+	loaderOpts := []dialog.LoaderOption{dialog.ReloadEvents(pub), dialog.Definitions(repo)}
+	if cfg.DialogStrict {
+		loaderOpts = append(loaderOpts, dialog.StrictMode())
+	}
+	loader := dialog.NewLoader(cfg.DialogDir, loaderOpts...)
 	if _, err := loader.LoadAll(); err != nil {
 		log.Printf("warning: loading dialogs: %v", err)
 	}
+	if err := loader.LoadStored(ctx); err != nil {
+		log.Printf("warning: loading stored dialogs: %v", err)
+	}
+	go loader.WatchStored(ctx, time.Duration(cfg.DialogStoreRefreshSec)*time.Second)
+	go func() {
+		if err := loader.WatchAndReload(ctx); err != nil {
+			log.Printf("warning: watching dialogs: %v", err)
+		}
+	}()
+
+	llmClient := llm.NewClient(cfg.OpenAIBaseURL, cfg.LLMKey(), llm.WithModel(cfg.LLMModel),
+		llm.WithHTTPClient(&http.Client{Timeout: time.Duration(cfg.LLMTimeoutSec) * time.Second}))
+	engineOpts := []dialog.EngineOption{dialog.UseLLM(llmClient)}
+	if cfg.HooksFile != "" {
+		registry, err := hooks.LoadRegistry(cfg.HooksFile)
+		if err != nil {
+			log.Fatalf("loading hooks: %v", err)
+		}
+		engineOpts = append(engineOpts, dialog.UseHookRegistry(registry))
+	}
 
-	handler := dialoghandler.NewDialogHandler(loader, hookExec, pub, pool)
+	handler := dialoghandler.NewDialogHandler(loader, hookExec, pub, repo, pool, engineOpts...)
+	if n, err := handler.Resume(ctx); err != nil {
+		log.Printf("warning: resuming dialog sessions: %v", err)
+	} else if n > 0 {
+		log.Printf("resumed %d dialog sessions", n)
+	}
 
 	mux := http.NewServeMux()
 	opts, err := connectutil.AuthenticatedOptions(ctx, authenticator)
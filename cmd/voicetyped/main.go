@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/pitabwire/frame"
 	"github.com/pitabwire/frame/config"
@@ -25,6 +26,7 @@ import (
 	"github.com/voicetyped/voicetyped/pkg/dialog"
 	"github.com/voicetyped/voicetyped/pkg/events"
 	"github.com/voicetyped/voicetyped/pkg/hooks"
+	"github.com/voicetyped/voicetyped/pkg/llm"
 	"github.com/voicetyped/voicetyped/pkg/webhook"
 	webhookapi "github.com/voicetyped/voicetyped/pkg/webhook/api"
 
@@ -99,16 +101,49 @@ func main() {
 
 	// --- Dialog Service ---
 	hookExec := hooks.NewExecutor(pub)
-	loader := dialog.NewLoader(cfg.DialogDir)
+	hookExec.SetLimits(hooks.Limits{
+		FailureThreshold:     cfg.HookCBFailThreshold,
+		ResetTimeout:         time.Duration(cfg.HookCBResetTimeoutSec) * time.Second,
+		MaxConcurrentPerHost: cfg.HookMaxConcurrentPerHost,
+	})
+	dbPool := srv.DatastoreManager().GetPool(ctx, "__default__pool_name__")
+	dialogRepo := dialog.NewRepository(dbPool)
+	loaderOpts := []dialog.LoaderOption{dialog.ReloadEvents(pub), dialog.Definitions(dialogRepo)}
+	if cfg.DialogStrict {
+		loaderOpts = append(loaderOpts, dialog.StrictMode())
+	}
+	loader := dialog.NewLoader(cfg.DialogDir, loaderOpts...)
 	if _, err := loader.LoadAll(); err != nil {
 		log.Printf("warning: loading dialogs: %v", err)
 	}
-	dialogHdlr := dialoghandler.NewDialogHandler(loader, hookExec, pub, pool)
+	if err := loader.LoadStored(ctx); err != nil {
+		log.Printf("warning: loading stored dialogs: %v", err)
+	}
+	go loader.WatchStored(ctx, time.Duration(cfg.DialogStoreRefreshSec)*time.Second)
+	go func() {
+		if err := loader.WatchAndReload(ctx); err != nil {
+			log.Printf("warning: watching dialogs: %v", err)
+		}
+	}()
+	llmClient := llm.NewClient(cfg.OpenAIBaseURL, cfg.LLMKey(), llm.WithModel(cfg.LLMModel),
+		llm.WithHTTPClient(&http.Client{Timeout: time.Duration(cfg.LLMTimeoutSec) * time.Second}))
+	engineOpts := []dialog.EngineOption{dialog.UseLLM(llmClient)}
+	if cfg.HooksFile != "" {
+		registry, err := hooks.LoadRegistry(cfg.HooksFile)
+		if err != nil {
+			log.Fatalf("loading hooks: %v", err)
+		}
+		engineOpts = append(engineOpts, dialog.UseHookRegistry(registry))
+	}
+	dialogHdlr := dialoghandler.NewDialogHandler(loader, hookExec, pub, dialogRepo, pool, engineOpts...)
+	if n, err := dialogHdlr.Resume(ctx); err != nil {
+		log.Printf("warning: resuming dialog sessions: %v", err)
+	} else if n > 0 {
+		log.Printf("resumed %d dialog sessions", n)
+	}
 
 	// --- Integration Service ---
-	whRepo := webhook.NewRepository(
-		srv.DatastoreManager().GetPool(ctx, "__default__pool_name__"),
-	)
+	whRepo := webhook.NewRepository(dbPool)
 	whDeliverer := webhook.NewDeliverer(whRepo, webhook.DelivererConfig{
 		MaxRetries:        cfg.WebhookMaxRetries,
 		TimeoutSec:        cfg.WebhookTimeoutSec,
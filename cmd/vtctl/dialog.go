@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/voicetyped/voicetyped/pkg/dialog"
+	"github.com/voicetyped/voicetyped/pkg/hooks"
+	"github.com/voicetyped/voicetyped/pkg/llm"
+	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
+)
+
+// dialogCmd runs a dialog subcommand and returns the process exit code.
+func dialogCmd(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "validate":
+		return dialogValidate(args[1:])
+	case "graph":
+		return dialogGraph(args[1:])
+	case "simulate":
+		return dialogSimulate(args[1:])
+	case "test":
+		return dialogTest(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "vtctl dialog: unknown subcommand %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+// dialogValidate lints a dialog directory and prints every diagnostic. It
+// exits 1 if the dialog service would reject any file.
+func dialogValidate(args []string) int {
+	fs := flag.NewFlagSet("dialog validate", flag.ExitOnError)
+	strict := fs.Bool("strict", false, "treat warnings as errors, like DIALOG_STRICT")
+	fs.Parse(args)
+
+	dir := "dialogs"
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	var opts []dialog.LoaderOption
+	if *strict {
+		opts = append(opts, dialog.StrictMode())
+	}
+	diags, err := dialog.NewLoader(dir, opts...).Lint()
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	var analysisErr *dialog.AnalysisError
+	switch {
+	case errors.As(err, &analysisErr):
+		fmt.Printf("%d errors, %d warnings\n", len(diags.Errors()), len(diags.Warnings()))
+		return 1
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "vtctl dialog validate: %v\n", err)
+		return 1
+	}
+	fmt.Printf("ok (%d warnings)\n", len(diags.Warnings()))
+	return 0
+}
+
+// dialogGraph prints a dialog's state graph as Graphviz DOT or Mermaid.
+func dialogGraph(args []string) int {
+	fs := flag.NewFlagSet("dialog graph", flag.ExitOnError)
+	format := fs.String("format", "dot", "output format: dot or mermaid")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: vtctl dialog graph [-format dot|mermaid] <dialog.yaml>")
+		return 2
+	}
+	d, _, err := dialog.AnalyzeFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "vtctl dialog graph: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "dot":
+		err = dialog.WriteDOT(os.Stdout, d)
+	case "mermaid":
+		err = dialog.WriteMermaid(os.Stdout, d)
+	default:
+		fmt.Fprintf(os.Stderr, "vtctl dialog graph: unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "vtctl dialog graph: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// dialogSimulate runs an interactive session against a dialog file.
+func dialogSimulate(args []string) int {
+	fs := flag.NewFlagSet("dialog simulate", flag.ExitOnError)
+	liveHooks := fs.Bool("live-hooks", false, "call hook endpoints instead of answering them with an empty response")
+	allowPrivate := fs.Bool("allow-private", false, "allow live hooks to private and loopback addresses")
+	autoTTS := fs.Bool("auto-tts", true, "send tts_complete after each prompt, as the orchestrator does")
+	llmURL := fs.String("llm-url", "", "OpenAI-compatible API base URL for llm_turn actions, e.g. http://localhost:11434/v1 (API key from LLM_API_KEY)")
+	llmModel := fs.String("llm-model", "gpt-4o-mini", "model for llm_turn actions that name none")
+	hooksFile := fs.String("hooks", "", "hook registry file for call_hook actions naming hooks the dialog does not define, like HOOKS_FILE")
+	var vars varFlags
+	fs.Var(&vars, "var", "initial session variable as name=value (repeatable)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: vtctl dialog simulate [flags] <dialog.yaml>")
+		return 2
+	}
+	path := fs.Arg(0)
+	d, diags, err := dialog.AnalyzeFile(path)
+	if err == nil {
+		err = diags.Err(false)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "vtctl dialog simulate: %v\n", err)
+		return 1
+	}
+
+	sim := &simulator{
+		sm:      dialog.NewStateMachine(d),
+		out:     os.Stdout,
+		autoTTS: *autoTTS,
+		vars:    vars,
+	}
+	if *liveHooks {
+		var opts []urlvalidation.Option
+		if *allowPrivate {
+			opts = append(opts, urlvalidation.AllowPrivateIPs())
+		}
+		sim.hooks = hooks.NewExecutor(nil, opts...)
+	} else {
+		sim.hooks = printHooks{out: os.Stdout}
+	}
+	if *llmURL != "" {
+		sim.llm = llm.NewClient(*llmURL, os.Getenv("LLM_API_KEY"), llm.WithModel(*llmModel))
+	}
+	if *hooksFile != "" {
+		if sim.registry, err = hooks.LoadRegistry(*hooksFile); err != nil {
+			fmt.Fprintf(os.Stderr, "vtctl dialog simulate: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Printf("Simulating %s (%s). Type speech, #digits for DTMF, or /help.\n", d.Name, filepath.Base(path))
+	if err := sim.run(context.Background(), os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "vtctl dialog simulate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// varFlags collects repeated -var name=value flags.
+type varFlags map[string]string
+
+func (v *varFlags) String() string {
+	return fmt.Sprint(map[string]string(*v))
+}
+
+func (v *varFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	if *v == nil {
+		*v = make(varFlags)
+	}
+	(*v)[name] = value
+	return nil
+}
+
+// dialogTest runs scenario files and reports each scenario. It exits 1 if any
+// scenario fails, so CI can use it as a regression test for IVR flows.
+func dialogTest(args []string) int {
+	fs := flag.NewFlagSet("dialog test", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "list passing scenarios too")
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"dialogs"}
+	}
+	files, err := dialog.FindScenarioFiles(paths...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "vtctl dialog test: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "vtctl dialog test: no scenario files in %v\n", paths)
+		return 1
+	}
+
+	ctx := context.Background()
+	failed := 0
+	for _, file := range files {
+		results, err := dialog.RunScenarioFile(ctx, file)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", file, err)
+			failed++
+			continue
+		}
+		for _, res := range results {
+			if res.Passed() {
+				if *verbose {
+					fmt.Printf("ok   %s: %s\n", file, res.Name)
+				}
+				continue
+			}
+			failed++
+			fmt.Printf("FAIL %s: %s\n", file, res.Name)
+			for _, f := range res.Failures {
+				fmt.Printf("    %s\n", f)
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d failed\n", failed)
+		return 1
+	}
+	fmt.Println("ok")
+	return 0
+}
@@ -0,0 +1,35 @@
+// Command vtctl is the voicetyped developer tool. It runs locally without any
+// of the services, so dialog authors can check their flows before deploying.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: vtctl <command> [arguments]
+
+Commands:
+  dialog validate [-strict] [dir]              lint a dialog directory (default ./dialogs)
+  dialog graph [-format dot|mermaid] <file>    print a dialog's state graph
+  dialog simulate [flags] <file>               talk to a dialog in the terminal
+  dialog test [-v] [paths...]                  run dialog scenario files (default ./dialogs)
+
+Run "vtctl dialog <subcommand> -h" for its flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "dialog":
+		os.Exit(dialogCmd(os.Args[2:]))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "vtctl: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/voicetyped/voicetyped/pkg/dialog"
+	"github.com/voicetyped/voicetyped/pkg/hooks"
+)
+
+const simulateHelp = `  <text>          say <text> (final speech result)
+  #<digits>       press DTMF digits, e.g. #12#
+  /timeout        let the current state time out
+  /event <type>   send any other event, e.g. /event tts_complete
+  /vars           show session variables
+  /hangup         hang up, running the dialog's on_hangup actions
+  /quit           end the simulation
+`
+
+// simulator runs one interactive dialog session and prints what the engine
+// asks the caller's side to do.
+type simulator struct {
+	sm       *dialog.StateMachine
+	hooks    dialog.HookExecutor
+	registry hooks.Registry
+	llm      dialog.LLMClient
+	out      io.Writer
+	autoTTS  bool
+	vars     map[string]string
+
+	engine  *dialog.Engine
+	session *dialog.Session
+	// reported is how many history records have been printed.
+	reported int
+}
+
+func (s *simulator) run(ctx context.Context, in io.Reader) error {
+	d := s.sm.Dialog()
+	opts := []dialog.EngineOption{dialog.UseHooks(s.hooks), dialog.UseHookRegistry(s.registry)}
+	if s.llm != nil {
+		opts = append(opts, dialog.UseLLM(s.llm))
+	}
+	s.engine = dialog.NewEngine(map[string]*dialog.StateMachine{d.Name: s.sm}, nil, nil, opts...)
+	s.session = dialog.NewSession("simulate", d.Name, d.InitialState)
+	s.session.Pin(s.sm)
+	if err := s.session.InitVariables(s.vars); err != nil {
+		return err
+	}
+
+	res, err := s.engine.Start(ctx, s.session)
+	if err != nil {
+		return err
+	}
+	if done, err := s.report(ctx, res); done || err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(s.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+
+		var ev dialog.Event
+		switch {
+		case line == "":
+			continue
+		case line == "/quit":
+			return nil
+		case line == "/hangup":
+			return s.hangUp(ctx)
+		case line == "/help":
+			fmt.Fprint(s.out, simulateHelp)
+			continue
+		case line == "/vars":
+			s.printVars()
+			continue
+		case line == "/timeout":
+			ev = dialog.Event{Type: dialog.EventTimeout}
+		case strings.HasPrefix(line, "/event "):
+			ev = dialog.Event{Type: strings.TrimSpace(strings.TrimPrefix(line, "/event "))}
+		case strings.HasPrefix(line, "/"):
+			fmt.Fprintf(s.out, "unknown command %s\n%s", line, simulateHelp)
+			continue
+		case strings.HasPrefix(line, "#"):
+			ev = dialog.Event{Type: dialog.EventDTMF, Data: strings.TrimPrefix(line, "#")}
+		default:
+			ev = dialog.Event{Type: dialog.EventSpeech, Data: line}
+		}
+
+		res, err := s.engine.HandleEvent(ctx, s.session, ev)
+		if err != nil {
+			return err
+		}
+		if done, err := s.report(ctx, res); done || err != nil {
+			return err
+		}
+	}
+}
+
+// report prints a step's transitions and directives and, with auto-tts, keeps
+// sending tts_complete while prompts are played. It returns true once the
+// dialog has ended, after running its on_hangup actions.
+func (s *simulator) report(ctx context.Context, res *dialog.StepResult) (bool, error) {
+	for {
+		history := s.session.CopyHistory()
+		for _, rec := range history[min(s.reported, len(history)):] {
+			fmt.Fprintf(s.out, "  [%s -> %s on %s]\n", rec.FromState, rec.ToState, rec.Trigger)
+		}
+		s.reported = len(history)
+		played, hangup := false, false
+		for _, a := range res.Directives {
+			fmt.Fprintf(s.out, "  %s\n", formatDirective(a))
+			switch a.Type {
+			case "play_tts":
+				played = played || a.Params["text"] != ""
+			case "hangup":
+				hangup = true
+			}
+		}
+		if res.Terminal || hangup {
+			fmt.Fprintf(s.out, "  dialog ended in %s\n", res.CurrentState)
+			return true, s.hangUp(ctx)
+		}
+		if !s.autoTTS || !played {
+			s.printWaiting()
+			return false, nil
+		}
+
+		fmt.Fprintln(s.out, "  (tts_complete)")
+		var err error
+		res, err = s.engine.HandleEvent(ctx, s.session, dialog.Event{Type: dialog.EventTTSComplete})
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// hangUp ends the session and prints the directives of its on_hangup actions.
+func (s *simulator) hangUp(ctx context.Context) error {
+	res, err := s.engine.End(ctx, s.session)
+	if err != nil {
+		return err
+	}
+	for _, a := range res.Directives {
+		fmt.Fprintf(s.out, "  on_hangup: %s\n", formatDirective(a))
+	}
+	return nil
+}
+
+func (s *simulator) printWaiting() {
+	name := s.session.GetCurrentState()
+	state, _ := s.sm.GetState(name)
+	if state.Timeout != "" && state.TimeoutNext != "" {
+		fmt.Fprintf(s.out, "  in %s, times out after %s to %s\n", name, state.Timeout, state.TimeoutNext)
+		return
+	}
+	fmt.Fprintf(s.out, "  in %s\n", name)
+}
+
+func (s *simulator) printVars() {
+	vars := s.session.CopyVariables()
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(s.out, "  %s = %q\n", k, vars[k])
+	}
+}
+
+func formatDirective(a dialog.Action) string {
+	if a.Type == "play_tts" {
+		return fmt.Sprintf("play_tts: %q", a.Params["text"])
+	}
+	keys := make([]string, 0, len(a.Params))
+	for k := range a.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, fmt.Sprintf("%s=%q", k, a.Params[k]))
+	}
+	return strings.TrimSpace(a.Type + " " + strings.Join(params, " "))
+}
+
+// printHooks answers every hook call with an empty response, raising
+// hook_result, and prints the request that would have been sent. A call with
+// a response_map finds none of its fields and raises hook_error.
+type printHooks struct {
+	out io.Writer
+}
+
+func (h printHooks) Execute(_ context.Context, cfg hooks.HookConfig, req hooks.HookRequest) (*hooks.HookResponse, error) {
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	fmt.Fprintf(h.out, "  call_hook %s %s (state %s, not called; use -live-hooks)\n", method, cfg.URL, req.State)
+	return &hooks.HookResponse{}, nil
+}
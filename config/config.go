@@ -50,7 +50,23 @@ type SpeechConfig struct {
 // DialogConfig holds configuration for the dialog service.
 type DialogConfig struct {
 	config.ConfigurationDefault
-	DialogDir string `envDefault:"./dialogs" env:"DIALOG_DIR"`
+	DialogDir             string `envDefault:"./dialogs" env:"DIALOG_DIR"`
+	DialogStrict          bool   `envDefault:"false"     env:"DIALOG_STRICT"`
+	DialogStoreRefreshSec int    `envDefault:"30"        env:"DIALOG_STORE_REFRESH_SEC"`
+	// OpenAI-compatible chat completions endpoint for llm_turn actions,
+	// shared with the speech service's OpenAI-compatible providers. Its key
+	// is LLM_API_KEY, or OPENAI_API_KEY when that is empty; see LLMKey.
+	OpenAIBaseURL string `envDefault:"https://api.openai.com/v1" env:"OPENAI_BASE_URL"`
+	OpenAIAPIKey  string `envDefault:""                          env:"OPENAI_API_KEY"`
+	LLMAPIKey     string `envDefault:""                          env:"LLM_API_KEY"`
+	LLMModel      string `envDefault:"gpt-4o-mini"               env:"LLM_MODEL"`
+	LLMTimeoutSec int    `envDefault:"30"                        env:"LLM_TIMEOUT_SEC"`
+	// YAML file of named hook definitions shared by all dialogs.
+	HooksFile string `envDefault:""                          env:"HOOKS_FILE"`
+	// Circuit breaking per hook URL and concurrency limit per hook host.
+	HookCBFailThreshold      int `envDefault:"5"  env:"HOOK_CB_FAILURE_THRESHOLD"`
+	HookCBResetTimeoutSec    int `envDefault:"30" env:"HOOK_CB_RESET_TIMEOUT_SEC"`
+	HookMaxConcurrentPerHost int `envDefault:"20" env:"HOOK_MAX_CONCURRENT_PER_HOST"`
 }
 
 // IntegrationConfig holds configuration for the integration service.
@@ -99,8 +115,18 @@ type MonolithConfig struct {
 	OpenAIBaseURL     string `envDefault:"https://api.openai.com/v1"        env:"OPENAI_BASE_URL"`
 
 	// Dialog
-	DialogDir     string `envDefault:"./dialogs" env:"DIALOG_DIR"`
-	DefaultDialog string `envDefault:"example"   env:"DEFAULT_DIALOG"`
+	DialogDir             string `envDefault:"./dialogs" env:"DIALOG_DIR"`
+	DialogStrict          bool   `envDefault:"false"     env:"DIALOG_STRICT"`
+	DialogStoreRefreshSec int    `envDefault:"30"        env:"DIALOG_STORE_REFRESH_SEC"`
+	DefaultDialog         string `envDefault:"example"   env:"DEFAULT_DIALOG"`
+	// Key for llm_turn requests to OPENAI_BASE_URL; see LLMKey.
+	LLMAPIKey                string `envDefault:""                          env:"LLM_API_KEY"`
+	LLMModel                 string `envDefault:"gpt-4o-mini"               env:"LLM_MODEL"`
+	LLMTimeoutSec            int    `envDefault:"30"                        env:"LLM_TIMEOUT_SEC"`
+	HooksFile                string `envDefault:""                          env:"HOOKS_FILE"`
+	HookCBFailThreshold      int    `envDefault:"5"  env:"HOOK_CB_FAILURE_THRESHOLD"`
+	HookCBResetTimeoutSec    int    `envDefault:"30" env:"HOOK_CB_RESET_TIMEOUT_SEC"`
+	HookMaxConcurrentPerHost int    `envDefault:"20" env:"HOOK_MAX_CONCURRENT_PER_HOST"`
 
 	// Webhooks
 	WebhookWorkers    int `envDefault:"16"  env:"WEBHOOK_WORKERS"`
@@ -118,6 +144,25 @@ type MonolithConfig struct {
 	IntegrationServiceURL string `envDefault:"" env:"INTEGRATION_SERVICE_URL"`
 }
 
+// LLMKey is the key for llm_turn requests to OPENAI_BASE_URL: LLM_API_KEY,
+// or OPENAI_API_KEY when that is empty.
+func (c *DialogConfig) LLMKey() string {
+	return llmKey(c.LLMAPIKey, c.OpenAIAPIKey)
+}
+
+// LLMKey is the key for llm_turn requests to OPENAI_BASE_URL: LLM_API_KEY,
+// or OPENAI_API_KEY when that is empty.
+func (c *MonolithConfig) LLMKey() string {
+	return llmKey(c.LLMAPIKey, c.OpenAIAPIKey)
+}
+
+func llmKey(llmAPIKey, openAIAPIKey string) string {
+	if llmAPIKey != "" {
+		return llmAPIKey
+	}
+	return openAIAPIKey
+}
+
 // WebRTCConfig builds a webrtc.Configuration from the STUN/TURN settings.
 func (c *MonolithConfig) WebRTCConfig() webrtc.Configuration {
 	return buildWebRTCConfig(c.STUNServers, c.TURNServers, c.TURNUsername, c.TURNPassword)
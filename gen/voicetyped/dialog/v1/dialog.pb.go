@@ -22,13 +22,21 @@ const (
 )
 
 type StartDialogRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	DialogName    string                 `protobuf:"bytes,2,opt,name=dialog_name,json=dialogName,proto3" json:"dialog_name,omitempty"`
-	InitialState  string                 `protobuf:"bytes,3,opt,name=initial_state,json=initialState,proto3" json:"initial_state,omitempty"`
-	Variables     map[string]string      `protobuf:"bytes,4,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	SessionId    string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	DialogName   string                 `protobuf:"bytes,2,opt,name=dialog_name,json=dialogName,proto3" json:"dialog_name,omitempty"`
+	InitialState string                 `protobuf:"bytes,3,opt,name=initial_state,json=initialState,proto3" json:"initial_state,omitempty"`
+	Variables    map[string]string      `protobuf:"bytes,4,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Room and peer the session belongs to, recorded with the persisted session.
+	RoomId string `protobuf:"bytes,5,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	PeerId string `protobuf:"bytes,6,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	// Version of the dialog to run. Empty selects by version_weights, or the
+	// highest loaded version when no weights are given.
+	DialogVersion string `protobuf:"bytes,7,opt,name=dialog_version,json=dialogVersion,proto3" json:"dialog_version,omitempty"`
+	// Weighted split between loaded versions, e.g. {"1.0": 90, "2.0": 10}.
+	VersionWeights map[string]uint32 `protobuf:"bytes,8,rep,name=version_weights,json=versionWeights,proto3" json:"version_weights,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *StartDialogRequest) Reset() {
@@ -89,11 +97,41 @@ func (x *StartDialogRequest) GetVariables() map[string]string {
 	return nil
 }
 
+func (x *StartDialogRequest) GetRoomId() string {
+	if x != nil {
+		return x.RoomId
+	}
+	return ""
+}
+
+func (x *StartDialogRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *StartDialogRequest) GetDialogVersion() string {
+	if x != nil {
+		return x.DialogVersion
+	}
+	return ""
+}
+
+func (x *StartDialogRequest) GetVersionWeights() map[string]uint32 {
+	if x != nil {
+		return x.VersionWeights
+	}
+	return nil
+}
+
 type StartDialogResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	CurrentState  string                 `protobuf:"bytes,2,opt,name=current_state,json=currentState,proto3" json:"current_state,omitempty"`
-	Actions       []*ActionDirective     `protobuf:"bytes,3,rep,name=actions,proto3" json:"actions,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	SessionId    string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	CurrentState string                 `protobuf:"bytes,2,opt,name=current_state,json=currentState,proto3" json:"current_state,omitempty"`
+	Actions      []*ActionDirective     `protobuf:"bytes,3,rep,name=actions,proto3" json:"actions,omitempty"`
+	// Version of the dialog the session is pinned to.
+	DialogVersion string `protobuf:"bytes,4,opt,name=dialog_version,json=dialogVersion,proto3" json:"dialog_version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -149,11 +187,23 @@ func (x *StartDialogResponse) GetActions() []*ActionDirective {
 	return nil
 }
 
+func (x *StartDialogResponse) GetDialogVersion() string {
+	if x != nil {
+		return x.DialogVersion
+	}
+	return ""
+}
+
 type SendEventRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
-	EventData     string                 `protobuf:"bytes,3,opt,name=event_data,json=eventData,proto3" json:"event_data,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// "speech", "dtmf", or any other event type the dialog has a transition
+	// for, e.g. "tts_complete" once the orchestrator finishes playback.
+	EventType string `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	// Speech text, or one or more DTMF keys (0-9, *, #, A-D) for "dtmf".
+	EventData string `protobuf:"bytes,3,opt,name=event_data,json=eventData,proto3" json:"event_data,omitempty"`
+	// Recognition confidence of a speech event, recorded in the transcript.
+	Confidence    float32 `protobuf:"fixed32,4,opt,name=confidence,proto3" json:"confidence,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -209,12 +259,16 @@ func (x *SendEventRequest) GetEventData() string {
 	return ""
 }
 
+func (x *SendEventRequest) GetConfidence() float32 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+// SendEventResponse acknowledges that the event was queued.
 type SendEventResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	PreviousState string                 `protobuf:"bytes,1,opt,name=previous_state,json=previousState,proto3" json:"previous_state,omitempty"`
-	CurrentState  string                 `protobuf:"bytes,2,opt,name=current_state,json=currentState,proto3" json:"current_state,omitempty"`
-	Terminal      bool                   `protobuf:"varint,3,opt,name=terminal,proto3" json:"terminal,omitempty"`
-	Actions       []*ActionDirective     `protobuf:"bytes,4,rep,name=actions,proto3" json:"actions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -249,28 +303,113 @@ func (*SendEventResponse) Descriptor() ([]byte, []int) {
 	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *SendEventResponse) GetPreviousState() string {
+type StreamActionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamActionsRequest) Reset() {
+	*x = StreamActionsRequest{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamActionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamActionsRequest) ProtoMessage() {}
+
+func (x *StreamActionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamActionsRequest.ProtoReflect.Descriptor instead.
+func (*StreamActionsRequest) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamActionsRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+// StreamActionsResponse reports one processed event.
+type StreamActionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PreviousState string                 `protobuf:"bytes,1,opt,name=previous_state,json=previousState,proto3" json:"previous_state,omitempty"`
+	CurrentState  string                 `protobuf:"bytes,2,opt,name=current_state,json=currentState,proto3" json:"current_state,omitempty"`
+	Terminal      bool                   `protobuf:"varint,3,opt,name=terminal,proto3" json:"terminal,omitempty"`
+	Actions       []*ActionDirective     `protobuf:"bytes,4,rep,name=actions,proto3" json:"actions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamActionsResponse) Reset() {
+	*x = StreamActionsResponse{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamActionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamActionsResponse) ProtoMessage() {}
+
+func (x *StreamActionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamActionsResponse.ProtoReflect.Descriptor instead.
+func (*StreamActionsResponse) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StreamActionsResponse) GetPreviousState() string {
 	if x != nil {
 		return x.PreviousState
 	}
 	return ""
 }
 
-func (x *SendEventResponse) GetCurrentState() string {
+func (x *StreamActionsResponse) GetCurrentState() string {
 	if x != nil {
 		return x.CurrentState
 	}
 	return ""
 }
 
-func (x *SendEventResponse) GetTerminal() bool {
+func (x *StreamActionsResponse) GetTerminal() bool {
 	if x != nil {
 		return x.Terminal
 	}
 	return false
 }
 
-func (x *SendEventResponse) GetActions() []*ActionDirective {
+func (x *StreamActionsResponse) GetActions() []*ActionDirective {
 	if x != nil {
 		return x.Actions
 	}
@@ -286,7 +425,7 @@ type GetSessionRequest struct {
 
 func (x *GetSessionRequest) Reset() {
 	*x = GetSessionRequest{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[4]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -298,7 +437,7 @@ func (x *GetSessionRequest) String() string {
 func (*GetSessionRequest) ProtoMessage() {}
 
 func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[4]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -311,7 +450,7 @@ func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSessionRequest.ProtoReflect.Descriptor instead.
 func (*GetSessionRequest) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{4}
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetSessionRequest) GetSessionId() string {
@@ -328,13 +467,16 @@ type GetSessionResponse struct {
 	CurrentState  string                 `protobuf:"bytes,3,opt,name=current_state,json=currentState,proto3" json:"current_state,omitempty"`
 	Variables     map[string]string      `protobuf:"bytes,4,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	History       []*StateRecord         `protobuf:"bytes,5,rep,name=history,proto3" json:"history,omitempty"`
+	DialogVersion string                 `protobuf:"bytes,6,opt,name=dialog_version,json=dialogVersion,proto3" json:"dialog_version,omitempty"`
+	// Caller utterances, prompts, DTMF keys and hook calls, oldest first.
+	Transcript    []*TranscriptTurn `protobuf:"bytes,7,rep,name=transcript,proto3" json:"transcript,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetSessionResponse) Reset() {
 	*x = GetSessionResponse{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[5]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -346,7 +488,7 @@ func (x *GetSessionResponse) String() string {
 func (*GetSessionResponse) ProtoMessage() {}
 
 func (x *GetSessionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[5]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -359,7 +501,7 @@ func (x *GetSessionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSessionResponse.ProtoReflect.Descriptor instead.
 func (*GetSessionResponse) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{5}
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetSessionResponse) GetSessionId() string {
@@ -397,6 +539,20 @@ func (x *GetSessionResponse) GetHistory() []*StateRecord {
 	return nil
 }
 
+func (x *GetSessionResponse) GetDialogVersion() string {
+	if x != nil {
+		return x.DialogVersion
+	}
+	return ""
+}
+
+func (x *GetSessionResponse) GetTranscript() []*TranscriptTurn {
+	if x != nil {
+		return x.Transcript
+	}
+	return nil
+}
+
 type EndDialogRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
@@ -406,7 +562,7 @@ type EndDialogRequest struct {
 
 func (x *EndDialogRequest) Reset() {
 	*x = EndDialogRequest{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[6]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -418,7 +574,7 @@ func (x *EndDialogRequest) String() string {
 func (*EndDialogRequest) ProtoMessage() {}
 
 func (x *EndDialogRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[6]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -431,7 +587,7 @@ func (x *EndDialogRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndDialogRequest.ProtoReflect.Descriptor instead.
 func (*EndDialogRequest) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{6}
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *EndDialogRequest) GetSessionId() string {
@@ -449,7 +605,7 @@ type EndDialogResponse struct {
 
 func (x *EndDialogResponse) Reset() {
 	*x = EndDialogResponse{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[7]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -461,7 +617,7 @@ func (x *EndDialogResponse) String() string {
 func (*EndDialogResponse) ProtoMessage() {}
 
 func (x *EndDialogResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[7]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -474,7 +630,7 @@ func (x *EndDialogResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndDialogResponse.ProtoReflect.Descriptor instead.
 func (*EndDialogResponse) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{7}
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{9}
 }
 
 type ListDialogsRequest struct {
@@ -485,7 +641,7 @@ type ListDialogsRequest struct {
 
 func (x *ListDialogsRequest) Reset() {
 	*x = ListDialogsRequest{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[8]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -497,7 +653,7 @@ func (x *ListDialogsRequest) String() string {
 func (*ListDialogsRequest) ProtoMessage() {}
 
 func (x *ListDialogsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[8]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -510,7 +666,7 @@ func (x *ListDialogsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListDialogsRequest.ProtoReflect.Descriptor instead.
 func (*ListDialogsRequest) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{8}
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{10}
 }
 
 type ListDialogsResponse struct {
@@ -522,7 +678,7 @@ type ListDialogsResponse struct {
 
 func (x *ListDialogsResponse) Reset() {
 	*x = ListDialogsResponse{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[9]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -534,7 +690,7 @@ func (x *ListDialogsResponse) String() string {
 func (*ListDialogsResponse) ProtoMessage() {}
 
 func (x *ListDialogsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[9]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -547,7 +703,7 @@ func (x *ListDialogsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListDialogsResponse.ProtoReflect.Descriptor instead.
 func (*ListDialogsResponse) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{9}
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *ListDialogsResponse) GetDialogs() []*DialogInfo {
@@ -558,19 +714,25 @@ func (x *ListDialogsResponse) GetDialogs() []*DialogInfo {
 }
 
 type DialogInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
-	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
-	InitialState  string                 `protobuf:"bytes,4,opt,name=initial_state,json=initialState,proto3" json:"initial_state,omitempty"`
-	States        []string               `protobuf:"bytes,5,rep,name=states,proto3" json:"states,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Name         string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version      string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Description  string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	InitialState string                 `protobuf:"bytes,4,opt,name=initial_state,json=initialState,proto3" json:"initial_state,omitempty"`
+	States       []string               `protobuf:"bytes,5,rep,name=states,proto3" json:"states,omitempty"`
+	// Whether this is the version new sessions run by default.
+	IsDefault bool `protobuf:"varint,6,opt,name=is_default,json=isDefault,proto3" json:"is_default,omitempty"`
+	// "file" or "database".
+	Source string `protobuf:"bytes,7,opt,name=source,proto3" json:"source,omitempty"`
+	// Every loaded version of the dialog, lowest first.
+	History       []*DialogVersionInfo `protobuf:"bytes,8,rep,name=history,proto3" json:"history,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DialogInfo) Reset() {
 	*x = DialogInfo{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[10]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -582,7 +744,7 @@ func (x *DialogInfo) String() string {
 func (*DialogInfo) ProtoMessage() {}
 
 func (x *DialogInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[10]
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -595,7 +757,7 @@ func (x *DialogInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DialogInfo.ProtoReflect.Descriptor instead.
 func (*DialogInfo) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{10}
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *DialogInfo) GetName() string {
@@ -633,29 +795,53 @@ func (x *DialogInfo) GetStates() []string {
 	return nil
 }
 
-type ActionDirective struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
-	Params        map[string]string      `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+func (x *DialogInfo) GetIsDefault() bool {
+	if x != nil {
+		return x.IsDefault
+	}
+	return false
+}
+
+func (x *DialogInfo) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *DialogInfo) GetHistory() []*DialogVersionInfo {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+type DialogVersionInfo struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Version   string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Source    string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	IsDefault bool                   `protobuf:"varint,3,opt,name=is_default,json=isDefault,proto3" json:"is_default,omitempty"`
+	// RFC 3339 time the version was stored, or the file's modification time.
+	CreatedAt     string `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ActionDirective) Reset() {
-	*x = ActionDirective{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[11]
+func (x *DialogVersionInfo) Reset() {
+	*x = DialogVersionInfo{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ActionDirective) String() string {
+func (x *DialogVersionInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ActionDirective) ProtoMessage() {}
+func (*DialogVersionInfo) ProtoMessage() {}
 
-func (x *ActionDirective) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[11]
+func (x *DialogVersionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -666,50 +852,63 @@ func (x *ActionDirective) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ActionDirective.ProtoReflect.Descriptor instead.
-func (*ActionDirective) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use DialogVersionInfo.ProtoReflect.Descriptor instead.
+func (*DialogVersionInfo) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *ActionDirective) GetType() string {
+func (x *DialogVersionInfo) GetVersion() string {
 	if x != nil {
-		return x.Type
+		return x.Version
 	}
 	return ""
 }
 
-func (x *ActionDirective) GetParams() map[string]string {
+func (x *DialogVersionInfo) GetSource() string {
 	if x != nil {
-		return x.Params
+		return x.Source
 	}
-	return nil
+	return ""
 }
 
-type StateRecord struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	FromState     string                 `protobuf:"bytes,1,opt,name=from_state,json=fromState,proto3" json:"from_state,omitempty"`
-	ToState       string                 `protobuf:"bytes,2,opt,name=to_state,json=toState,proto3" json:"to_state,omitempty"`
-	Trigger       string                 `protobuf:"bytes,3,opt,name=trigger,proto3" json:"trigger,omitempty"`
-	Timestamp     string                 `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+func (x *DialogVersionInfo) GetIsDefault() bool {
+	if x != nil {
+		return x.IsDefault
+	}
+	return false
+}
+
+func (x *DialogVersionInfo) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+type CreateDialogRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// YAML dialog definition. It must set name and version, and the name must
+	// not be used by another dialog. The version becomes the active one.
+	Definition    string `protobuf:"bytes,1,opt,name=definition,proto3" json:"definition,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StateRecord) Reset() {
-	*x = StateRecord{}
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[12]
+func (x *CreateDialogRequest) Reset() {
+	*x = CreateDialogRequest{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StateRecord) String() string {
+func (x *CreateDialogRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StateRecord) ProtoMessage() {}
+func (*CreateDialogRequest) ProtoMessage() {}
 
-func (x *StateRecord) ProtoReflect() protoreflect.Message {
-	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[12]
+func (x *CreateDialogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -720,74 +919,760 @@ func (x *StateRecord) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StateRecord.ProtoReflect.Descriptor instead.
-func (*StateRecord) Descriptor() ([]byte, []int) {
-	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use CreateDialogRequest.ProtoReflect.Descriptor instead.
+func (*CreateDialogRequest) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *StateRecord) GetFromState() string {
+func (x *CreateDialogRequest) GetDefinition() string {
 	if x != nil {
-		return x.FromState
+		return x.Definition
 	}
 	return ""
 }
 
-func (x *StateRecord) GetToState() string {
-	if x != nil {
-		return x.ToState
-	}
-	return ""
+type CreateDialogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dialog        *DialogInfo            `protobuf:"bytes,1,opt,name=dialog,proto3" json:"dialog,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StateRecord) GetTrigger() string {
-	if x != nil {
-		return x.Trigger
-	}
-	return ""
+func (x *CreateDialogResponse) Reset() {
+	*x = CreateDialogResponse{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *StateRecord) GetTimestamp() string {
-	if x != nil {
-		return x.Timestamp
-	}
-	return ""
+func (x *CreateDialogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var File_voicetyped_dialog_v1_dialog_proto protoreflect.FileDescriptor
+func (*CreateDialogResponse) ProtoMessage() {}
 
-const file_voicetyped_dialog_v1_dialog_proto_rawDesc = "" +
-	"\n" +
-	"!voicetyped/dialog/v1/dialog.proto\x12\x14voicetyped.dialog.v1\"\x8e\x02\n" +
-	"\x12StartDialogRequest\x12\x1d\n" +
-	"\n" +
+func (x *CreateDialogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDialogResponse.ProtoReflect.Descriptor instead.
+func (*CreateDialogResponse) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CreateDialogResponse) GetDialog() *DialogInfo {
+	if x != nil {
+		return x.Dialog
+	}
+	return nil
+}
+
+type UpdateDialogRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	DialogName string                 `protobuf:"bytes,1,opt,name=dialog_name,json=dialogName,proto3" json:"dialog_name,omitempty"`
+	// YAML definition of a new version; versions are immutable.
+	Definition string `protobuf:"bytes,2,opt,name=definition,proto3" json:"definition,omitempty"`
+	// Make the new version the default for new sessions.
+	Activate      bool `protobuf:"varint,3,opt,name=activate,proto3" json:"activate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateDialogRequest) Reset() {
+	*x = UpdateDialogRequest{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateDialogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDialogRequest) ProtoMessage() {}
+
+func (x *UpdateDialogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDialogRequest.ProtoReflect.Descriptor instead.
+func (*UpdateDialogRequest) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UpdateDialogRequest) GetDialogName() string {
+	if x != nil {
+		return x.DialogName
+	}
+	return ""
+}
+
+func (x *UpdateDialogRequest) GetDefinition() string {
+	if x != nil {
+		return x.Definition
+	}
+	return ""
+}
+
+func (x *UpdateDialogRequest) GetActivate() bool {
+	if x != nil {
+		return x.Activate
+	}
+	return false
+}
+
+type UpdateDialogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dialog        *DialogInfo            `protobuf:"bytes,1,opt,name=dialog,proto3" json:"dialog,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateDialogResponse) Reset() {
+	*x = UpdateDialogResponse{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateDialogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDialogResponse) ProtoMessage() {}
+
+func (x *UpdateDialogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDialogResponse.ProtoReflect.Descriptor instead.
+func (*UpdateDialogResponse) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *UpdateDialogResponse) GetDialog() *DialogInfo {
+	if x != nil {
+		return x.Dialog
+	}
+	return nil
+}
+
+type DeleteDialogRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	DialogName string                 `protobuf:"bytes,1,opt,name=dialog_name,json=dialogName,proto3" json:"dialog_name,omitempty"`
+	// Version to delete; empty deletes every stored version.
+	Version       string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDialogRequest) Reset() {
+	*x = DeleteDialogRequest{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDialogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDialogRequest) ProtoMessage() {}
+
+func (x *DeleteDialogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDialogRequest.ProtoReflect.Descriptor instead.
+func (*DeleteDialogRequest) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DeleteDialogRequest) GetDialogName() string {
+	if x != nil {
+		return x.DialogName
+	}
+	return ""
+}
+
+func (x *DeleteDialogRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type DeleteDialogResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DeletedVersions []string               `protobuf:"bytes,1,rep,name=deleted_versions,json=deletedVersions,proto3" json:"deleted_versions,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DeleteDialogResponse) Reset() {
+	*x = DeleteDialogResponse{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDialogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDialogResponse) ProtoMessage() {}
+
+func (x *DeleteDialogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDialogResponse.ProtoReflect.Descriptor instead.
+func (*DeleteDialogResponse) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DeleteDialogResponse) GetDeletedVersions() []string {
+	if x != nil {
+		return x.DeletedVersions
+	}
+	return nil
+}
+
+type GetDialogDefinitionRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	DialogName string                 `protobuf:"bytes,1,opt,name=dialog_name,json=dialogName,proto3" json:"dialog_name,omitempty"`
+	// Empty returns the default version.
+	Version       string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDialogDefinitionRequest) Reset() {
+	*x = GetDialogDefinitionRequest{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDialogDefinitionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDialogDefinitionRequest) ProtoMessage() {}
+
+func (x *GetDialogDefinitionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDialogDefinitionRequest.ProtoReflect.Descriptor instead.
+func (*GetDialogDefinitionRequest) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetDialogDefinitionRequest) GetDialogName() string {
+	if x != nil {
+		return x.DialogName
+	}
+	return ""
+}
+
+func (x *GetDialogDefinitionRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type GetDialogDefinitionResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Dialog *DialogInfo            `protobuf:"bytes,1,opt,name=dialog,proto3" json:"dialog,omitempty"`
+	// YAML the version was loaded from.
+	Definition    string `protobuf:"bytes,2,opt,name=definition,proto3" json:"definition,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDialogDefinitionResponse) Reset() {
+	*x = GetDialogDefinitionResponse{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDialogDefinitionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDialogDefinitionResponse) ProtoMessage() {}
+
+func (x *GetDialogDefinitionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDialogDefinitionResponse.ProtoReflect.Descriptor instead.
+func (*GetDialogDefinitionResponse) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetDialogDefinitionResponse) GetDialog() *DialogInfo {
+	if x != nil {
+		return x.Dialog
+	}
+	return nil
+}
+
+func (x *GetDialogDefinitionResponse) GetDefinition() string {
+	if x != nil {
+		return x.Definition
+	}
+	return ""
+}
+
+type ActivateDialogVersionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DialogName    string                 `protobuf:"bytes,1,opt,name=dialog_name,json=dialogName,proto3" json:"dialog_name,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActivateDialogVersionRequest) Reset() {
+	*x = ActivateDialogVersionRequest{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateDialogVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateDialogVersionRequest) ProtoMessage() {}
+
+func (x *ActivateDialogVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateDialogVersionRequest.ProtoReflect.Descriptor instead.
+func (*ActivateDialogVersionRequest) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ActivateDialogVersionRequest) GetDialogName() string {
+	if x != nil {
+		return x.DialogName
+	}
+	return ""
+}
+
+func (x *ActivateDialogVersionRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type ActivateDialogVersionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dialog        *DialogInfo            `protobuf:"bytes,1,opt,name=dialog,proto3" json:"dialog,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActivateDialogVersionResponse) Reset() {
+	*x = ActivateDialogVersionResponse{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateDialogVersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateDialogVersionResponse) ProtoMessage() {}
+
+func (x *ActivateDialogVersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateDialogVersionResponse.ProtoReflect.Descriptor instead.
+func (*ActivateDialogVersionResponse) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ActivateDialogVersionResponse) GetDialog() *DialogInfo {
+	if x != nil {
+		return x.Dialog
+	}
+	return nil
+}
+
+type ActionDirective struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Params        map[string]string      `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActionDirective) Reset() {
+	*x = ActionDirective{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActionDirective) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActionDirective) ProtoMessage() {}
+
+func (x *ActionDirective) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActionDirective.ProtoReflect.Descriptor instead.
+func (*ActionDirective) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ActionDirective) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ActionDirective) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type StateRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromState     string                 `protobuf:"bytes,1,opt,name=from_state,json=fromState,proto3" json:"from_state,omitempty"`
+	ToState       string                 `protobuf:"bytes,2,opt,name=to_state,json=toState,proto3" json:"to_state,omitempty"`
+	Trigger       string                 `protobuf:"bytes,3,opt,name=trigger,proto3" json:"trigger,omitempty"`
+	Timestamp     string                 `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StateRecord) Reset() {
+	*x = StateRecord{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StateRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StateRecord) ProtoMessage() {}
+
+func (x *StateRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StateRecord.ProtoReflect.Descriptor instead.
+func (*StateRecord) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *StateRecord) GetFromState() string {
+	if x != nil {
+		return x.FromState
+	}
+	return ""
+}
+
+func (x *StateRecord) GetToState() string {
+	if x != nil {
+		return x.ToState
+	}
+	return ""
+}
+
+func (x *StateRecord) GetTrigger() string {
+	if x != nil {
+		return x.Trigger
+	}
+	return ""
+}
+
+func (x *StateRecord) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+type TranscriptTurn struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "caller", "system", "dtmf", "hook" or "tool".
+	Kind string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	// The utterance, the prompt as rendered, the DTMF keys or the result of a
+	// tool call.
+	Text       string  `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Confidence float32 `protobuf:"fixed32,3,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	// The hook definition called, or the inline hook URL without its
+	// credentials and query, and why the call failed if it did.
+	Hook  string `protobuf:"bytes,10,opt,name=hook,proto3" json:"hook,omitempty"`
+	Url   string `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	Error string `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	// The state the session was in.
+	State     string `protobuf:"bytes,6,opt,name=state,proto3" json:"state,omitempty"`
+	Timestamp string `protobuf:"bytes,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// The tool an llm_turn called and the arguments it passed, as JSON.
+	Tool          string `protobuf:"bytes,8,opt,name=tool,proto3" json:"tool,omitempty"`
+	Arguments     string `protobuf:"bytes,9,opt,name=arguments,proto3" json:"arguments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TranscriptTurn) Reset() {
+	*x = TranscriptTurn{}
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TranscriptTurn) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TranscriptTurn) ProtoMessage() {}
+
+func (x *TranscriptTurn) ProtoReflect() protoreflect.Message {
+	mi := &file_voicetyped_dialog_v1_dialog_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TranscriptTurn.ProtoReflect.Descriptor instead.
+func (*TranscriptTurn) Descriptor() ([]byte, []int) {
+	return file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TranscriptTurn) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetConfidence() float32 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *TranscriptTurn) GetHook() string {
+	if x != nil {
+		return x.Hook
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetTool() string {
+	if x != nil {
+		return x.Tool
+	}
+	return ""
+}
+
+func (x *TranscriptTurn) GetArguments() string {
+	if x != nil {
+		return x.Arguments
+	}
+	return ""
+}
+
+var File_voicetyped_dialog_v1_dialog_proto protoreflect.FileDescriptor
+
+const file_voicetyped_dialog_v1_dialog_proto_rawDesc = "" +
+	"\n" +
+	"!voicetyped/dialog/v1/dialog.proto\x12\x14voicetyped.dialog.v1\"\x91\x04\n" +
+	"\x12StartDialogRequest\x12\x1d\n" +
+	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1f\n" +
 	"\vdialog_name\x18\x02 \x01(\tR\n" +
 	"dialogName\x12#\n" +
 	"\rinitial_state\x18\x03 \x01(\tR\finitialState\x12U\n" +
-	"\tvariables\x18\x04 \x03(\v27.voicetyped.dialog.v1.StartDialogRequest.VariablesEntryR\tvariables\x1a<\n" +
+	"\tvariables\x18\x04 \x03(\v27.voicetyped.dialog.v1.StartDialogRequest.VariablesEntryR\tvariables\x12\x17\n" +
+	"\aroom_id\x18\x05 \x01(\tR\x06roomId\x12\x17\n" +
+	"\apeer_id\x18\x06 \x01(\tR\x06peerId\x12%\n" +
+	"\x0edialog_version\x18\a \x01(\tR\rdialogVersion\x12e\n" +
+	"\x0fversion_weights\x18\b \x03(\v2<.voicetyped.dialog.v1.StartDialogRequest.VersionWeightsEntryR\x0eversionWeights\x1a<\n" +
 	"\x0eVariablesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x9a\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aA\n" +
+	"\x13VersionWeightsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\rR\x05value:\x028\x01\"\xc1\x01\n" +
 	"\x13StartDialogResponse\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12#\n" +
 	"\rcurrent_state\x18\x02 \x01(\tR\fcurrentState\x12?\n" +
-	"\aactions\x18\x03 \x03(\v2%.voicetyped.dialog.v1.ActionDirectiveR\aactions\"o\n" +
+	"\aactions\x18\x03 \x03(\v2%.voicetyped.dialog.v1.ActionDirectiveR\aactions\x12%\n" +
+	"\x0edialog_version\x18\x04 \x01(\tR\rdialogVersion\"\x8f\x01\n" +
 	"\x10SendEventRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1d\n" +
 	"\n" +
 	"event_type\x18\x02 \x01(\tR\teventType\x12\x1d\n" +
 	"\n" +
-	"event_data\x18\x03 \x01(\tR\teventData\"\xbc\x01\n" +
-	"\x11SendEventResponse\x12%\n" +
+	"event_data\x18\x03 \x01(\tR\teventData\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x04 \x01(\x02R\n" +
+	"confidence\"K\n" +
+	"\x11SendEventResponseJ\x04\b\x01\x10\x05R\x0eprevious_stateR\rcurrent_stateR\bterminalR\aactions\"5\n" +
+	"\x14StreamActionsRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xc0\x01\n" +
+	"\x15StreamActionsResponse\x12%\n" +
 	"\x0eprevious_state\x18\x01 \x01(\tR\rpreviousState\x12#\n" +
 	"\rcurrent_state\x18\x02 \x01(\tR\fcurrentState\x12\x1a\n" +
 	"\bterminal\x18\x03 \x01(\bR\bterminal\x12?\n" +
 	"\aactions\x18\x04 \x03(\v2%.voicetyped.dialog.v1.ActionDirectiveR\aactions\"2\n" +
 	"\x11GetSessionRequest\x12\x1d\n" +
 	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\"\xcb\x02\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xb8\x03\n" +
 	"\x12GetSessionResponse\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1f\n" +
@@ -795,7 +1680,11 @@ const file_voicetyped_dialog_v1_dialog_proto_rawDesc = "" +
 	"dialogName\x12#\n" +
 	"\rcurrent_state\x18\x03 \x01(\tR\fcurrentState\x12U\n" +
 	"\tvariables\x18\x04 \x03(\v27.voicetyped.dialog.v1.GetSessionResponse.VariablesEntryR\tvariables\x12;\n" +
-	"\ahistory\x18\x05 \x03(\v2!.voicetyped.dialog.v1.StateRecordR\ahistory\x1a<\n" +
+	"\ahistory\x18\x05 \x03(\v2!.voicetyped.dialog.v1.StateRecordR\ahistory\x12%\n" +
+	"\x0edialog_version\x18\x06 \x01(\tR\rdialogVersion\x12D\n" +
+	"\n" +
+	"transcript\x18\a \x03(\v2$.voicetyped.dialog.v1.TranscriptTurnR\n" +
+	"transcript\x1a<\n" +
 	"\x0eVariablesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"1\n" +
@@ -805,14 +1694,61 @@ const file_voicetyped_dialog_v1_dialog_proto_rawDesc = "" +
 	"\x11EndDialogResponse\"\x14\n" +
 	"\x12ListDialogsRequest\"Q\n" +
 	"\x13ListDialogsResponse\x12:\n" +
-	"\adialogs\x18\x01 \x03(\v2 .voicetyped.dialog.v1.DialogInfoR\adialogs\"\x99\x01\n" +
+	"\adialogs\x18\x01 \x03(\v2 .voicetyped.dialog.v1.DialogInfoR\adialogs\"\x93\x02\n" +
 	"\n" +
 	"DialogInfo\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\tR\aversion\x12 \n" +
 	"\vdescription\x18\x03 \x01(\tR\vdescription\x12#\n" +
 	"\rinitial_state\x18\x04 \x01(\tR\finitialState\x12\x16\n" +
-	"\x06states\x18\x05 \x03(\tR\x06states\"\xab\x01\n" +
+	"\x06states\x18\x05 \x03(\tR\x06states\x12\x1d\n" +
+	"\n" +
+	"is_default\x18\x06 \x01(\bR\tisDefault\x12\x16\n" +
+	"\x06source\x18\a \x01(\tR\x06source\x12A\n" +
+	"\ahistory\x18\b \x03(\v2'.voicetyped.dialog.v1.DialogVersionInfoR\ahistory\"\x83\x01\n" +
+	"\x11DialogVersionInfo\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x1d\n" +
+	"\n" +
+	"is_default\x18\x03 \x01(\bR\tisDefault\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\tR\tcreatedAt\"5\n" +
+	"\x13CreateDialogRequest\x12\x1e\n" +
+	"\n" +
+	"definition\x18\x01 \x01(\tR\n" +
+	"definition\"P\n" +
+	"\x14CreateDialogResponse\x128\n" +
+	"\x06dialog\x18\x01 \x01(\v2 .voicetyped.dialog.v1.DialogInfoR\x06dialog\"r\n" +
+	"\x13UpdateDialogRequest\x12\x1f\n" +
+	"\vdialog_name\x18\x01 \x01(\tR\n" +
+	"dialogName\x12\x1e\n" +
+	"\n" +
+	"definition\x18\x02 \x01(\tR\n" +
+	"definition\x12\x1a\n" +
+	"\bactivate\x18\x03 \x01(\bR\bactivate\"P\n" +
+	"\x14UpdateDialogResponse\x128\n" +
+	"\x06dialog\x18\x01 \x01(\v2 .voicetyped.dialog.v1.DialogInfoR\x06dialog\"P\n" +
+	"\x13DeleteDialogRequest\x12\x1f\n" +
+	"\vdialog_name\x18\x01 \x01(\tR\n" +
+	"dialogName\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"A\n" +
+	"\x14DeleteDialogResponse\x12)\n" +
+	"\x10deleted_versions\x18\x01 \x03(\tR\x0fdeletedVersions\"W\n" +
+	"\x1aGetDialogDefinitionRequest\x12\x1f\n" +
+	"\vdialog_name\x18\x01 \x01(\tR\n" +
+	"dialogName\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"w\n" +
+	"\x1bGetDialogDefinitionResponse\x128\n" +
+	"\x06dialog\x18\x01 \x01(\v2 .voicetyped.dialog.v1.DialogInfoR\x06dialog\x12\x1e\n" +
+	"\n" +
+	"definition\x18\x02 \x01(\tR\n" +
+	"definition\"Y\n" +
+	"\x1cActivateDialogVersionRequest\x12\x1f\n" +
+	"\vdialog_name\x18\x01 \x01(\tR\n" +
+	"dialogName\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"Y\n" +
+	"\x1dActivateDialogVersionResponse\x128\n" +
+	"\x06dialog\x18\x01 \x01(\v2 .voicetyped.dialog.v1.DialogInfoR\x06dialog\"\xab\x01\n" +
 	"\x0fActionDirective\x12\x12\n" +
 	"\x04type\x18\x01 \x01(\tR\x04type\x12I\n" +
 	"\x06params\x18\x02 \x03(\v21.voicetyped.dialog.v1.ActionDirective.ParamsEntryR\x06params\x1a9\n" +
@@ -824,14 +1760,34 @@ const file_voicetyped_dialog_v1_dialog_proto_rawDesc = "" +
 	"from_state\x18\x01 \x01(\tR\tfromState\x12\x19\n" +
 	"\bto_state\x18\x02 \x01(\tR\atoState\x12\x18\n" +
 	"\atrigger\x18\x03 \x01(\tR\atrigger\x12\x1c\n" +
-	"\ttimestamp\x18\x04 \x01(\tR\ttimestamp2\xf4\x03\n" +
+	"\ttimestamp\x18\x04 \x01(\tR\ttimestamp\"\xfa\x01\n" +
+	"\x0eTranscriptTurn\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x03 \x01(\x02R\n" +
+	"confidence\x12\x12\n" +
+	"\x04hook\x18\n" +
+	" \x01(\tR\x04hook\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x14\n" +
+	"\x05state\x18\x06 \x01(\tR\x05state\x12\x1c\n" +
+	"\ttimestamp\x18\a \x01(\tR\ttimestamp\x12\x12\n" +
+	"\x04tool\x18\b \x01(\tR\x04tool\x12\x1c\n" +
+	"\targuments\x18\t \x01(\tR\targuments2\x94\t\n" +
 	"\rDialogService\x12b\n" +
 	"\vStartDialog\x12(.voicetyped.dialog.v1.StartDialogRequest\x1a).voicetyped.dialog.v1.StartDialogResponse\x12\\\n" +
-	"\tSendEvent\x12&.voicetyped.dialog.v1.SendEventRequest\x1a'.voicetyped.dialog.v1.SendEventResponse\x12_\n" +
+	"\tSendEvent\x12&.voicetyped.dialog.v1.SendEventRequest\x1a'.voicetyped.dialog.v1.SendEventResponse\x12j\n" +
+	"\rStreamActions\x12*.voicetyped.dialog.v1.StreamActionsRequest\x1a+.voicetyped.dialog.v1.StreamActionsResponse0\x01\x12_\n" +
 	"\n" +
 	"GetSession\x12'.voicetyped.dialog.v1.GetSessionRequest\x1a(.voicetyped.dialog.v1.GetSessionResponse\x12\\\n" +
 	"\tEndDialog\x12&.voicetyped.dialog.v1.EndDialogRequest\x1a'.voicetyped.dialog.v1.EndDialogResponse\x12b\n" +
-	"\vListDialogs\x12(.voicetyped.dialog.v1.ListDialogsRequest\x1a).voicetyped.dialog.v1.ListDialogsResponseBDZBgithub.com/voicetyped/voicetyped/gen/voicetyped/dialog/v1;dialogv1b\x06proto3"
+	"\vListDialogs\x12(.voicetyped.dialog.v1.ListDialogsRequest\x1a).voicetyped.dialog.v1.ListDialogsResponse\x12e\n" +
+	"\fCreateDialog\x12).voicetyped.dialog.v1.CreateDialogRequest\x1a*.voicetyped.dialog.v1.CreateDialogResponse\x12e\n" +
+	"\fUpdateDialog\x12).voicetyped.dialog.v1.UpdateDialogRequest\x1a*.voicetyped.dialog.v1.UpdateDialogResponse\x12e\n" +
+	"\fDeleteDialog\x12).voicetyped.dialog.v1.DeleteDialogRequest\x1a*.voicetyped.dialog.v1.DeleteDialogResponse\x12z\n" +
+	"\x13GetDialogDefinition\x120.voicetyped.dialog.v1.GetDialogDefinitionRequest\x1a1.voicetyped.dialog.v1.GetDialogDefinitionResponse\x12\x80\x01\n" +
+	"\x15ActivateDialogVersion\x122.voicetyped.dialog.v1.ActivateDialogVersionRequest\x1a3.voicetyped.dialog.v1.ActivateDialogVersionResponseBDZBgithub.com/voicetyped/voicetyped/gen/voicetyped/dialog/v1;dialogv1b\x06proto3"
 
 var (
 	file_voicetyped_dialog_v1_dialog_proto_rawDescOnce sync.Once
@@ -845,48 +1801,82 @@ func file_voicetyped_dialog_v1_dialog_proto_rawDescGZIP() []byte {
 	return file_voicetyped_dialog_v1_dialog_proto_rawDescData
 }
 
-var file_voicetyped_dialog_v1_dialog_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_voicetyped_dialog_v1_dialog_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_voicetyped_dialog_v1_dialog_proto_goTypes = []any{
-	(*StartDialogRequest)(nil),  // 0: voicetyped.dialog.v1.StartDialogRequest
-	(*StartDialogResponse)(nil), // 1: voicetyped.dialog.v1.StartDialogResponse
-	(*SendEventRequest)(nil),    // 2: voicetyped.dialog.v1.SendEventRequest
-	(*SendEventResponse)(nil),   // 3: voicetyped.dialog.v1.SendEventResponse
-	(*GetSessionRequest)(nil),   // 4: voicetyped.dialog.v1.GetSessionRequest
-	(*GetSessionResponse)(nil),  // 5: voicetyped.dialog.v1.GetSessionResponse
-	(*EndDialogRequest)(nil),    // 6: voicetyped.dialog.v1.EndDialogRequest
-	(*EndDialogResponse)(nil),   // 7: voicetyped.dialog.v1.EndDialogResponse
-	(*ListDialogsRequest)(nil),  // 8: voicetyped.dialog.v1.ListDialogsRequest
-	(*ListDialogsResponse)(nil), // 9: voicetyped.dialog.v1.ListDialogsResponse
-	(*DialogInfo)(nil),          // 10: voicetyped.dialog.v1.DialogInfo
-	(*ActionDirective)(nil),     // 11: voicetyped.dialog.v1.ActionDirective
-	(*StateRecord)(nil),         // 12: voicetyped.dialog.v1.StateRecord
-	nil,                         // 13: voicetyped.dialog.v1.StartDialogRequest.VariablesEntry
-	nil,                         // 14: voicetyped.dialog.v1.GetSessionResponse.VariablesEntry
-	nil,                         // 15: voicetyped.dialog.v1.ActionDirective.ParamsEntry
+	(*StartDialogRequest)(nil),            // 0: voicetyped.dialog.v1.StartDialogRequest
+	(*StartDialogResponse)(nil),           // 1: voicetyped.dialog.v1.StartDialogResponse
+	(*SendEventRequest)(nil),              // 2: voicetyped.dialog.v1.SendEventRequest
+	(*SendEventResponse)(nil),             // 3: voicetyped.dialog.v1.SendEventResponse
+	(*StreamActionsRequest)(nil),          // 4: voicetyped.dialog.v1.StreamActionsRequest
+	(*StreamActionsResponse)(nil),         // 5: voicetyped.dialog.v1.StreamActionsResponse
+	(*GetSessionRequest)(nil),             // 6: voicetyped.dialog.v1.GetSessionRequest
+	(*GetSessionResponse)(nil),            // 7: voicetyped.dialog.v1.GetSessionResponse
+	(*EndDialogRequest)(nil),              // 8: voicetyped.dialog.v1.EndDialogRequest
+	(*EndDialogResponse)(nil),             // 9: voicetyped.dialog.v1.EndDialogResponse
+	(*ListDialogsRequest)(nil),            // 10: voicetyped.dialog.v1.ListDialogsRequest
+	(*ListDialogsResponse)(nil),           // 11: voicetyped.dialog.v1.ListDialogsResponse
+	(*DialogInfo)(nil),                    // 12: voicetyped.dialog.v1.DialogInfo
+	(*DialogVersionInfo)(nil),             // 13: voicetyped.dialog.v1.DialogVersionInfo
+	(*CreateDialogRequest)(nil),           // 14: voicetyped.dialog.v1.CreateDialogRequest
+	(*CreateDialogResponse)(nil),          // 15: voicetyped.dialog.v1.CreateDialogResponse
+	(*UpdateDialogRequest)(nil),           // 16: voicetyped.dialog.v1.UpdateDialogRequest
+	(*UpdateDialogResponse)(nil),          // 17: voicetyped.dialog.v1.UpdateDialogResponse
+	(*DeleteDialogRequest)(nil),           // 18: voicetyped.dialog.v1.DeleteDialogRequest
+	(*DeleteDialogResponse)(nil),          // 19: voicetyped.dialog.v1.DeleteDialogResponse
+	(*GetDialogDefinitionRequest)(nil),    // 20: voicetyped.dialog.v1.GetDialogDefinitionRequest
+	(*GetDialogDefinitionResponse)(nil),   // 21: voicetyped.dialog.v1.GetDialogDefinitionResponse
+	(*ActivateDialogVersionRequest)(nil),  // 22: voicetyped.dialog.v1.ActivateDialogVersionRequest
+	(*ActivateDialogVersionResponse)(nil), // 23: voicetyped.dialog.v1.ActivateDialogVersionResponse
+	(*ActionDirective)(nil),               // 24: voicetyped.dialog.v1.ActionDirective
+	(*StateRecord)(nil),                   // 25: voicetyped.dialog.v1.StateRecord
+	(*TranscriptTurn)(nil),                // 26: voicetyped.dialog.v1.TranscriptTurn
+	nil,                                   // 27: voicetyped.dialog.v1.StartDialogRequest.VariablesEntry
+	nil,                                   // 28: voicetyped.dialog.v1.StartDialogRequest.VersionWeightsEntry
+	nil,                                   // 29: voicetyped.dialog.v1.GetSessionResponse.VariablesEntry
+	nil,                                   // 30: voicetyped.dialog.v1.ActionDirective.ParamsEntry
 }
 var file_voicetyped_dialog_v1_dialog_proto_depIdxs = []int32{
-	13, // 0: voicetyped.dialog.v1.StartDialogRequest.variables:type_name -> voicetyped.dialog.v1.StartDialogRequest.VariablesEntry
-	11, // 1: voicetyped.dialog.v1.StartDialogResponse.actions:type_name -> voicetyped.dialog.v1.ActionDirective
-	11, // 2: voicetyped.dialog.v1.SendEventResponse.actions:type_name -> voicetyped.dialog.v1.ActionDirective
-	14, // 3: voicetyped.dialog.v1.GetSessionResponse.variables:type_name -> voicetyped.dialog.v1.GetSessionResponse.VariablesEntry
-	12, // 4: voicetyped.dialog.v1.GetSessionResponse.history:type_name -> voicetyped.dialog.v1.StateRecord
-	10, // 5: voicetyped.dialog.v1.ListDialogsResponse.dialogs:type_name -> voicetyped.dialog.v1.DialogInfo
-	15, // 6: voicetyped.dialog.v1.ActionDirective.params:type_name -> voicetyped.dialog.v1.ActionDirective.ParamsEntry
-	0,  // 7: voicetyped.dialog.v1.DialogService.StartDialog:input_type -> voicetyped.dialog.v1.StartDialogRequest
-	2,  // 8: voicetyped.dialog.v1.DialogService.SendEvent:input_type -> voicetyped.dialog.v1.SendEventRequest
-	4,  // 9: voicetyped.dialog.v1.DialogService.GetSession:input_type -> voicetyped.dialog.v1.GetSessionRequest
-	6,  // 10: voicetyped.dialog.v1.DialogService.EndDialog:input_type -> voicetyped.dialog.v1.EndDialogRequest
-	8,  // 11: voicetyped.dialog.v1.DialogService.ListDialogs:input_type -> voicetyped.dialog.v1.ListDialogsRequest
-	1,  // 12: voicetyped.dialog.v1.DialogService.StartDialog:output_type -> voicetyped.dialog.v1.StartDialogResponse
-	3,  // 13: voicetyped.dialog.v1.DialogService.SendEvent:output_type -> voicetyped.dialog.v1.SendEventResponse
-	5,  // 14: voicetyped.dialog.v1.DialogService.GetSession:output_type -> voicetyped.dialog.v1.GetSessionResponse
-	7,  // 15: voicetyped.dialog.v1.DialogService.EndDialog:output_type -> voicetyped.dialog.v1.EndDialogResponse
-	9,  // 16: voicetyped.dialog.v1.DialogService.ListDialogs:output_type -> voicetyped.dialog.v1.ListDialogsResponse
-	12, // [12:17] is the sub-list for method output_type
-	7,  // [7:12] is the sub-list for method input_type
-	7,  // [7:7] is the sub-list for extension type_name
-	7,  // [7:7] is the sub-list for extension extendee
-	0,  // [0:7] is the sub-list for field type_name
+	27, // 0: voicetyped.dialog.v1.StartDialogRequest.variables:type_name -> voicetyped.dialog.v1.StartDialogRequest.VariablesEntry
+	28, // 1: voicetyped.dialog.v1.StartDialogRequest.version_weights:type_name -> voicetyped.dialog.v1.StartDialogRequest.VersionWeightsEntry
+	24, // 2: voicetyped.dialog.v1.StartDialogResponse.actions:type_name -> voicetyped.dialog.v1.ActionDirective
+	24, // 3: voicetyped.dialog.v1.StreamActionsResponse.actions:type_name -> voicetyped.dialog.v1.ActionDirective
+	29, // 4: voicetyped.dialog.v1.GetSessionResponse.variables:type_name -> voicetyped.dialog.v1.GetSessionResponse.VariablesEntry
+	25, // 5: voicetyped.dialog.v1.GetSessionResponse.history:type_name -> voicetyped.dialog.v1.StateRecord
+	26, // 6: voicetyped.dialog.v1.GetSessionResponse.transcript:type_name -> voicetyped.dialog.v1.TranscriptTurn
+	12, // 7: voicetyped.dialog.v1.ListDialogsResponse.dialogs:type_name -> voicetyped.dialog.v1.DialogInfo
+	13, // 8: voicetyped.dialog.v1.DialogInfo.history:type_name -> voicetyped.dialog.v1.DialogVersionInfo
+	12, // 9: voicetyped.dialog.v1.CreateDialogResponse.dialog:type_name -> voicetyped.dialog.v1.DialogInfo
+	12, // 10: voicetyped.dialog.v1.UpdateDialogResponse.dialog:type_name -> voicetyped.dialog.v1.DialogInfo
+	12, // 11: voicetyped.dialog.v1.GetDialogDefinitionResponse.dialog:type_name -> voicetyped.dialog.v1.DialogInfo
+	12, // 12: voicetyped.dialog.v1.ActivateDialogVersionResponse.dialog:type_name -> voicetyped.dialog.v1.DialogInfo
+	30, // 13: voicetyped.dialog.v1.ActionDirective.params:type_name -> voicetyped.dialog.v1.ActionDirective.ParamsEntry
+	0,  // 14: voicetyped.dialog.v1.DialogService.StartDialog:input_type -> voicetyped.dialog.v1.StartDialogRequest
+	2,  // 15: voicetyped.dialog.v1.DialogService.SendEvent:input_type -> voicetyped.dialog.v1.SendEventRequest
+	4,  // 16: voicetyped.dialog.v1.DialogService.StreamActions:input_type -> voicetyped.dialog.v1.StreamActionsRequest
+	6,  // 17: voicetyped.dialog.v1.DialogService.GetSession:input_type -> voicetyped.dialog.v1.GetSessionRequest
+	8,  // 18: voicetyped.dialog.v1.DialogService.EndDialog:input_type -> voicetyped.dialog.v1.EndDialogRequest
+	10, // 19: voicetyped.dialog.v1.DialogService.ListDialogs:input_type -> voicetyped.dialog.v1.ListDialogsRequest
+	14, // 20: voicetyped.dialog.v1.DialogService.CreateDialog:input_type -> voicetyped.dialog.v1.CreateDialogRequest
+	16, // 21: voicetyped.dialog.v1.DialogService.UpdateDialog:input_type -> voicetyped.dialog.v1.UpdateDialogRequest
+	18, // 22: voicetyped.dialog.v1.DialogService.DeleteDialog:input_type -> voicetyped.dialog.v1.DeleteDialogRequest
+	20, // 23: voicetyped.dialog.v1.DialogService.GetDialogDefinition:input_type -> voicetyped.dialog.v1.GetDialogDefinitionRequest
+	22, // 24: voicetyped.dialog.v1.DialogService.ActivateDialogVersion:input_type -> voicetyped.dialog.v1.ActivateDialogVersionRequest
+	1,  // 25: voicetyped.dialog.v1.DialogService.StartDialog:output_type -> voicetyped.dialog.v1.StartDialogResponse
+	3,  // 26: voicetyped.dialog.v1.DialogService.SendEvent:output_type -> voicetyped.dialog.v1.SendEventResponse
+	5,  // 27: voicetyped.dialog.v1.DialogService.StreamActions:output_type -> voicetyped.dialog.v1.StreamActionsResponse
+	7,  // 28: voicetyped.dialog.v1.DialogService.GetSession:output_type -> voicetyped.dialog.v1.GetSessionResponse
+	9,  // 29: voicetyped.dialog.v1.DialogService.EndDialog:output_type -> voicetyped.dialog.v1.EndDialogResponse
+	11, // 30: voicetyped.dialog.v1.DialogService.ListDialogs:output_type -> voicetyped.dialog.v1.ListDialogsResponse
+	15, // 31: voicetyped.dialog.v1.DialogService.CreateDialog:output_type -> voicetyped.dialog.v1.CreateDialogResponse
+	17, // 32: voicetyped.dialog.v1.DialogService.UpdateDialog:output_type -> voicetyped.dialog.v1.UpdateDialogResponse
+	19, // 33: voicetyped.dialog.v1.DialogService.DeleteDialog:output_type -> voicetyped.dialog.v1.DeleteDialogResponse
+	21, // 34: voicetyped.dialog.v1.DialogService.GetDialogDefinition:output_type -> voicetyped.dialog.v1.GetDialogDefinitionResponse
+	23, // 35: voicetyped.dialog.v1.DialogService.ActivateDialogVersion:output_type -> voicetyped.dialog.v1.ActivateDialogVersionResponse
+	25, // [25:36] is the sub-list for method output_type
+	14, // [14:25] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_voicetyped_dialog_v1_dialog_proto_init() }
@@ -900,7 +1890,7 @@ func file_voicetyped_dialog_v1_dialog_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_voicetyped_dialog_v1_dialog_proto_rawDesc), len(file_voicetyped_dialog_v1_dialog_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   16,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -38,6 +38,9 @@ const (
 	DialogServiceStartDialogProcedure = "/voicetyped.dialog.v1.DialogService/StartDialog"
 	// DialogServiceSendEventProcedure is the fully-qualified name of the DialogService's SendEvent RPC.
 	DialogServiceSendEventProcedure = "/voicetyped.dialog.v1.DialogService/SendEvent"
+	// DialogServiceStreamActionsProcedure is the fully-qualified name of the DialogService's
+	// StreamActions RPC.
+	DialogServiceStreamActionsProcedure = "/voicetyped.dialog.v1.DialogService/StreamActions"
 	// DialogServiceGetSessionProcedure is the fully-qualified name of the DialogService's GetSession
 	// RPC.
 	DialogServiceGetSessionProcedure = "/voicetyped.dialog.v1.DialogService/GetSession"
@@ -46,15 +49,44 @@ const (
 	// DialogServiceListDialogsProcedure is the fully-qualified name of the DialogService's ListDialogs
 	// RPC.
 	DialogServiceListDialogsProcedure = "/voicetyped.dialog.v1.DialogService/ListDialogs"
+	// DialogServiceCreateDialogProcedure is the fully-qualified name of the DialogService's
+	// CreateDialog RPC.
+	DialogServiceCreateDialogProcedure = "/voicetyped.dialog.v1.DialogService/CreateDialog"
+	// DialogServiceUpdateDialogProcedure is the fully-qualified name of the DialogService's
+	// UpdateDialog RPC.
+	DialogServiceUpdateDialogProcedure = "/voicetyped.dialog.v1.DialogService/UpdateDialog"
+	// DialogServiceDeleteDialogProcedure is the fully-qualified name of the DialogService's
+	// DeleteDialog RPC.
+	DialogServiceDeleteDialogProcedure = "/voicetyped.dialog.v1.DialogService/DeleteDialog"
+	// DialogServiceGetDialogDefinitionProcedure is the fully-qualified name of the DialogService's
+	// GetDialogDefinition RPC.
+	DialogServiceGetDialogDefinitionProcedure = "/voicetyped.dialog.v1.DialogService/GetDialogDefinition"
+	// DialogServiceActivateDialogVersionProcedure is the fully-qualified name of the DialogService's
+	// ActivateDialogVersion RPC.
+	DialogServiceActivateDialogVersionProcedure = "/voicetyped.dialog.v1.DialogService/ActivateDialogVersion"
 )
 
 // DialogServiceClient is a client for the voicetyped.dialog.v1.DialogService service.
 type DialogServiceClient interface {
 	StartDialog(context.Context, *connect.Request[v1.StartDialogRequest]) (*connect.Response[v1.StartDialogResponse], error)
+	// SendEvent queues an event for the session's dialog loop. The actions it
+	// leads to are delivered on StreamActions.
 	SendEvent(context.Context, *connect.Request[v1.SendEventRequest]) (*connect.Response[v1.SendEventResponse], error)
+	// StreamActions delivers the result of every step the session's dialog
+	// loop processes, whether triggered by SendEvent or by the engine itself,
+	// e.g. a state timeout.
+	StreamActions(context.Context, *connect.Request[v1.StreamActionsRequest]) (*connect.ServerStreamForClient[v1.StreamActionsResponse], error)
 	GetSession(context.Context, *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error)
 	EndDialog(context.Context, *connect.Request[v1.EndDialogRequest]) (*connect.Response[v1.EndDialogResponse], error)
 	ListDialogs(context.Context, *connect.Request[v1.ListDialogsRequest]) (*connect.Response[v1.ListDialogsResponse], error)
+	// Dialog management. Dialogs created through the API are stored in the
+	// database and served next to the files in DIALOG_DIR; file dialogs can
+	// only be changed on disk.
+	CreateDialog(context.Context, *connect.Request[v1.CreateDialogRequest]) (*connect.Response[v1.CreateDialogResponse], error)
+	UpdateDialog(context.Context, *connect.Request[v1.UpdateDialogRequest]) (*connect.Response[v1.UpdateDialogResponse], error)
+	DeleteDialog(context.Context, *connect.Request[v1.DeleteDialogRequest]) (*connect.Response[v1.DeleteDialogResponse], error)
+	GetDialogDefinition(context.Context, *connect.Request[v1.GetDialogDefinitionRequest]) (*connect.Response[v1.GetDialogDefinitionResponse], error)
+	ActivateDialogVersion(context.Context, *connect.Request[v1.ActivateDialogVersionRequest]) (*connect.Response[v1.ActivateDialogVersionResponse], error)
 }
 
 // NewDialogServiceClient constructs a client for the voicetyped.dialog.v1.DialogService service. By
@@ -80,6 +112,12 @@ func NewDialogServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(dialogServiceMethods.ByName("SendEvent")),
 			connect.WithClientOptions(opts...),
 		),
+		streamActions: connect.NewClient[v1.StreamActionsRequest, v1.StreamActionsResponse](
+			httpClient,
+			baseURL+DialogServiceStreamActionsProcedure,
+			connect.WithSchema(dialogServiceMethods.ByName("StreamActions")),
+			connect.WithClientOptions(opts...),
+		),
 		getSession: connect.NewClient[v1.GetSessionRequest, v1.GetSessionResponse](
 			httpClient,
 			baseURL+DialogServiceGetSessionProcedure,
@@ -98,16 +136,52 @@ func NewDialogServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(dialogServiceMethods.ByName("ListDialogs")),
 			connect.WithClientOptions(opts...),
 		),
+		createDialog: connect.NewClient[v1.CreateDialogRequest, v1.CreateDialogResponse](
+			httpClient,
+			baseURL+DialogServiceCreateDialogProcedure,
+			connect.WithSchema(dialogServiceMethods.ByName("CreateDialog")),
+			connect.WithClientOptions(opts...),
+		),
+		updateDialog: connect.NewClient[v1.UpdateDialogRequest, v1.UpdateDialogResponse](
+			httpClient,
+			baseURL+DialogServiceUpdateDialogProcedure,
+			connect.WithSchema(dialogServiceMethods.ByName("UpdateDialog")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteDialog: connect.NewClient[v1.DeleteDialogRequest, v1.DeleteDialogResponse](
+			httpClient,
+			baseURL+DialogServiceDeleteDialogProcedure,
+			connect.WithSchema(dialogServiceMethods.ByName("DeleteDialog")),
+			connect.WithClientOptions(opts...),
+		),
+		getDialogDefinition: connect.NewClient[v1.GetDialogDefinitionRequest, v1.GetDialogDefinitionResponse](
+			httpClient,
+			baseURL+DialogServiceGetDialogDefinitionProcedure,
+			connect.WithSchema(dialogServiceMethods.ByName("GetDialogDefinition")),
+			connect.WithClientOptions(opts...),
+		),
+		activateDialogVersion: connect.NewClient[v1.ActivateDialogVersionRequest, v1.ActivateDialogVersionResponse](
+			httpClient,
+			baseURL+DialogServiceActivateDialogVersionProcedure,
+			connect.WithSchema(dialogServiceMethods.ByName("ActivateDialogVersion")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // dialogServiceClient implements DialogServiceClient.
 type dialogServiceClient struct {
-	startDialog *connect.Client[v1.StartDialogRequest, v1.StartDialogResponse]
-	sendEvent   *connect.Client[v1.SendEventRequest, v1.SendEventResponse]
-	getSession  *connect.Client[v1.GetSessionRequest, v1.GetSessionResponse]
-	endDialog   *connect.Client[v1.EndDialogRequest, v1.EndDialogResponse]
-	listDialogs *connect.Client[v1.ListDialogsRequest, v1.ListDialogsResponse]
+	startDialog           *connect.Client[v1.StartDialogRequest, v1.StartDialogResponse]
+	sendEvent             *connect.Client[v1.SendEventRequest, v1.SendEventResponse]
+	streamActions         *connect.Client[v1.StreamActionsRequest, v1.StreamActionsResponse]
+	getSession            *connect.Client[v1.GetSessionRequest, v1.GetSessionResponse]
+	endDialog             *connect.Client[v1.EndDialogRequest, v1.EndDialogResponse]
+	listDialogs           *connect.Client[v1.ListDialogsRequest, v1.ListDialogsResponse]
+	createDialog          *connect.Client[v1.CreateDialogRequest, v1.CreateDialogResponse]
+	updateDialog          *connect.Client[v1.UpdateDialogRequest, v1.UpdateDialogResponse]
+	deleteDialog          *connect.Client[v1.DeleteDialogRequest, v1.DeleteDialogResponse]
+	getDialogDefinition   *connect.Client[v1.GetDialogDefinitionRequest, v1.GetDialogDefinitionResponse]
+	activateDialogVersion *connect.Client[v1.ActivateDialogVersionRequest, v1.ActivateDialogVersionResponse]
 }
 
 // StartDialog calls voicetyped.dialog.v1.DialogService.StartDialog.
@@ -120,6 +194,11 @@ func (c *dialogServiceClient) SendEvent(ctx context.Context, req *connect.Reques
 	return c.sendEvent.CallUnary(ctx, req)
 }
 
+// StreamActions calls voicetyped.dialog.v1.DialogService.StreamActions.
+func (c *dialogServiceClient) StreamActions(ctx context.Context, req *connect.Request[v1.StreamActionsRequest]) (*connect.ServerStreamForClient[v1.StreamActionsResponse], error) {
+	return c.streamActions.CallServerStream(ctx, req)
+}
+
 // GetSession calls voicetyped.dialog.v1.DialogService.GetSession.
 func (c *dialogServiceClient) GetSession(ctx context.Context, req *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error) {
 	return c.getSession.CallUnary(ctx, req)
@@ -135,13 +214,52 @@ func (c *dialogServiceClient) ListDialogs(ctx context.Context, req *connect.Requ
 	return c.listDialogs.CallUnary(ctx, req)
 }
 
+// CreateDialog calls voicetyped.dialog.v1.DialogService.CreateDialog.
+func (c *dialogServiceClient) CreateDialog(ctx context.Context, req *connect.Request[v1.CreateDialogRequest]) (*connect.Response[v1.CreateDialogResponse], error) {
+	return c.createDialog.CallUnary(ctx, req)
+}
+
+// UpdateDialog calls voicetyped.dialog.v1.DialogService.UpdateDialog.
+func (c *dialogServiceClient) UpdateDialog(ctx context.Context, req *connect.Request[v1.UpdateDialogRequest]) (*connect.Response[v1.UpdateDialogResponse], error) {
+	return c.updateDialog.CallUnary(ctx, req)
+}
+
+// DeleteDialog calls voicetyped.dialog.v1.DialogService.DeleteDialog.
+func (c *dialogServiceClient) DeleteDialog(ctx context.Context, req *connect.Request[v1.DeleteDialogRequest]) (*connect.Response[v1.DeleteDialogResponse], error) {
+	return c.deleteDialog.CallUnary(ctx, req)
+}
+
+// GetDialogDefinition calls voicetyped.dialog.v1.DialogService.GetDialogDefinition.
+func (c *dialogServiceClient) GetDialogDefinition(ctx context.Context, req *connect.Request[v1.GetDialogDefinitionRequest]) (*connect.Response[v1.GetDialogDefinitionResponse], error) {
+	return c.getDialogDefinition.CallUnary(ctx, req)
+}
+
+// ActivateDialogVersion calls voicetyped.dialog.v1.DialogService.ActivateDialogVersion.
+func (c *dialogServiceClient) ActivateDialogVersion(ctx context.Context, req *connect.Request[v1.ActivateDialogVersionRequest]) (*connect.Response[v1.ActivateDialogVersionResponse], error) {
+	return c.activateDialogVersion.CallUnary(ctx, req)
+}
+
 // DialogServiceHandler is an implementation of the voicetyped.dialog.v1.DialogService service.
 type DialogServiceHandler interface {
 	StartDialog(context.Context, *connect.Request[v1.StartDialogRequest]) (*connect.Response[v1.StartDialogResponse], error)
+	// SendEvent queues an event for the session's dialog loop. The actions it
+	// leads to are delivered on StreamActions.
 	SendEvent(context.Context, *connect.Request[v1.SendEventRequest]) (*connect.Response[v1.SendEventResponse], error)
+	// StreamActions delivers the result of every step the session's dialog
+	// loop processes, whether triggered by SendEvent or by the engine itself,
+	// e.g. a state timeout.
+	StreamActions(context.Context, *connect.Request[v1.StreamActionsRequest], *connect.ServerStream[v1.StreamActionsResponse]) error
 	GetSession(context.Context, *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error)
 	EndDialog(context.Context, *connect.Request[v1.EndDialogRequest]) (*connect.Response[v1.EndDialogResponse], error)
 	ListDialogs(context.Context, *connect.Request[v1.ListDialogsRequest]) (*connect.Response[v1.ListDialogsResponse], error)
+	// Dialog management. Dialogs created through the API are stored in the
+	// database and served next to the files in DIALOG_DIR; file dialogs can
+	// only be changed on disk.
+	CreateDialog(context.Context, *connect.Request[v1.CreateDialogRequest]) (*connect.Response[v1.CreateDialogResponse], error)
+	UpdateDialog(context.Context, *connect.Request[v1.UpdateDialogRequest]) (*connect.Response[v1.UpdateDialogResponse], error)
+	DeleteDialog(context.Context, *connect.Request[v1.DeleteDialogRequest]) (*connect.Response[v1.DeleteDialogResponse], error)
+	GetDialogDefinition(context.Context, *connect.Request[v1.GetDialogDefinitionRequest]) (*connect.Response[v1.GetDialogDefinitionResponse], error)
+	ActivateDialogVersion(context.Context, *connect.Request[v1.ActivateDialogVersionRequest]) (*connect.Response[v1.ActivateDialogVersionResponse], error)
 }
 
 // NewDialogServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -163,6 +281,12 @@ func NewDialogServiceHandler(svc DialogServiceHandler, opts ...connect.HandlerOp
 		connect.WithSchema(dialogServiceMethods.ByName("SendEvent")),
 		connect.WithHandlerOptions(opts...),
 	)
+	dialogServiceStreamActionsHandler := connect.NewServerStreamHandler(
+		DialogServiceStreamActionsProcedure,
+		svc.StreamActions,
+		connect.WithSchema(dialogServiceMethods.ByName("StreamActions")),
+		connect.WithHandlerOptions(opts...),
+	)
 	dialogServiceGetSessionHandler := connect.NewUnaryHandler(
 		DialogServiceGetSessionProcedure,
 		svc.GetSession,
@@ -181,18 +305,60 @@ func NewDialogServiceHandler(svc DialogServiceHandler, opts ...connect.HandlerOp
 		connect.WithSchema(dialogServiceMethods.ByName("ListDialogs")),
 		connect.WithHandlerOptions(opts...),
 	)
+	dialogServiceCreateDialogHandler := connect.NewUnaryHandler(
+		DialogServiceCreateDialogProcedure,
+		svc.CreateDialog,
+		connect.WithSchema(dialogServiceMethods.ByName("CreateDialog")),
+		connect.WithHandlerOptions(opts...),
+	)
+	dialogServiceUpdateDialogHandler := connect.NewUnaryHandler(
+		DialogServiceUpdateDialogProcedure,
+		svc.UpdateDialog,
+		connect.WithSchema(dialogServiceMethods.ByName("UpdateDialog")),
+		connect.WithHandlerOptions(opts...),
+	)
+	dialogServiceDeleteDialogHandler := connect.NewUnaryHandler(
+		DialogServiceDeleteDialogProcedure,
+		svc.DeleteDialog,
+		connect.WithSchema(dialogServiceMethods.ByName("DeleteDialog")),
+		connect.WithHandlerOptions(opts...),
+	)
+	dialogServiceGetDialogDefinitionHandler := connect.NewUnaryHandler(
+		DialogServiceGetDialogDefinitionProcedure,
+		svc.GetDialogDefinition,
+		connect.WithSchema(dialogServiceMethods.ByName("GetDialogDefinition")),
+		connect.WithHandlerOptions(opts...),
+	)
+	dialogServiceActivateDialogVersionHandler := connect.NewUnaryHandler(
+		DialogServiceActivateDialogVersionProcedure,
+		svc.ActivateDialogVersion,
+		connect.WithSchema(dialogServiceMethods.ByName("ActivateDialogVersion")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/voicetyped.dialog.v1.DialogService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case DialogServiceStartDialogProcedure:
 			dialogServiceStartDialogHandler.ServeHTTP(w, r)
 		case DialogServiceSendEventProcedure:
 			dialogServiceSendEventHandler.ServeHTTP(w, r)
+		case DialogServiceStreamActionsProcedure:
+			dialogServiceStreamActionsHandler.ServeHTTP(w, r)
 		case DialogServiceGetSessionProcedure:
 			dialogServiceGetSessionHandler.ServeHTTP(w, r)
 		case DialogServiceEndDialogProcedure:
 			dialogServiceEndDialogHandler.ServeHTTP(w, r)
 		case DialogServiceListDialogsProcedure:
 			dialogServiceListDialogsHandler.ServeHTTP(w, r)
+		case DialogServiceCreateDialogProcedure:
+			dialogServiceCreateDialogHandler.ServeHTTP(w, r)
+		case DialogServiceUpdateDialogProcedure:
+			dialogServiceUpdateDialogHandler.ServeHTTP(w, r)
+		case DialogServiceDeleteDialogProcedure:
+			dialogServiceDeleteDialogHandler.ServeHTTP(w, r)
+		case DialogServiceGetDialogDefinitionProcedure:
+			dialogServiceGetDialogDefinitionHandler.ServeHTTP(w, r)
+		case DialogServiceActivateDialogVersionProcedure:
+			dialogServiceActivateDialogVersionHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -210,6 +376,10 @@ func (UnimplementedDialogServiceHandler) SendEvent(context.Context, *connect.Req
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.SendEvent is not implemented"))
 }
 
+func (UnimplementedDialogServiceHandler) StreamActions(context.Context, *connect.Request[v1.StreamActionsRequest], *connect.ServerStream[v1.StreamActionsResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.StreamActions is not implemented"))
+}
+
 func (UnimplementedDialogServiceHandler) GetSession(context.Context, *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.GetSession is not implemented"))
 }
@@ -221,3 +391,23 @@ func (UnimplementedDialogServiceHandler) EndDialog(context.Context, *connect.Req
 func (UnimplementedDialogServiceHandler) ListDialogs(context.Context, *connect.Request[v1.ListDialogsRequest]) (*connect.Response[v1.ListDialogsResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.ListDialogs is not implemented"))
 }
+
+func (UnimplementedDialogServiceHandler) CreateDialog(context.Context, *connect.Request[v1.CreateDialogRequest]) (*connect.Response[v1.CreateDialogResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.CreateDialog is not implemented"))
+}
+
+func (UnimplementedDialogServiceHandler) UpdateDialog(context.Context, *connect.Request[v1.UpdateDialogRequest]) (*connect.Response[v1.UpdateDialogResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.UpdateDialog is not implemented"))
+}
+
+func (UnimplementedDialogServiceHandler) DeleteDialog(context.Context, *connect.Request[v1.DeleteDialogRequest]) (*connect.Response[v1.DeleteDialogResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.DeleteDialog is not implemented"))
+}
+
+func (UnimplementedDialogServiceHandler) GetDialogDefinition(context.Context, *connect.Request[v1.GetDialogDefinitionRequest]) (*connect.Response[v1.GetDialogDefinitionResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.GetDialogDefinition is not implemented"))
+}
+
+func (UnimplementedDialogServiceHandler) ActivateDialogVersion(context.Context, *connect.Request[v1.ActivateDialogVersionRequest]) (*connect.Response[v1.ActivateDialogVersionResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("voicetyped.dialog.v1.DialogService.ActivateDialogVersion is not implemented"))
+}
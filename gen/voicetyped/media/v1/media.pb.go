@@ -2263,9 +2263,12 @@ func (x *CreateSIPBridgeResponse) GetBridgePeerId() string {
 
 // PlayAudio streams audio frames into a room for playback.
 type PlayAudioRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	RoomId        string                 `protobuf:"bytes,1,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
-	Frame         *v1.AudioFrame         `protobuf:"bytes,2,opt,name=frame,proto3" json:"frame,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	RoomId string                 `protobuf:"bytes,1,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	Frame  *v1.AudioFrame         `protobuf:"bytes,2,opt,name=frame,proto3" json:"frame,omitempty"`
+	// Stops playback: this and any later frames are discarded and the
+	// response reports interrupted. Used for barge-in.
+	Stop          bool `protobuf:"varint,3,opt,name=stop,proto3" json:"stop,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -2314,9 +2317,17 @@ func (x *PlayAudioRequest) GetFrame() *v1.AudioFrame {
 	return nil
 }
 
+func (x *PlayAudioRequest) GetStop() bool {
+	if x != nil {
+		return x.Stop
+	}
+	return false
+}
+
 type PlayAudioResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FramesPlayed  int64                  `protobuf:"varint,1,opt,name=frames_played,json=framesPlayed,proto3" json:"frames_played,omitempty"`
+	Interrupted   bool                   `protobuf:"varint,2,opt,name=interrupted,proto3" json:"interrupted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -2358,6 +2369,13 @@ func (x *PlayAudioResponse) GetFramesPlayed() int64 {
 	return 0
 }
 
+func (x *PlayAudioResponse) GetInterrupted() bool {
+	if x != nil {
+		return x.Interrupted
+	}
+	return false
+}
+
 var File_voicetyped_media_v1_media_proto protoreflect.FileDescriptor
 
 const file_voicetyped_media_v1_media_proto_rawDesc = "" +
@@ -2524,12 +2542,14 @@ const file_voicetyped_media_v1_media_proto_rawDesc = "" +
 	"\aroom_id\x18\x01 \x01(\tR\x06roomId\x12\x17\n" +
 	"\asip_uri\x18\x02 \x01(\tR\x06sipUri\"?\n" +
 	"\x17CreateSIPBridgeResponse\x12$\n" +
-	"\x0ebridge_peer_id\x18\x01 \x01(\tR\fbridgePeerId\"c\n" +
+	"\x0ebridge_peer_id\x18\x01 \x01(\tR\fbridgePeerId\"w\n" +
 	"\x10PlayAudioRequest\x12\x17\n" +
 	"\aroom_id\x18\x01 \x01(\tR\x06roomId\x126\n" +
-	"\x05frame\x18\x02 \x01(\v2 .voicetyped.common.v1.AudioFrameR\x05frame\"8\n" +
+	"\x05frame\x18\x02 \x01(\v2 .voicetyped.common.v1.AudioFrameR\x05frame\x12\x12\n" +
+	"\x04stop\x18\x03 \x01(\bR\x04stop\"Z\n" +
 	"\x11PlayAudioResponse\x12#\n" +
-	"\rframes_played\x18\x01 \x01(\x03R\fframesPlayed*S\n" +
+	"\rframes_played\x18\x01 \x01(\x03R\fframesPlayed\x12 \n" +
+	"\vinterrupted\x18\x02 \x01(\bR\vinterrupted*S\n" +
 	"\tTrackKind\x12\x1a\n" +
 	"\x16TRACK_KIND_UNSPECIFIED\x10\x00\x12\x14\n" +
 	"\x10TRACK_KIND_AUDIO\x10\x01\x12\x14\n" +
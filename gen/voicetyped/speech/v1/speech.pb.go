@@ -199,12 +199,15 @@ func (x *TranscribeConfig) GetModel() string {
 }
 
 type TranscribeResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
-	Confidence    float32                `protobuf:"fixed32,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
-	IsFinal       bool                   `protobuf:"varint,3,opt,name=is_final,json=isFinal,proto3" json:"is_final,omitempty"`
-	Segments      []*TranscribeSegment   `protobuf:"bytes,4,rep,name=segments,proto3" json:"segments,omitempty"`
-	Language      string                 `protobuf:"bytes,5,opt,name=language,proto3" json:"language,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Text       string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Confidence float32                `protobuf:"fixed32,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	IsFinal    bool                   `protobuf:"varint,3,opt,name=is_final,json=isFinal,proto3" json:"is_final,omitempty"`
+	Segments   []*TranscribeSegment   `protobuf:"bytes,4,rep,name=segments,proto3" json:"segments,omitempty"`
+	Language   string                 `protobuf:"bytes,5,opt,name=language,proto3" json:"language,omitempty"`
+	// Set on a message without text when voice activity detection hears the
+	// caller start speaking, ahead of any transcript. Used for barge-in.
+	SpeechStarted bool `protobuf:"varint,6,opt,name=speech_started,json=speechStarted,proto3" json:"speech_started,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -274,6 +277,13 @@ func (x *TranscribeResponse) GetLanguage() string {
 	return ""
 }
 
+func (x *TranscribeResponse) GetSpeechStarted() bool {
+	if x != nil {
+		return x.SpeechStarted
+	}
+	return false
+}
+
 type TranscribeSegment struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
@@ -972,7 +982,7 @@ const file_voicetyped_speech_v1_speech_proto_rawDesc = "" +
 	"\vsample_rate\x18\x05 \x01(\x05R\n" +
 	"sampleRate\x12\x14\n" +
 	"\x05codec\x18\x06 \x01(\tR\x05codec\x12\x14\n" +
-	"\x05model\x18\a \x01(\tR\x05model\"\xc4\x01\n" +
+	"\x05model\x18\a \x01(\tR\x05model\"\xeb\x01\n" +
 	"\x12TranscribeResponse\x12\x12\n" +
 	"\x04text\x18\x01 \x01(\tR\x04text\x12\x1e\n" +
 	"\n" +
@@ -980,7 +990,8 @@ const file_voicetyped_speech_v1_speech_proto_rawDesc = "" +
 	"confidence\x12\x19\n" +
 	"\bis_final\x18\x03 \x01(\bR\aisFinal\x12C\n" +
 	"\bsegments\x18\x04 \x03(\v2'.voicetyped.speech.v1.TranscribeSegmentR\bsegments\x12\x1a\n" +
-	"\blanguage\x18\x05 \x01(\tR\blanguage\"y\n" +
+	"\blanguage\x18\x05 \x01(\tR\blanguage\x12%\n" +
+	"\x0espeech_started\x18\x06 \x01(\bR\rspeechStarted\"y\n" +
 	"\x11TranscribeSegment\x12\x12\n" +
 	"\x04text\x18\x01 \x01(\tR\x04text\x12\x19\n" +
 	"\bstart_ms\x18\x02 \x01(\x05R\astartMs\x12\x15\n" +
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
@@ -21,26 +23,30 @@ const (
 	sessionTTL      = 30 * time.Minute
 	reaperInterval  = 1 * time.Minute
 	endDialogWait   = 5 * time.Second
+	// finishedLinger is how long a session that reached a terminal state
+	// stays readable when no stream has delivered its last step.
+	finishedLinger = 1 * time.Minute
 )
 
 // Ensure we implement the interface.
 var _ dialogv1connect.DialogServiceHandler = (*DialogHandler)(nil)
 
-type actionResult struct {
-	actions  []dialog.Action
-	newState string
-	terminal bool
-	err      error
+type activeSession struct {
+	session *dialog.Session
+	sm      *dialog.StateMachine
+	eventCh chan dialog.Event
+	steps   *stepQueue
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when runDialogLoop exits
 }
 
-type activeSession struct {
-	session  *dialog.Session
-	sm       *dialog.StateMachine
-	speechCh chan dialog.ASRResult
-	dtmfCh   chan rune
-	resultCh chan actionResult
-	cancel   context.CancelFunc
-	done     chan struct{} // closed when runDialogLoop exits
+// SessionRepository persists dialog sessions so they survive restarts.
+// *dialog.Repository implements it.
+type SessionRepository interface {
+	SaveSession(ctx context.Context, rec *dialog.SessionRecord) error
+	UpdateSession(ctx context.Context, rec *dialog.SessionRecord) error
+	MarkInactive(ctx context.Context, id string) error
+	ListActive(ctx context.Context) ([]dialog.SessionRecord, error)
 }
 
 // SessionStore holds active dialog sessions.
@@ -51,20 +57,22 @@ type SessionStore struct {
 
 // DialogHandler implements dialogv1connect.DialogServiceHandler.
 type DialogHandler struct {
-	loader    *dialog.Loader
-	hookExec  *hooks.Executor
-	publisher *events.Publisher
-	store     SessionStore
-	pool      workerpool.WorkerPool
+	loader *dialog.Loader
+	engine *dialog.Engine
+	store  SessionStore
+	repo   SessionRepository
+	pool   workerpool.WorkerPool
 }
 
-// NewDialogHandler creates a new dialog service handler.
-func NewDialogHandler(loader *dialog.Loader, hookExec *hooks.Executor, pub *events.Publisher, pool workerpool.WorkerPool) *DialogHandler {
+// NewDialogHandler creates a new dialog service handler. When repo is nil
+// sessions are kept in memory only. engineOpts configure the dialog engine,
+// e.g. dialog.UseLLM.
+func NewDialogHandler(loader *dialog.Loader, hookExec *hooks.Executor, pub *events.Publisher, repo SessionRepository, pool workerpool.WorkerPool, engineOpts ...dialog.EngineOption) *DialogHandler {
 	return &DialogHandler{
-		loader:    loader,
-		hookExec:  hookExec,
-		publisher: pub,
-		pool:      pool,
+		loader: loader,
+		engine: dialog.NewEngineWithSource(loader, hookExec, pub, engineOpts...),
+		repo:   repo,
+		pool:   pool,
 		store: SessionStore{
 			sessions: make(map[string]*activeSession),
 		},
@@ -81,7 +89,7 @@ func (h *DialogHandler) StartReaper(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				h.reapStaleSessions()
+				h.reapStaleSessions(ctx)
 			}
 		}
 	}
@@ -92,24 +100,87 @@ func (h *DialogHandler) StartReaper(ctx context.Context) {
 	}
 }
 
-func (h *DialogHandler) reapStaleSessions() {
+func (h *DialogHandler) reapStaleSessions(ctx context.Context) {
 	now := time.Now()
+	var reaped []*activeSession
 	h.store.mu.Lock()
-	defer h.store.mu.Unlock()
 	for id, as := range h.store.sessions {
 		if now.Sub(as.session.StartTime) > sessionTTL {
 			slog.Warn("reaping stale dialog session", slog.String("session_id", id))
 			as.cancel()
 			delete(h.store.sessions, id)
+			reaped = append(reaped, as)
+		}
+	}
+	h.store.mu.Unlock()
+
+	for _, as := range reaped {
+		h.hangUp(ctx, as)
+		h.deactivate(ctx, as.session.ID)
+	}
+}
+
+// Resume restores the sessions still marked active in the repository, as if
+// StartDialog had been called for each in its persisted state. on_enter
+// actions are not replayed. Sessions whose dialog or state no longer exists
+// are marked inactive.
+func (h *DialogHandler) Resume(ctx context.Context) (int, error) {
+	if h.repo == nil {
+		return 0, nil
+	}
+	records, err := h.repo.ListActive(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list active sessions: %w", err)
+	}
+
+	resumed := 0
+	for i := range records {
+		session := records[i].Session()
+		sm, ok := h.loader.GetVersion(session.DialogName, session.DialogVersion)
+		if !ok {
+			// The pinned version is gone; carry on with the default one if
+			// it still has the session's state.
+			if sm, ok = h.loader.Get(session.DialogName); ok {
+				slog.Warn("resuming dialog session on a different version",
+					slog.String("session_id", session.ID),
+					slog.String("dialog", session.DialogName),
+					slog.String("version", session.DialogVersion),
+					slog.String("resumed_version", sm.Dialog().Version))
+			}
 		}
+		if ok {
+			_, ok = sm.GetState(session.GetCurrentState())
+		}
+		if !ok {
+			slog.Warn("dropping persisted dialog session",
+				slog.String("session_id", session.ID),
+				slog.String("dialog", session.DialogName),
+				slog.String("state", session.GetCurrentState()))
+			h.deactivate(ctx, session.ID)
+			continue
+		}
+
+		session.Pin(sm)
+		sessionCtx, cancel := context.WithCancel(context.Background())
+		as := newActiveSession(session, sm, cancel)
+		if !h.register(as) {
+			cancel()
+			continue
+		}
+		h.launch(sessionCtx, as)
+		resumed++
 	}
+	return resumed, nil
 }
 
 func (h *DialogHandler) StartDialog(ctx context.Context, req *connect.Request[dialogv1.StartDialogRequest]) (*connect.Response[dialogv1.StartDialogResponse], error) {
 	dialogName := req.Msg.DialogName
-	sm, ok := h.loader.Get(dialogName)
-	if !ok {
-		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("dialog %q not found", dialogName))
+	if h.exists(req.Msg.SessionId) {
+		return nil, sessionExists(req.Msg.SessionId)
+	}
+	sm, err := h.selectVersion(req.Msg)
+	if err != nil {
+		return nil, err
 	}
 
 	initialState := req.Msg.InitialState
@@ -118,57 +189,80 @@ func (h *DialogHandler) StartDialog(ctx context.Context, req *connect.Request[di
 	}
 
 	session := dialog.NewSession(req.Msg.SessionId, dialogName, initialState)
-	for k, v := range req.Msg.Variables {
-		session.SetVariable(k, v)
+	session.Pin(sm)
+	if err := session.InitVariables(req.Msg.Variables); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
 
-	// Get on_enter actions for the initial state.
-	state, ok := sm.GetState(initialState)
-	if !ok {
+	if _, ok := sm.GetState(initialState); !ok {
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("state %q not found in dialog %q", initialState, dialogName))
 	}
 
-	// Create channels for the background dialog loop.
-	speechCh := make(chan dialog.ASRResult, 8)
-	dtmfCh := make(chan rune, 16)
-	resultCh := make(chan actionResult, 8)
-
 	// Session context is independent of RPC contexts since the dialog session
 	// outlives individual RPC calls. Cancellation happens via EndDialog.
 	sessionCtx, cancel := context.WithCancel(context.Background())
 
-	as := &activeSession{
-		session:  session,
-		sm:       sm,
-		speechCh: speechCh,
-		dtmfCh:   dtmfCh,
-		resultCh: resultCh,
-		cancel:   cancel,
-		done:     make(chan struct{}),
+	// Enter the initial state: server-side actions run here, client-side
+	// actions come back as directives.
+	res, err := h.engine.Start(sessionCtx, session)
+	if err != nil {
+		cancel()
+		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 
-	h.store.mu.Lock()
-	h.store.sessions[session.ID] = as
-	h.store.mu.Unlock()
+	// A concurrent StartDialog may have taken the session ID meanwhile.
+	as := newActiveSession(session, sm, cancel)
+	if !h.register(as) {
+		cancel()
+		return nil, sessionExists(session.ID)
+	}
 
-	// Start background dialog engine loop.
-	loopFunc := func() { h.runDialogLoop(sessionCtx, as) }
-	if h.pool != nil {
-		_ = h.pool.Submit(sessionCtx, loopFunc)
-	} else {
-		go loopFunc()
+	if h.repo != nil {
+		rec := dialog.NewSessionRecord(session, req.Msg.RoomId, req.Msg.PeerId)
+		if err := h.repo.SaveSession(ctx, rec); err != nil {
+			slog.Warn("persisting dialog session failed",
+				slog.String("session_id", session.ID), slog.String("error", err.Error()))
+		}
 	}
 
-	// Collect on_enter actions for the initial state.
-	actions := actionsToDirectives(state.OnEnter)
+	h.launch(sessionCtx, as)
 
 	return connect.NewResponse(&dialogv1.StartDialogResponse{
-		SessionId:    session.ID,
-		CurrentState: initialState,
-		Actions:      actions,
+		SessionId:     session.ID,
+		CurrentState:  res.CurrentState,
+		Actions:       actionsToDirectives(res.Directives),
+		DialogVersion: session.GetDialogVersion(),
 	}), nil
 }
 
+// selectVersion resolves the dialog version a new session runs: the requested
+// version, one drawn from the requested weights, or the default version.
+func (h *DialogHandler) selectVersion(req *dialogv1.StartDialogRequest) (*dialog.StateMachine, error) {
+	if req.DialogVersion != "" {
+		sm, ok := h.loader.GetVersion(req.DialogName, req.DialogVersion)
+		if !ok {
+			return nil, connect.NewError(connect.CodeNotFound,
+				fmt.Errorf("dialog %q version %q not found", req.DialogName, req.DialogVersion))
+		}
+		return sm, nil
+	}
+
+	if len(req.VersionWeights) > 0 {
+		var total uint64
+		for _, w := range req.VersionWeights {
+			total += uint64(w)
+		}
+		if total == 0 {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("version_weights must not all be zero"))
+		}
+	}
+	sm, err := h.loader.Select(req.DialogName, req.VersionWeights)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	}
+	return sm, nil
+}
+
 func (h *DialogHandler) SendEvent(_ context.Context, req *connect.Request[dialogv1.SendEventRequest]) (*connect.Response[dialogv1.SendEventResponse], error) {
 	h.store.mu.RLock()
 	as, ok := h.store.sessions[req.Msg.SessionId]
@@ -178,46 +272,95 @@ func (h *DialogHandler) SendEvent(_ context.Context, req *connect.Request[dialog
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("session %q not found", req.Msg.SessionId))
 	}
 
-	previousState := as.session.GetCurrentState()
-
+	var ev dialog.Event
 	switch req.Msg.EventType {
-	case "speech":
-		// Use select to avoid blocking if the channel is full.
-		select {
-		case as.speechCh <- dialog.ASRResult{
-			Text:    req.Msg.EventData,
-			IsFinal: true,
-		}:
-		case <-time.After(5 * time.Second):
-			return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("dialog engine busy, cannot accept speech event"))
+	case dialog.EventSpeech:
+		ev = dialog.Event{Type: dialog.EventSpeech, Data: req.Msg.EventData, Confidence: req.Msg.Confidence}
+	case dialog.EventDTMF:
+		if len(req.Msg.EventData) == 0 {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("dtmf event requires a digit"))
 		}
-	case "dtmf":
-		if len(req.Msg.EventData) > 0 {
-			select {
-			case as.dtmfCh <- rune(req.Msg.EventData[0]):
-			case <-time.After(5 * time.Second):
-				return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("dialog engine busy, cannot accept dtmf event"))
+		for _, r := range req.Msg.EventData {
+			if !dialog.IsDTMFDigit(r) {
+				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid dtmf digit %q", r))
 			}
 		}
-	default:
+		// Several digits are delivered in order as individual DTMF events.
+		ev = dialog.Event{Type: dialog.EventDTMF, Data: req.Msg.EventData}
+	case dialog.EventTimeout, "":
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unsupported event type %q", req.Msg.EventType))
+	default:
+		// Generic events such as tts_complete are accepted when the dialog
+		// has a transition that listens for them.
+		if !as.sm.HandlesEvent(req.Msg.EventType) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unsupported event type %q", req.Msg.EventType))
+		}
+		ev = dialog.Event{Type: req.Msg.EventType}
+		if req.Msg.EventData != "" {
+			ev.Data = req.Msg.EventData
+		}
 	}
 
-	// Wait for the dialog engine to process and return results.
+	// The event is only queued; its outcome is delivered on StreamActions.
+	// Use select to avoid blocking if the channel is full.
+	stopped := connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("dialog of session %q has stopped", req.Msg.SessionId))
 	select {
-	case result := <-as.resultCh:
-		if result.err != nil {
-			return nil, connect.NewError(connect.CodeInternal, result.err)
+	case <-as.done:
+		return nil, stopped
+	default:
+	}
+	select {
+	case as.eventCh <- ev:
+	case <-as.done:
+		return nil, stopped
+	case <-time.After(5 * time.Second):
+		return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("dialog engine busy, cannot accept %s event", ev.Type))
+	}
+
+	return connect.NewResponse(&dialogv1.SendEventResponse{}), nil
+}
+
+// StreamActions sends the result of every step the session's dialog loop
+// processes, in order, including steps triggered by state timeouts. The
+// stream opens with a step without actions reporting the state the following
+// steps start from; steps processed while no stream was open come next. A
+// session has a single stream: opening another one ends the previous one
+// with Aborted. The stream ends when the dialog loop stops, after a terminal
+// state or on EndDialog, or with the error that stopped it.
+func (h *DialogHandler) StreamActions(ctx context.Context, req *connect.Request[dialogv1.StreamActionsRequest], stream *connect.ServerStream[dialogv1.StreamActionsResponse]) error {
+	h.store.mu.RLock()
+	as, ok := h.store.sessions[req.Msg.SessionId]
+	h.store.mu.RUnlock()
+
+	if !ok {
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("session %q not found", req.Msg.SessionId))
+	}
+
+	wake, first := as.steps.attach()
+	if err := stream.Send(first); err != nil {
+		return err
+	}
+	for {
+		steps, done, err := as.steps.take(wake)
+		for _, step := range steps {
+			if err := stream.Send(step); err != nil {
+				return err
+			}
+		}
+		switch {
+		case errors.Is(err, errStreamReplaced):
+			return connect.NewError(connect.CodeAborted, err)
+		case err != nil:
+			return connect.NewError(connect.CodeInternal, err)
+		case done:
+			return nil
 		}
 
-		return connect.NewResponse(&dialogv1.SendEventResponse{
-			PreviousState: previousState,
-			CurrentState:  result.newState,
-			Terminal:      result.terminal,
-			Actions:       actionsToDirectives(result.actions),
-		}), nil
-	case <-time.After(10 * time.Second):
-		return nil, connect.NewError(connect.CodeDeadlineExceeded, fmt.Errorf("dialog engine timeout"))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
 	}
 }
 
@@ -233,9 +376,11 @@ func (h *DialogHandler) GetSession(_ context.Context, req *connect.Request[dialo
 	// Use thread-safe session accessors.
 	sessionID := as.session.ID
 	dialogName := as.session.DialogName
+	dialogVersion := as.session.GetDialogVersion()
 	currentState := as.session.GetCurrentState()
 	variables := as.session.CopyVariables()
 	history := as.session.CopyHistory()
+	transcript := as.session.CopyTranscript()
 
 	historyProto := make([]*dialogv1.StateRecord, 0, len(history))
 	for _, r := range history {
@@ -247,16 +392,34 @@ func (h *DialogHandler) GetSession(_ context.Context, req *connect.Request[dialo
 		})
 	}
 
+	transcriptProto := make([]*dialogv1.TranscriptTurn, 0, len(transcript))
+	for _, t := range transcript {
+		transcriptProto = append(transcriptProto, &dialogv1.TranscriptTurn{
+			Kind:       t.Kind,
+			Text:       t.Text,
+			Confidence: t.Confidence,
+			Hook:       t.Hook,
+			Url:        t.URL,
+			Error:      t.Error,
+			State:      t.State,
+			Timestamp:  t.Timestamp.Format(time.RFC3339),
+			Tool:       t.Tool,
+			Arguments:  t.Arguments,
+		})
+	}
+
 	return connect.NewResponse(&dialogv1.GetSessionResponse{
-		SessionId:    sessionID,
-		DialogName:   dialogName,
-		CurrentState: currentState,
-		Variables:    variables,
-		History:      historyProto,
+		SessionId:     sessionID,
+		DialogName:    dialogName,
+		DialogVersion: dialogVersion,
+		CurrentState:  currentState,
+		Variables:     variables,
+		History:       historyProto,
+		Transcript:    transcriptProto,
 	}), nil
 }
 
-func (h *DialogHandler) EndDialog(_ context.Context, req *connect.Request[dialogv1.EndDialogRequest]) (*connect.Response[dialogv1.EndDialogResponse], error) {
+func (h *DialogHandler) EndDialog(ctx context.Context, req *connect.Request[dialogv1.EndDialogRequest]) (*connect.Response[dialogv1.EndDialogResponse], error) {
 	h.store.mu.Lock()
 	as, ok := h.store.sessions[req.Msg.SessionId]
 	if ok {
@@ -268,162 +431,288 @@ func (h *DialogHandler) EndDialog(_ context.Context, req *connect.Request[dialog
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("session %q not found", req.Msg.SessionId))
 	}
 
-	// Cancel and wait for the dialog loop to exit before closing channels.
+	// Cancel and wait for the dialog loop to exit. eventCh is left open:
+	// a concurrent SendEvent may still hold the session.
 	as.cancel()
-	select {
-	case <-as.done:
-	case <-time.After(endDialogWait):
-		slog.Warn("dialog loop did not exit in time", slog.String("session_id", req.Msg.SessionId))
-	}
-	close(as.speechCh)
-	close(as.dtmfCh)
+	h.hangUp(ctx, as)
+
+	h.deactivate(ctx, req.Msg.SessionId)
 
 	return connect.NewResponse(&dialogv1.EndDialogResponse{}), nil
 }
 
 func (h *DialogHandler) ListDialogs(_ context.Context, _ *connect.Request[dialogv1.ListDialogsRequest]) (*connect.Response[dialogv1.ListDialogsResponse], error) {
-	all := h.loader.All()
+	all := h.loader.AllVersions()
 
 	dialogs := make([]*dialogv1.DialogInfo, 0, len(all))
 	for _, sm := range all {
-		d := sm.Dialog()
-		states := make([]string, 0, len(d.States))
-		for name := range d.States {
-			states = append(states, name)
+		dialogs = append(dialogs, h.dialogInfo(sm))
+	}
+	sort.Slice(dialogs, func(i, j int) bool {
+		if dialogs[i].Name != dialogs[j].Name {
+			return dialogs[i].Name < dialogs[j].Name
 		}
+		return dialogs[i].Version < dialogs[j].Version
+	})
 
-		dialogs = append(dialogs, &dialogv1.DialogInfo{
-			Name:         d.Name,
-			Version:      d.Version,
-			Description:  d.Description,
-			InitialState: d.InitialState,
-			States:       states,
-		})
+	return connect.NewResponse(&dialogv1.ListDialogsResponse{Dialogs: dialogs}), nil
+}
+
+func (h *DialogHandler) CreateDialog(ctx context.Context, req *connect.Request[dialogv1.CreateDialogRequest]) (*connect.Response[dialogv1.CreateDialogResponse], error) {
+	sm, err := h.loader.CreateDialog(ctx, []byte(req.Msg.Definition))
+	if err != nil {
+		return nil, managementError(err)
 	}
+	return connect.NewResponse(&dialogv1.CreateDialogResponse{Dialog: h.dialogInfo(sm)}), nil
+}
 
-	return connect.NewResponse(&dialogv1.ListDialogsResponse{Dialogs: dialogs}), nil
+func (h *DialogHandler) UpdateDialog(ctx context.Context, req *connect.Request[dialogv1.UpdateDialogRequest]) (*connect.Response[dialogv1.UpdateDialogResponse], error) {
+	sm, err := h.loader.UpdateDialog(ctx, req.Msg.DialogName, []byte(req.Msg.Definition), req.Msg.Activate)
+	if err != nil {
+		return nil, managementError(err)
+	}
+	return connect.NewResponse(&dialogv1.UpdateDialogResponse{Dialog: h.dialogInfo(sm)}), nil
 }
 
-// runDialogLoop runs a simplified dialog event loop in the background.
+func (h *DialogHandler) DeleteDialog(ctx context.Context, req *connect.Request[dialogv1.DeleteDialogRequest]) (*connect.Response[dialogv1.DeleteDialogResponse], error) {
+	deleted, err := h.loader.DeleteDialog(ctx, req.Msg.DialogName, req.Msg.Version)
+	if err != nil {
+		return nil, managementError(err)
+	}
+	return connect.NewResponse(&dialogv1.DeleteDialogResponse{DeletedVersions: deleted}), nil
+}
+
+func (h *DialogHandler) GetDialogDefinition(_ context.Context, req *connect.Request[dialogv1.GetDialogDefinitionRequest]) (*connect.Response[dialogv1.GetDialogDefinitionResponse], error) {
+	var (
+		sm *dialog.StateMachine
+		ok bool
+	)
+	if req.Msg.Version == "" {
+		sm, ok = h.loader.Get(req.Msg.DialogName)
+	} else {
+		sm, ok = h.loader.GetVersion(req.Msg.DialogName, req.Msg.Version)
+	}
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound,
+			fmt.Errorf("dialog %q version %q not found", req.Msg.DialogName, req.Msg.Version))
+	}
+	return connect.NewResponse(&dialogv1.GetDialogDefinitionResponse{
+		Dialog:     h.dialogInfo(sm),
+		Definition: sm.Origin().Definition,
+	}), nil
+}
+
+func (h *DialogHandler) ActivateDialogVersion(ctx context.Context, req *connect.Request[dialogv1.ActivateDialogVersionRequest]) (*connect.Response[dialogv1.ActivateDialogVersionResponse], error) {
+	sm, err := h.loader.ActivateDialogVersion(ctx, req.Msg.DialogName, req.Msg.Version)
+	if err != nil {
+		return nil, managementError(err)
+	}
+	return connect.NewResponse(&dialogv1.ActivateDialogVersionResponse{Dialog: h.dialogInfo(sm)}), nil
+}
+
+// managementError maps a dialog management error to a Connect error.
+func managementError(err error) error {
+	switch {
+	case errors.Is(err, dialog.ErrInvalidDefinition):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, dialog.ErrDialogExists):
+		return connect.NewError(connect.CodeAlreadyExists, err)
+	case errors.Is(err, dialog.ErrDialogNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, dialog.ErrFileDialog), errors.Is(err, dialog.ErrNoDefinitionStore):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	default:
+		return connect.NewError(connect.CodeInternal, err)
+	}
+}
+
+// dialogInfo describes a loaded dialog version, with the history of every
+// loaded version of the dialog.
+func (h *DialogHandler) dialogInfo(sm *dialog.StateMachine) *dialogv1.DialogInfo {
+	d := sm.Dialog()
+	def, _ := h.loader.Get(d.Name)
+	states := make([]string, 0, len(d.States))
+	for name := range d.States {
+		states = append(states, name)
+	}
+	sort.Strings(states)
+
+	var history []*dialogv1.DialogVersionInfo
+	for _, v := range h.loader.Versions(d.Name) {
+		vsm, ok := h.loader.GetVersion(d.Name, v)
+		if !ok {
+			continue
+		}
+		info := &dialogv1.DialogVersionInfo{
+			Version:   v,
+			Source:    vsm.Origin().Source,
+			IsDefault: sameVersion(vsm, def),
+		}
+		if t := vsm.Origin().CreatedAt; !t.IsZero() {
+			info.CreatedAt = t.Format(time.RFC3339)
+		}
+		history = append(history, info)
+	}
+
+	return &dialogv1.DialogInfo{
+		Name:         d.Name,
+		Version:      d.Version,
+		Description:  d.Description,
+		InitialState: d.InitialState,
+		States:       states,
+		IsDefault:    sameVersion(sm, def),
+		Source:       sm.Origin().Source,
+		History:      history,
+	}
+}
+
+// sameVersion reports whether a and b are the same dialog version. Loaded
+// state machines are rebuilt whenever stored dialogs are reloaded, so they
+// are compared by name and version rather than by pointer.
+func sameVersion(a, b *dialog.StateMachine) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Dialog().Name == b.Dialog().Name && a.Dialog().Version == b.Dialog().Version
+}
+
+// runDialogLoop drives the dialog engine in the background, queueing the
+// outcome of every processed event for StreamActions.
 func (h *DialogHandler) runDialogLoop(ctx context.Context, as *activeSession) {
-	defer close(as.done)
+	err := h.engine.Run(ctx, as.session, as.eventCh, func(res *dialog.StepResult) error {
+		h.persist(ctx, as.session)
+		as.steps.push(&dialogv1.StreamActionsResponse{
+			PreviousState: res.PreviousState,
+			CurrentState:  res.CurrentState,
+			Terminal:      res.Terminal,
+			Actions:       actionsToDirectives(res.Directives),
+		})
+		return nil
+	})
+	if err != nil && ctx.Err() != nil {
+		err = nil
+	}
+	// The loop only stops by itself, without error, in a terminal state.
+	terminal := err == nil && ctx.Err() == nil
+	if err != nil {
+		slog.Warn("dialog loop failed",
+			slog.String("session_id", as.session.ID), slog.String("error", err.Error()))
+	}
+	// SendEvent rejects events once done is closed, before the stream ends.
+	close(as.done)
+	as.steps.close(err)
+	if terminal {
+		h.finish(ctx, as)
+	}
+}
+
+// finish ends a session whose dialog reached a terminal state. on_hangup
+// runs and the persisted session is marked inactive at once. The session
+// stays readable, for GetSession and a late StreamActions, until its stream
+// has delivered the terminal step or for finishedLinger, and is then removed
+// unless EndDialog or the reaper removed it first.
+func (h *DialogHandler) finish(ctx context.Context, as *activeSession) {
+	h.hangUp(ctx, as)
+	h.deactivate(ctx, as.session.ID)
+	as.cancel()
 
-	var timer *time.Timer
-	defer func() {
-		if timer != nil {
-			timer.Stop()
+	go func() {
+		timer := time.NewTimer(finishedLinger)
+		defer timer.Stop()
+		select {
+		case <-as.steps.delivered():
+		case <-timer.C:
+		}
+		h.store.mu.Lock()
+		if h.store.sessions[as.session.ID] == as {
+			delete(h.store.sessions, as.session.ID)
 		}
+		h.store.mu.Unlock()
 	}()
+}
 
-	for {
-		currentState := as.session.GetCurrentState()
+func newActiveSession(session *dialog.Session, sm *dialog.StateMachine, cancel context.CancelFunc) *activeSession {
+	current := session.GetCurrentState()
+	state, _ := sm.GetState(current)
+	return &activeSession{
+		session: session,
+		sm:      sm,
+		eventCh: make(chan dialog.Event, 16),
+		steps:   newStepQueue(session.ID, current, state.Terminal),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+}
 
-		state, ok := as.sm.GetState(currentState)
-		if !ok || state.Terminal {
-			as.resultCh <- actionResult{terminal: true, newState: currentState}
-			return
-		}
+// exists reports whether a session with the given ID is active.
+func (h *DialogHandler) exists(id string) bool {
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+	_, ok := h.store.sessions[id]
+	return ok
+}
 
-		// Set up timeout with proper timer management.
-		if dur, err := time.ParseDuration(state.Timeout); err == nil && dur > 0 {
-			if timer == nil {
-				timer = time.NewTimer(dur)
-			} else {
-				if !timer.Stop() {
-					select {
-					case <-timer.C:
-					default:
-					}
-				}
-				timer.Reset(dur)
-			}
-		} else if timer != nil {
-			timer.Stop()
-			timer = nil
-		}
+// register adds a session to the store unless its ID is taken.
+func (h *DialogHandler) register(as *activeSession) bool {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	if _, ok := h.store.sessions[as.session.ID]; ok {
+		return false
+	}
+	h.store.sessions[as.session.ID] = as
+	return true
+}
 
-		var timeoutCh <-chan time.Time
-		if timer != nil {
-			timeoutCh = timer.C
-		}
+func sessionExists(id string) error {
+	return connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("session %q is already active", id))
+}
 
-		select {
-		case <-ctx.Done():
-			return
+// launch starts the dialog loop of a registered session under the session
+// context ctx.
+func (h *DialogHandler) launch(ctx context.Context, as *activeSession) {
+	// Start background dialog engine loop.
+	loopFunc := func() { h.runDialogLoop(ctx, as) }
+	if h.pool != nil {
+		_ = h.pool.Submit(ctx, loopFunc)
+	} else {
+		go loopFunc()
+	}
+}
 
-		case result, ok := <-as.speechCh:
-			if !ok {
-				return
-			}
-			as.session.SetLastEvent(result.Text)
-			nextState, actions, err := as.sm.EvaluateTransitions(state, "speech", as.session)
-			if err != nil {
-				as.resultCh <- actionResult{err: err}
-				return
-			}
-			if nextState != "" {
-				as.session.RecordTransition(as.session.GetCurrentState(), nextState, result.Text)
-
-				newState, stateOk := as.sm.GetState(nextState)
-				if !stateOk {
-					as.resultCh <- actionResult{err: fmt.Errorf("state %q not found", nextState)}
-					return
-				}
-				allActions := append(actions, newState.OnEnter...)
-				as.resultCh <- actionResult{
-					actions:  allActions,
-					newState: nextState,
-					terminal: newState.Terminal,
-				}
-			} else {
-				curState := as.session.GetCurrentState()
-				as.resultCh <- actionResult{newState: curState}
-			}
+// hangUp waits for the cancelled dialog loop of as to exit, then runs the
+// dialog's on_hangup actions. Failures are logged; the session ends anyway.
+func (h *DialogHandler) hangUp(ctx context.Context, as *activeSession) {
+	select {
+	case <-as.done:
+	case <-time.After(endDialogWait):
+		slog.Warn("dialog loop did not exit in time", slog.String("session_id", as.session.ID))
+	}
+	if _, err := h.engine.End(ctx, as.session); err != nil {
+		slog.Warn("dialog on_hangup failed",
+			slog.String("session_id", as.session.ID), slog.String("error", err.Error()))
+	}
+}
 
-		case digit, ok := <-as.dtmfCh:
-			if !ok {
-				return
-			}
-			as.session.SetLastEvent(digit)
-			nextState, actions, err := as.sm.EvaluateTransitions(state, "dtmf", as.session)
-			if err != nil {
-				as.resultCh <- actionResult{err: err}
-				return
-			}
-			if nextState != "" {
-				as.session.RecordTransition(as.session.GetCurrentState(), nextState, string(digit))
-
-				newState, stateOk := as.sm.GetState(nextState)
-				if !stateOk {
-					as.resultCh <- actionResult{err: fmt.Errorf("state %q not found", nextState)}
-					return
-				}
-				allActions := append(actions, newState.OnEnter...)
-				as.resultCh <- actionResult{
-					actions:  allActions,
-					newState: nextState,
-					terminal: newState.Terminal,
-				}
-			} else {
-				curState := as.session.GetCurrentState()
-				as.resultCh <- actionResult{newState: curState}
-			}
+// persist writes the session's current state through to the repository.
+// Failures are logged; the in-memory session stays authoritative.
+func (h *DialogHandler) persist(ctx context.Context, session *dialog.Session) {
+	if h.repo == nil {
+		return
+	}
+	if err := h.repo.UpdateSession(ctx, dialog.NewSessionRecord(session, "", "")); err != nil {
+		slog.Warn("persisting dialog session failed",
+			slog.String("session_id", session.ID), slog.String("error", err.Error()))
+	}
+}
 
-		case <-timeoutCh:
-			if state.TimeoutNext != "" {
-				as.session.RecordTransition(as.session.GetCurrentState(), state.TimeoutNext, "timeout")
-
-				newState, stateOk := as.sm.GetState(state.TimeoutNext)
-				if !stateOk {
-					continue
-				}
-				as.resultCh <- actionResult{
-					actions:  newState.OnEnter,
-					newState: state.TimeoutNext,
-					terminal: newState.Terminal,
-				}
-			}
-		}
+// deactivate marks a persisted session as no longer live.
+func (h *DialogHandler) deactivate(ctx context.Context, id string) {
+	if h.repo == nil {
+		return
+	}
+	if err := h.repo.MarkInactive(ctx, id); err != nil {
+		slog.Warn("marking dialog session inactive failed",
+			slog.String("session_id", id), slog.String("error", err.Error()))
 	}
 }
 
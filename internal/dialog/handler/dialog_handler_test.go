@@ -2,11 +2,17 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
 
@@ -14,6 +20,7 @@ import (
 	"github.com/voicetyped/voicetyped/gen/voicetyped/dialog/v1/dialogv1connect"
 	"github.com/voicetyped/voicetyped/pkg/dialog"
 	"github.com/voicetyped/voicetyped/pkg/hooks"
+	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
 )
 
 const testDialogYAML = `
@@ -36,10 +43,12 @@ states:
     on_enter:
       - type: play_tts
         params:
-          text: "I heard you say something."
+          text: "I heard you say {{ .Event }}."
     transitions:
       - event: speech
         target: goodbye
+      - event: tts_complete
+        target: goodbye
   goodbye:
     on_enter:
       - type: play_tts
@@ -51,21 +60,35 @@ states:
 
 func setupDialogTestServer(t *testing.T) (dialogv1connect.DialogServiceClient, func()) {
 	t.Helper()
+	return serveDialogHandler(newTestDialogHandler(t, nil))
+}
+
+func newTestDialogHandler(t *testing.T, repo SessionRepository) *DialogHandler {
+	t.Helper()
+	loader := loadTestDialogs(t, map[string]string{"test-dialog.yaml": testDialogYAML})
+	hookExec := hooks.NewExecutor(nil)
+	return NewDialogHandler(loader, hookExec, nil, repo, nil)
+}
+
+// loadTestDialogs writes the given dialog files to a temp dir and loads them.
+func loadTestDialogs(t *testing.T, files map[string]string) *dialog.Loader {
+	t.Helper()
 
-	// Write test dialog to temp dir.
 	dir := t.TempDir()
-	if err := os.WriteFile(filepath.Join(dir, "test-dialog.yaml"), []byte(testDialogYAML), 0644); err != nil {
-		t.Fatalf("write test dialog: %v", err)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("write test dialog: %v", err)
+		}
 	}
 
 	loader := dialog.NewLoader(dir)
 	if _, err := loader.LoadAll(); err != nil {
 		t.Fatalf("load dialogs: %v", err)
 	}
+	return loader
+}
 
-	hookExec := hooks.NewExecutor(nil)
-	handler := NewDialogHandler(loader, hookExec, nil, nil)
-
+func serveDialogHandler(handler *DialogHandler) (dialogv1connect.DialogServiceClient, func()) {
 	mux := http.NewServeMux()
 	path, hdlr := dialogv1connect.NewDialogServiceHandler(handler)
 	mux.Handle(path, hdlr)
@@ -76,6 +99,31 @@ func setupDialogTestServer(t *testing.T) (dialogv1connect.DialogServiceClient, f
 	return client, server.Close
 }
 
+// streamActions opens the action stream of a session and checks its opening
+// step. Tests close it before the test server, which waits for open streams.
+func streamActions(t *testing.T, client dialogv1connect.DialogServiceClient, sessionID, state string) *connect.ServerStreamForClient[dialogv1.StreamActionsResponse] {
+	t.Helper()
+	stream, err := client.StreamActions(context.Background(), connect.NewRequest(&dialogv1.StreamActionsRequest{
+		SessionId: sessionID,
+	}))
+	if err != nil {
+		t.Fatalf("StreamActions: %v", err)
+	}
+	if first := nextStep(t, stream); first.CurrentState != state || len(first.Actions) != 0 {
+		t.Errorf("opening step = %+v, want state %s without actions", first, state)
+	}
+	return stream
+}
+
+// nextStep receives the next step from an action stream.
+func nextStep(t *testing.T, stream *connect.ServerStreamForClient[dialogv1.StreamActionsResponse]) *dialogv1.StreamActionsResponse {
+	t.Helper()
+	if !stream.Receive() {
+		t.Fatalf("action stream ended: %v", stream.Err())
+	}
+	return stream.Msg()
+}
+
 func TestStartDialog(t *testing.T) {
 	client, cleanup := setupDialogTestServer(t)
 	defer cleanup()
@@ -123,6 +171,42 @@ func TestStartDialogNotFound(t *testing.T) {
 	}
 }
 
+func TestStartDialogTypedVariables(t *testing.T) {
+	dialogYAML := strings.Replace(testDialogYAML, "initial_state: greeting", `initial_state: greeting
+variables:
+  attempts: {type: int, default: 0}`, 1)
+	loader := loadTestDialogs(t, map[string]string{"test-dialog.yaml": dialogYAML})
+	client, cleanup := serveDialogHandler(NewDialogHandler(loader, nil, nil, nil, nil))
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-bad",
+		DialogName: "test-dialog",
+		Variables:  map[string]string{"attempts": "several"},
+	}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("got %v, want InvalidArgument", err)
+	}
+
+	_, err = client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-typed",
+		DialogName: "test-dialog",
+		Variables:  map[string]string{"attempts": " 02"},
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+	resp, err := client.GetSession(context.Background(), connect.NewRequest(&dialogv1.GetSessionRequest{
+		SessionId: "session-typed",
+	}))
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got := resp.Msg.Variables["attempts"]; got != "2" {
+		t.Errorf("attempts = %q, want 2", got)
+	}
+}
+
 func TestSendEvent(t *testing.T) {
 	client, cleanup := setupDialogTestServer(t)
 	defer cleanup()
@@ -136,8 +220,11 @@ func TestSendEvent(t *testing.T) {
 		t.Fatalf("StartDialog: %v", err)
 	}
 
+	stream := streamActions(t, client, "session-2", "greeting")
+	defer stream.Close()
+
 	// Send speech event to transition from greeting to handle_input.
-	resp, err := client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
 		SessionId: "session-2",
 		EventType: "speech",
 		EventData: "hello there",
@@ -146,15 +233,22 @@ func TestSendEvent(t *testing.T) {
 		t.Fatalf("SendEvent: %v", err)
 	}
 
-	if resp.Msg.PreviousState != "greeting" {
-		t.Errorf("got previous state %q, want greeting", resp.Msg.PreviousState)
+	resp := nextStep(t, stream)
+	if resp.PreviousState != "greeting" {
+		t.Errorf("got previous state %q, want greeting", resp.PreviousState)
 	}
-	if resp.Msg.CurrentState != "handle_input" {
-		t.Errorf("got current state %q, want handle_input", resp.Msg.CurrentState)
+	if resp.CurrentState != "handle_input" {
+		t.Errorf("got current state %q, want handle_input", resp.CurrentState)
 	}
-	if resp.Msg.Terminal {
+	if resp.Terminal {
 		t.Error("expected non-terminal state")
 	}
+	if len(resp.Actions) != 1 {
+		t.Fatalf("got %d actions, want 1", len(resp.Actions))
+	}
+	if got := resp.Actions[0].Params["text"]; got != "I heard you say hello there." {
+		t.Errorf("got rendered text %q, want %q", got, "I heard you say hello there.")
+	}
 
 	// Clean up.
 	_, _ = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
@@ -174,6 +268,9 @@ func TestSendEventToTerminal(t *testing.T) {
 		t.Fatalf("StartDialog: %v", err)
 	}
 
+	stream := streamActions(t, client, "session-3", "greeting")
+	defer stream.Close()
+
 	// Move to handle_input.
 	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
 		SessionId: "session-3",
@@ -183,9 +280,10 @@ func TestSendEventToTerminal(t *testing.T) {
 	if err != nil {
 		t.Fatalf("first SendEvent: %v", err)
 	}
+	nextStep(t, stream)
 
 	// Move to goodbye (terminal).
-	resp, err := client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
 		SessionId: "session-3",
 		EventType: "speech",
 		EventData: "goodbye",
@@ -194,17 +292,62 @@ func TestSendEventToTerminal(t *testing.T) {
 		t.Fatalf("second SendEvent: %v", err)
 	}
 
-	if resp.Msg.CurrentState != "goodbye" {
-		t.Errorf("got state %q, want goodbye", resp.Msg.CurrentState)
+	resp := nextStep(t, stream)
+	if resp.CurrentState != "goodbye" {
+		t.Errorf("got state %q, want goodbye", resp.CurrentState)
 	}
-	if !resp.Msg.Terminal {
+	if !resp.Terminal {
 		t.Error("expected terminal state")
 	}
 
-	// Clean up.
-	_, _ = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
+	// The dialog loop stops at the terminal state, ending the stream.
+	if stream.Receive() || stream.Err() != nil {
+		t.Errorf("stream after terminal step: got %v, err %v; want end of stream", stream.Msg(), stream.Err())
+	}
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
 		SessionId: "session-3",
+		EventType: "speech",
+		EventData: "hello?",
 	}))
+	if code := connect.CodeOf(err); code != connect.CodeFailedPrecondition && code != connect.CodeNotFound {
+		t.Errorf("SendEvent after terminal: got %v, want FailedPrecondition or NotFound", err)
+	}
+
+	// Once the terminal step is delivered the session is removed.
+	waitSessionRemoved(t, client, "session-3")
+}
+
+// waitSessionRemoved waits for a session that reached a terminal state to be
+// removed.
+func waitSessionRemoved(t *testing.T, client dialogv1connect.DialogServiceClient, id string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := client.GetSession(context.Background(), connect.NewRequest(&dialogv1.GetSessionRequest{SessionId: id}))
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session %s not removed after its terminal step: GetSession error %v", id, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartDialogSessionExists(t *testing.T) {
+	client, cleanup := setupDialogTestServer(t)
+	defer cleanup()
+
+	start := connect.NewRequest(&dialogv1.StartDialogRequest{SessionId: "session-dup", DialogName: "test-dialog"})
+	if _, err := client.StartDialog(context.Background(), start); err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+	defer client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{SessionId: "session-dup"}))
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{SessionId: "session-dup", DialogName: "test-dialog"}))
+	if connect.CodeOf(err) != connect.CodeAlreadyExists {
+		t.Errorf("second StartDialog: got %v, want AlreadyExists", err)
+	}
 }
 
 func TestSendEventInvalidType(t *testing.T) {
@@ -236,6 +379,59 @@ func TestSendEventInvalidType(t *testing.T) {
 	}))
 }
 
+func TestSendEventGenericEvent(t *testing.T) {
+	client, cleanup := setupDialogTestServer(t)
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-7",
+		DialogName: "test-dialog",
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+
+	stream := streamActions(t, client, "session-7", "greeting")
+	defer stream.Close()
+
+	// tts_complete has no transition in greeting; the state is unchanged.
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId: "session-7",
+		EventType: "tts_complete",
+	}))
+	if err != nil {
+		t.Fatalf("SendEvent tts_complete: %v", err)
+	}
+	if resp := nextStep(t, stream); resp.CurrentState != "greeting" {
+		t.Errorf("got state %q, want greeting", resp.CurrentState)
+	}
+
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId: "session-7",
+		EventType: "speech",
+		EventData: "hello",
+	}))
+	if err != nil {
+		t.Fatalf("SendEvent speech: %v", err)
+	}
+	nextStep(t, stream)
+
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId: "session-7",
+		EventType: "tts_complete",
+	}))
+	if err != nil {
+		t.Fatalf("SendEvent tts_complete: %v", err)
+	}
+	if resp := nextStep(t, stream); resp.CurrentState != "goodbye" || !resp.Terminal {
+		t.Errorf("got state %q (terminal=%v), want terminal goodbye", resp.CurrentState, resp.Terminal)
+	}
+
+	_, _ = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
+		SessionId: "session-7",
+	}))
+}
+
 func TestSendEventSessionNotFound(t *testing.T) {
 	client, cleanup := setupDialogTestServer(t)
 	defer cleanup()
@@ -253,6 +449,115 @@ func TestSendEventSessionNotFound(t *testing.T) {
 	}
 }
 
+func TestStreamActionsTimeout(t *testing.T) {
+	dialogYAML := strings.Replace(testDialogYAML, `timeout: "30s"`, `timeout: "20ms"`, 1)
+	loader := loadTestDialogs(t, map[string]string{"test-dialog.yaml": dialogYAML})
+	client, cleanup := serveDialogHandler(NewDialogHandler(loader, nil, nil, nil, nil))
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-t1",
+		DialogName: "test-dialog",
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+
+	// The timeout fires before the stream is opened; its step is queued.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := client.GetSession(context.Background(), connect.NewRequest(&dialogv1.GetSessionRequest{
+			SessionId: "session-t1",
+		}))
+		if err != nil {
+			t.Fatalf("GetSession: %v", err)
+		}
+		if resp.Msg.CurrentState == "goodbye" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("state %q, want goodbye after the timeout", resp.Msg.CurrentState)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stream := streamActions(t, client, "session-t1", "greeting")
+	defer stream.Close()
+	resp := nextStep(t, stream)
+	if resp.PreviousState != "greeting" || resp.CurrentState != "goodbye" || !resp.Terminal {
+		t.Errorf("timeout step = %+v", resp)
+	}
+	if len(resp.Actions) != 2 || resp.Actions[1].Type != "hangup" {
+		t.Errorf("actions = %+v, want Goodbye and hangup", resp.Actions)
+	}
+	if stream.Receive() {
+		t.Errorf("unexpected step %+v", stream.Msg())
+	}
+}
+
+func TestStreamActionsReplaced(t *testing.T) {
+	client, cleanup := setupDialogTestServer(t)
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-s1",
+		DialogName: "test-dialog",
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+
+	first := streamActions(t, client, "session-s1", "greeting")
+	defer first.Close()
+	second := streamActions(t, client, "session-s1", "greeting")
+	defer second.Close()
+	if first.Receive() || connect.CodeOf(first.Err()) != connect.CodeAborted {
+		t.Errorf("replaced stream: got %v, want Aborted", first.Err())
+	}
+
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId: "session-s1",
+		EventType: "speech",
+		EventData: "hello",
+	}))
+	if err != nil {
+		t.Fatalf("SendEvent: %v", err)
+	}
+	if resp := nextStep(t, second); resp.CurrentState != "handle_input" {
+		t.Errorf("got state %q, want handle_input", resp.CurrentState)
+	}
+
+	// EndDialog ends the stream.
+	_, err = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
+		SessionId: "session-s1",
+	}))
+	if err != nil {
+		t.Fatalf("EndDialog: %v", err)
+	}
+	if second.Receive() || second.Err() != nil {
+		t.Errorf("stream after EndDialog: err %v, want end of stream", second.Err())
+	}
+}
+
+func TestStreamActionsSessionNotFound(t *testing.T) {
+	client, cleanup := setupDialogTestServer(t)
+	defer cleanup()
+
+	stream, err := client.StreamActions(context.Background(), connect.NewRequest(&dialogv1.StreamActionsRequest{
+		SessionId: "nonexistent",
+	}))
+	if err == nil {
+		defer stream.Close()
+		if stream.Receive() {
+			t.Fatal("got a step for a nonexistent session")
+		}
+		err = stream.Err()
+	}
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("got code %v, want NotFound", connect.CodeOf(err))
+	}
+}
+
 func TestGetSession(t *testing.T) {
 	client, cleanup := setupDialogTestServer(t)
 	defer cleanup()
@@ -285,6 +590,9 @@ func TestGetSession(t *testing.T) {
 	if resp.Msg.Variables["key"] != "value" {
 		t.Errorf("got variables %v, want key=value", resp.Msg.Variables)
 	}
+	if resp.Msg.DialogVersion != "1.0" {
+		t.Errorf("got dialog version %q, want 1.0", resp.Msg.DialogVersion)
+	}
 
 	_, _ = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
 		SessionId: "session-5",
@@ -319,6 +627,50 @@ func TestEndDialog(t *testing.T) {
 	}
 }
 
+func TestEndDialogRunsOnHangup(t *testing.T) {
+	var mu sync.Mutex
+	var states []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req hooks.HookRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		states = append(states, req.State)
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(hooks.HookResponse{})
+	}))
+	defer ts.Close()
+
+	dialogYAML := strings.Replace(testDialogYAML, "initial_state: greeting", `initial_state: greeting
+on_hangup:
+  - type: call_hook
+    params:
+      url: "`+ts.URL+`"`, 1)
+	loader := loadTestDialogs(t, map[string]string{"test-dialog.yaml": dialogYAML})
+	hookExec := hooks.NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	client, cleanup := serveDialogHandler(NewDialogHandler(loader, hookExec, nil, nil, nil))
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-hangup",
+		DialogName: "test-dialog",
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+	_, err = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
+		SessionId: "session-hangup",
+	}))
+	if err != nil {
+		t.Fatalf("EndDialog: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) != 1 || states[0] != "greeting" {
+		t.Errorf("on_hangup hook calls from states %v, want [greeting]", states)
+	}
+}
+
 func TestEndDialogNotFound(t *testing.T) {
 	client, cleanup := setupDialogTestServer(t)
 	defer cleanup()
@@ -360,3 +712,524 @@ func TestListDialogs(t *testing.T) {
 		t.Error("test-dialog not found in list")
 	}
 }
+
+func TestStartDialogVersions(t *testing.T) {
+	loader := loadTestDialogs(t, map[string]string{
+		"test-dialog.yaml":    testDialogYAML,
+		"test-dialog-v2.yaml": strings.Replace(testDialogYAML, `version: "1.0"`, `version: "2.0"`, 1),
+	})
+	client, cleanup := serveDialogHandler(NewDialogHandler(loader, hooks.NewExecutor(nil), nil, nil, nil))
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		req     *dialogv1.StartDialogRequest
+		want    string
+		errCode connect.Code
+	}{
+		{name: "default", req: &dialogv1.StartDialogRequest{}, want: "2.0"},
+		{name: "pinned", req: &dialogv1.StartDialogRequest{DialogVersion: "1.0"}, want: "1.0"},
+		{name: "weighted", req: &dialogv1.StartDialogRequest{VersionWeights: map[string]uint32{"1.0": 1, "2.0": 0}}, want: "1.0"},
+		{name: "unknown version", req: &dialogv1.StartDialogRequest{DialogVersion: "3.0"}, errCode: connect.CodeNotFound},
+		{name: "unknown weighted version", req: &dialogv1.StartDialogRequest{VersionWeights: map[string]uint32{"3.0": 1}}, errCode: connect.CodeNotFound},
+		{name: "zero weights", req: &dialogv1.StartDialogRequest{VersionWeights: map[string]uint32{"1.0": 0}}, errCode: connect.CodeInvalidArgument},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionID := fmt.Sprintf("session-v%d", i)
+			tt.req.SessionId = sessionID
+			tt.req.DialogName = "test-dialog"
+			resp, err := client.StartDialog(context.Background(), connect.NewRequest(tt.req))
+			if tt.errCode != 0 {
+				if connect.CodeOf(err) != tt.errCode {
+					t.Fatalf("got error %v, want code %v", err, tt.errCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("StartDialog: %v", err)
+			}
+			defer client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{SessionId: sessionID}))
+
+			if resp.Msg.DialogVersion != tt.want {
+				t.Errorf("started version %q, want %q", resp.Msg.DialogVersion, tt.want)
+			}
+			getResp, err := client.GetSession(context.Background(), connect.NewRequest(&dialogv1.GetSessionRequest{SessionId: sessionID}))
+			if err != nil {
+				t.Fatalf("GetSession: %v", err)
+			}
+			if getResp.Msg.DialogVersion != tt.want {
+				t.Errorf("session version %q, want %q", getResp.Msg.DialogVersion, tt.want)
+			}
+		})
+	}
+
+	listResp, err := client.ListDialogs(context.Background(), connect.NewRequest(&dialogv1.ListDialogsRequest{}))
+	if err != nil {
+		t.Fatalf("ListDialogs: %v", err)
+	}
+	var got []string
+	for _, d := range listResp.Msg.Dialogs {
+		got = append(got, fmt.Sprintf("%s@%s default=%v", d.Name, d.Version, d.IsDefault))
+	}
+	if want := "test-dialog@1.0 default=false, test-dialog@2.0 default=true"; strings.Join(got, ", ") != want {
+		t.Errorf("ListDialogs = %v, want %s", got, want)
+	}
+}
+
+// memorySessionRepository is an in-memory SessionRepository for tests.
+type memorySessionRepository struct {
+	mu      sync.Mutex
+	records map[string]dialog.SessionRecord
+}
+
+func newMemorySessionRepository() *memorySessionRepository {
+	return &memorySessionRepository{records: make(map[string]dialog.SessionRecord)}
+}
+
+func (m *memorySessionRepository) SaveSession(_ context.Context, rec *dialog.SessionRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := *rec
+	r.IsActive = true
+	m.records[rec.ID] = r
+	return nil
+}
+
+func (m *memorySessionRepository) UpdateSession(_ context.Context, rec *dialog.SessionRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[rec.ID]
+	if !ok || !r.IsActive {
+		return nil
+	}
+	r.CurrentState = rec.CurrentState
+	r.Variables = rec.Variables
+	r.History = rec.History
+	r.Transcript = rec.Transcript
+	r.Visits = rec.Visits
+	r.Attempts = rec.Attempts
+	r.Digits = rec.Digits
+	m.records[rec.ID] = r
+	return nil
+}
+
+func (m *memorySessionRepository) MarkInactive(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[id]; ok {
+		r.IsActive = false
+		m.records[id] = r
+	}
+	return nil
+}
+
+func (m *memorySessionRepository) ListActive(_ context.Context) ([]dialog.SessionRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []dialog.SessionRecord
+	for _, r := range m.records {
+		if r.IsActive {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *memorySessionRepository) get(id string) (dialog.SessionRecord, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[id]
+	return r, ok
+}
+
+func TestSessionPersistence(t *testing.T) {
+	repo := newMemorySessionRepository()
+	client, cleanup := serveDialogHandler(newTestDialogHandler(t, repo))
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-p1",
+		DialogName: "test-dialog",
+		Variables:  map[string]string{"caller": "alice"},
+		RoomId:     "room-1",
+		PeerId:     "peer-1",
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+
+	rec, ok := repo.get("session-p1")
+	if !ok {
+		t.Fatal("session not persisted on start")
+	}
+	if rec.CurrentState != "greeting" || rec.RoomID != "room-1" || rec.PeerID != "peer-1" || rec.Variables["caller"] != "alice" {
+		t.Errorf("persisted record = %+v", rec)
+	}
+
+	stream := streamActions(t, client, "session-p1", "greeting")
+	defer stream.Close()
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId:  "session-p1",
+		EventType:  "speech",
+		EventData:  "hello",
+		Confidence: 0.9,
+	}))
+	if err != nil {
+		t.Fatalf("SendEvent: %v", err)
+	}
+	// Steps are persisted before they are streamed.
+	nextStep(t, stream)
+
+	rec, _ = repo.get("session-p1")
+	if rec.CurrentState != "handle_input" {
+		t.Errorf("persisted state = %q, want handle_input", rec.CurrentState)
+	}
+	if len(rec.History) != 1 || rec.History[0].Trigger != "hello" {
+		t.Errorf("persisted history = %+v", rec.History)
+	}
+	if !slices.ContainsFunc(rec.Transcript, func(turn dialog.Turn) bool { return turn.Kind == dialog.TurnCaller && turn.Text == "hello" }) {
+		t.Errorf("persisted transcript = %+v, want the caller's hello", rec.Transcript)
+	}
+
+	resp, err := client.GetSession(context.Background(), connect.NewRequest(&dialogv1.GetSessionRequest{
+		SessionId: "session-p1",
+	}))
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	var caller *dialogv1.TranscriptTurn
+	for _, turn := range resp.Msg.Transcript {
+		if turn.Kind == dialog.TurnCaller {
+			caller = turn
+		}
+	}
+	if caller == nil || caller.Text != "hello" || caller.Confidence != 0.9 || caller.State != "greeting" || caller.Timestamp == "" {
+		t.Errorf("GetSession transcript = %v, want the caller's hello", resp.Msg.Transcript)
+	}
+
+	_, err = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
+		SessionId: "session-p1",
+	}))
+	if err != nil {
+		t.Fatalf("EndDialog: %v", err)
+	}
+	if rec, _ = repo.get("session-p1"); rec.IsActive {
+		t.Error("session still active after EndDialog")
+	}
+}
+
+func TestResumeSessions(t *testing.T) {
+	repo := newMemorySessionRepository()
+	live := dialog.NewSession("session-r1", "test-dialog", "handle_input")
+	live.SetVariable("caller", "bob")
+	live.RecordTransition("greeting", "handle_input", "hi")
+	_ = repo.SaveSession(context.Background(), dialog.NewSessionRecord(live, "room-1", "peer-1"))
+	stale := dialog.NewSession("session-r2", "removed-dialog", "start")
+	_ = repo.SaveSession(context.Background(), dialog.NewSessionRecord(stale, "", ""))
+
+	handler := newTestDialogHandler(t, repo)
+	n, err := handler.Resume(context.Background())
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("resumed %d sessions, want 1", n)
+	}
+	if rec, _ := repo.get("session-r2"); rec.IsActive {
+		t.Error("session for unknown dialog should be marked inactive")
+	}
+
+	client, cleanup := serveDialogHandler(handler)
+	defer cleanup()
+
+	getResp, err := client.GetSession(context.Background(), connect.NewRequest(&dialogv1.GetSessionRequest{
+		SessionId: "session-r1",
+	}))
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if getResp.Msg.CurrentState != "handle_input" || getResp.Msg.Variables["caller"] != "bob" || len(getResp.Msg.History) != 1 {
+		t.Errorf("resumed session = %+v", getResp.Msg)
+	}
+
+	stream := streamActions(t, client, "session-r1", "handle_input")
+	defer stream.Close()
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId: "session-r1",
+		EventType: "speech",
+		EventData: "bye",
+	}))
+	if err != nil {
+		t.Fatalf("SendEvent: %v", err)
+	}
+	if resp := nextStep(t, stream); resp.CurrentState != "goodbye" || !resp.Terminal {
+		t.Errorf("got state %q terminal=%v, want terminal goodbye", resp.CurrentState, resp.Terminal)
+	}
+
+	// Reaching the terminal state ends the session.
+	waitSessionRemoved(t, client, "session-r1")
+	if rec, _ := repo.get("session-r1"); rec.IsActive {
+		t.Error("session still active after its terminal state")
+	}
+}
+
+func TestReaperMarksSessionInactive(t *testing.T) {
+	repo := newMemorySessionRepository()
+	handler := newTestDialogHandler(t, repo)
+	client, cleanup := serveDialogHandler(handler)
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-p2",
+		DialogName: "test-dialog",
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+
+	handler.store.mu.Lock()
+	handler.store.sessions["session-p2"].session.StartTime = time.Now().Add(-2 * sessionTTL)
+	handler.store.mu.Unlock()
+
+	handler.reapStaleSessions(context.Background())
+
+	if rec, _ := repo.get("session-p2"); rec.IsActive {
+		t.Error("reaped session still marked active")
+	}
+	_, err = client.GetSession(context.Background(), connect.NewRequest(&dialogv1.GetSessionRequest{
+		SessionId: "session-p2",
+	}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("got code %v, want NotFound", connect.CodeOf(err))
+	}
+}
+
+const digitsDialogYAML = `
+name: digits-dialog
+initial_state: account
+states:
+  account:
+    on_enter:
+      - type: collect_digits
+        params:
+          variable: account
+          terminator: "#"
+    transitions:
+      - event: digits_collected
+        target: done
+  done:
+    on_enter:
+      - type: play_tts
+        params:
+          text: "Account {{ .Variables.account }}"
+    terminal: true
+`
+
+func TestSendEventMultipleDigits(t *testing.T) {
+	loader := loadTestDialogs(t, map[string]string{"digits-dialog.yaml": digitsDialogYAML})
+	client, cleanup := serveDialogHandler(NewDialogHandler(loader, hooks.NewExecutor(nil), nil, nil, nil))
+	defer cleanup()
+
+	_, err := client.StartDialog(context.Background(), connect.NewRequest(&dialogv1.StartDialogRequest{
+		SessionId:  "session-d1",
+		DialogName: "digits-dialog",
+	}))
+	if err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId: "session-d1",
+		EventType: "dtmf",
+		EventData: "12x",
+	}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("got code %v, want InvalidArgument", connect.CodeOf(err))
+	}
+
+	stream := streamActions(t, client, "session-d1", "account")
+	defer stream.Close()
+	_, err = client.SendEvent(context.Background(), connect.NewRequest(&dialogv1.SendEventRequest{
+		SessionId: "session-d1",
+		EventType: "dtmf",
+		EventData: "4711#",
+	}))
+	if err != nil {
+		t.Fatalf("SendEvent: %v", err)
+	}
+	resp := nextStep(t, stream)
+	if resp.CurrentState != "done" || !resp.Terminal {
+		t.Errorf("got state %q terminal=%v, want terminal done", resp.CurrentState, resp.Terminal)
+	}
+	if len(resp.Actions) != 1 || resp.Actions[0].Params["text"] != "Account 4711" {
+		t.Errorf("actions = %+v", resp.Actions)
+	}
+
+	_, _ = client.EndDialog(context.Background(), connect.NewRequest(&dialogv1.EndDialogRequest{
+		SessionId: "session-d1",
+	}))
+}
+
+// memoryDefinitionStore is an in-memory dialog.DefinitionStore for tests.
+type memoryDefinitionStore struct {
+	mu      sync.Mutex
+	records []dialog.DefinitionRecord
+}
+
+func (m *memoryDefinitionStore) ListDefinitions(_ context.Context) ([]dialog.DefinitionRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]dialog.DefinitionRecord(nil), m.records...), nil
+}
+
+func (m *memoryDefinitionStore) CreateDefinition(_ context.Context, rec *dialog.DefinitionRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec.ID = fmt.Sprintf("def-%d", len(m.records)+1)
+	rec.CreatedAt = time.Now()
+	if rec.IsActive {
+		m.deactivate(rec.Name)
+	}
+	m.records = append(m.records, *rec)
+	return nil
+}
+
+func (m *memoryDefinitionStore) ActivateDefinition(_ context.Context, name, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deactivate(name)
+	for i := range m.records {
+		if m.records[i].Name == name && m.records[i].DialogVersion == version {
+			m.records[i].IsActive = true
+		}
+	}
+	return nil
+}
+
+func (m *memoryDefinitionStore) DeleteDefinitions(_ context.Context, name, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.records[:0]
+	for _, r := range m.records {
+		if r.Name != name || (version != "" && r.DialogVersion != version) {
+			kept = append(kept, r)
+		}
+	}
+	m.records = kept
+	return nil
+}
+
+func (m *memoryDefinitionStore) deactivate(name string) {
+	for i := range m.records {
+		if m.records[i].Name == name {
+			m.records[i].IsActive = false
+		}
+	}
+}
+
+func TestDialogManagement(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "test-dialog.yaml"), []byte(testDialogYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loader := dialog.NewLoader(dir, dialog.Definitions(&memoryDefinitionStore{}))
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("load dialogs: %v", err)
+	}
+	client, cleanup := serveDialogHandler(NewDialogHandler(loader, hooks.NewExecutor(nil), nil, nil, nil))
+	defer cleanup()
+	ctx := context.Background()
+
+	managed := strings.Replace(testDialogYAML, "name: test-dialog", "name: managed", 1)
+	createResp, err := client.CreateDialog(ctx, connect.NewRequest(&dialogv1.CreateDialogRequest{Definition: managed}))
+	if err != nil {
+		t.Fatalf("CreateDialog: %v", err)
+	}
+	if d := createResp.Msg.Dialog; d.Name != "managed" || d.Source != dialog.SourceDatabase || !d.IsDefault {
+		t.Errorf("created %+v", d)
+	}
+
+	// A session started now keeps version 1.0 after 2.0 is activated.
+	if _, err := client.StartDialog(ctx, connect.NewRequest(&dialogv1.StartDialogRequest{SessionId: "managed-1", DialogName: "managed"})); err != nil {
+		t.Fatalf("StartDialog: %v", err)
+	}
+	defer client.EndDialog(ctx, connect.NewRequest(&dialogv1.EndDialogRequest{SessionId: "managed-1"}))
+
+	v2 := strings.Replace(managed, `version: "1.0"`, `version: "2.0"`, 1)
+	if _, err := client.UpdateDialog(ctx, connect.NewRequest(&dialogv1.UpdateDialogRequest{DialogName: "managed", Definition: v2})); err != nil {
+		t.Fatalf("UpdateDialog: %v", err)
+	}
+	actResp, err := client.ActivateDialogVersion(ctx, connect.NewRequest(&dialogv1.ActivateDialogVersionRequest{DialogName: "managed", Version: "2.0"}))
+	if err != nil {
+		t.Fatalf("ActivateDialogVersion: %v", err)
+	}
+	if d := actResp.Msg.Dialog; d.Version != "2.0" || !d.IsDefault {
+		t.Errorf("activated version %s is_default=%v, want 2.0 is_default=true", d.Version, d.IsDefault)
+	}
+	var history []string
+	for _, h := range actResp.Msg.Dialog.History {
+		history = append(history, fmt.Sprintf("%s default=%v", h.Version, h.IsDefault))
+		if h.CreatedAt == "" {
+			t.Errorf("version %s has no created_at", h.Version)
+		}
+	}
+	if want := "1.0 default=false, 2.0 default=true"; strings.Join(history, ", ") != want {
+		t.Errorf("history = %v, want %s", history, want)
+	}
+
+	getResp, err := client.GetSession(ctx, connect.NewRequest(&dialogv1.GetSessionRequest{SessionId: "managed-1"}))
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if getResp.Msg.DialogVersion != "1.0" {
+		t.Errorf("running session moved to version %s", getResp.Msg.DialogVersion)
+	}
+
+	defResp, err := client.GetDialogDefinition(ctx, connect.NewRequest(&dialogv1.GetDialogDefinitionRequest{DialogName: "managed", Version: "1.0"}))
+	if err != nil {
+		t.Fatalf("GetDialogDefinition: %v", err)
+	}
+	if defResp.Msg.Definition != managed {
+		t.Errorf("definition = %q, want the submitted YAML", defResp.Msg.Definition)
+	}
+
+	delResp, err := client.DeleteDialog(ctx, connect.NewRequest(&dialogv1.DeleteDialogRequest{DialogName: "managed"}))
+	if err != nil {
+		t.Fatalf("DeleteDialog: %v", err)
+	}
+	if got := strings.Join(delResp.Msg.DeletedVersions, ","); got != "1.0,2.0" {
+		t.Errorf("deleted %s, want 1.0,2.0", got)
+	}
+}
+
+func TestDialogManagementErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "test-dialog.yaml"), []byte(testDialogYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loader := dialog.NewLoader(dir, dialog.Definitions(&memoryDefinitionStore{}))
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("load dialogs: %v", err)
+	}
+	client, cleanup := serveDialogHandler(NewDialogHandler(loader, hooks.NewExecutor(nil), nil, nil, nil))
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := client.CreateDialog(ctx, connect.NewRequest(&dialogv1.CreateDialogRequest{Definition: testDialogYAML}))
+	if connect.CodeOf(err) != connect.CodeAlreadyExists {
+		t.Errorf("create existing: got %v, want AlreadyExists", err)
+	}
+	_, err = client.CreateDialog(ctx, connect.NewRequest(&dialogv1.CreateDialogRequest{Definition: "name: [broken"}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("create invalid: got %v, want InvalidArgument", err)
+	}
+	_, err = client.DeleteDialog(ctx, connect.NewRequest(&dialogv1.DeleteDialogRequest{DialogName: "test-dialog"}))
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Errorf("delete file dialog: got %v, want FailedPrecondition", err)
+	}
+	_, err = client.GetDialogDefinition(ctx, connect.NewRequest(&dialogv1.GetDialogDefinitionRequest{DialogName: "missing"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("get missing: got %v, want NotFound", err)
+	}
+}
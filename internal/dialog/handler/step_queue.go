@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+
+	dialogv1 "github.com/voicetyped/voicetyped/gen/voicetyped/dialog/v1"
+)
+
+// maxQueuedSteps bounds the steps kept for a session whose action stream is
+// not being read. Beyond it the oldest steps are dropped.
+const maxQueuedSteps = 64
+
+var errStreamReplaced = errors.New("action stream replaced by a newer one")
+
+// stepQueue buffers the step results of a session's dialog loop until its
+// action stream sends them, so the loop never waits for the orchestrator.
+// It has at most one reader; a new reader replaces the previous one.
+type stepQueue struct {
+	sessionID string
+
+	mu    sync.Mutex
+	steps []*dialogv1.StreamActionsResponse
+	done  bool
+	err   error
+	// state and terminal describe the session after the last step taken,
+	// where the next reader starts.
+	state    string
+	terminal bool
+	// wake belongs to the current reader and is signalled whenever there is
+	// something to take. It is closed when the reader is replaced.
+	wake chan struct{}
+	// drained is closed once a reader has taken the last step.
+	drained chan struct{}
+}
+
+func newStepQueue(sessionID, state string, terminal bool) *stepQueue {
+	return &stepQueue{sessionID: sessionID, state: state, terminal: terminal, drained: make(chan struct{})}
+}
+
+// push queues the result of a step.
+func (q *stepQueue) push(step *dialogv1.StreamActionsResponse) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.done {
+		return
+	}
+	if len(q.steps) == maxQueuedSteps {
+		slog.Warn("dropping unread dialog step",
+			slog.String("session_id", q.sessionID), slog.String("state", q.steps[0].CurrentState))
+		q.steps = q.steps[1:]
+	}
+	q.steps = append(q.steps, step)
+	q.signal()
+}
+
+// close marks the end of the dialog loop, with the error that stopped it if
+// any. Steps already queued are still delivered.
+func (q *stepQueue) close(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.done {
+		return
+	}
+	q.done, q.err = true, err
+	q.signal()
+}
+
+func (q *stepQueue) signal() {
+	if q.wake == nil {
+		return
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// attach makes the caller the queue's reader. It returns the channel that
+// wakes the reader and a step without actions reporting the state the queued
+// steps start from.
+func (q *stepQueue) attach() (chan struct{}, *dialogv1.StreamActionsResponse) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.wake != nil {
+		close(q.wake)
+	}
+	q.wake = make(chan struct{}, 1)
+	return q.wake, &dialogv1.StreamActionsResponse{
+		PreviousState: q.state,
+		CurrentState:  q.state,
+		Terminal:      q.terminal,
+	}
+}
+
+// take removes and returns the queued steps for the reader woken by wake,
+// and reports whether the loop has stopped and with which error. A replaced
+// reader gets no steps and errStreamReplaced.
+func (q *stepQueue) take(wake chan struct{}) ([]*dialogv1.StreamActionsResponse, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.wake != wake {
+		return nil, true, errStreamReplaced
+	}
+	steps := q.steps
+	q.steps = nil
+	if len(steps) > 0 {
+		last := steps[len(steps)-1]
+		q.state, q.terminal = last.CurrentState, last.Terminal
+	}
+	if q.done {
+		select {
+		case <-q.drained:
+		default:
+			close(q.drained)
+		}
+	}
+	return steps, q.done, q.err
+}
+
+// delivered returns a channel closed once the loop has stopped and a reader
+// has taken every step.
+func (q *stepQueue) delivered() <-chan struct{} {
+	return q.drained
+}
@@ -339,15 +339,26 @@ func (h *MediaHandler) Renegotiate(_ context.Context, req *connect.Request[media
 	}), nil
 }
 
-func (h *MediaHandler) PlayAudio(_ context.Context, stream *connect.ClientStream[mediav1.PlayAudioRequest]) (*connect.Response[mediav1.PlayAudioResponse], error) {
+// PlayAudio injects the streamed frames into a room as they arrive. A
+// request with stop set ends playback early: it and any later frames are
+// discarded and the response reports interrupted. A client that cancels the
+// stream instead gets no response, and frames already received stay played.
+func (h *MediaHandler) PlayAudio(ctx context.Context, stream *connect.ClientStream[mediav1.PlayAudioRequest]) (*connect.Response[mediav1.PlayAudioResponse], error) {
 	var framesPlayed int64
 	var roomID string
+	var interrupted bool
 
 	for stream.Receive() {
 		msg := stream.Msg()
 		if roomID == "" {
 			roomID = msg.RoomId
 		}
+		if msg.Stop {
+			interrupted = true
+		}
+		if interrupted {
+			continue
+		}
 
 		room, ok := h.sfu.GetRoom(msg.RoomId)
 		if !ok {
@@ -361,11 +372,15 @@ func (h *MediaHandler) PlayAudio(_ context.Context, stream *connect.ClientStream
 	}
 
 	if err := stream.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, connect.NewError(connect.CodeCanceled, ctx.Err())
+		}
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 
 	return connect.NewResponse(&mediav1.PlayAudioResponse{
 		FramesPlayed: framesPlayed,
+		Interrupted:  interrupted,
 	}), nil
 }
 
@@ -2,10 +2,13 @@ package runtime
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
@@ -44,12 +47,15 @@ func NewOrchestrator(mediaURL, speechURL, dialogURL string, pub *events.Publishe
 			ForceAttemptHTTP2:   true,
 		},
 	}
+	// The dialog's action stream lasts as long as the call, so the dialog
+	// client shares the transport without the overall timeout.
+	dialogClient := &http.Client{Transport: httpClient.Transport}
 	opts := connectutil.DefaultClientOptions()
 
 	return &Orchestrator{
 		media:         mediav1connect.NewMediaServiceClient(httpClient, mediaURL, opts...),
 		speech:        speechv1connect.NewSpeechServiceClient(httpClient, speechURL, opts...),
-		dialog:        dialogv1connect.NewDialogServiceClient(httpClient, dialogURL, opts...),
+		dialog:        dialogv1connect.NewDialogServiceClient(dialogClient, dialogURL, opts...),
 		pub:           pub,
 		defaultDialog: defaultDialog,
 		pool:          pool,
@@ -89,8 +95,12 @@ func (o *Orchestrator) HandleNewRoom(ctx context.Context, roomID, peerID, dialog
 		slog.String("dialog", dialogName),
 	)
 
+	// The audio and transcription streams are torn down when the call ends.
+	streamCtx, streamCancel := context.WithCancel(ctx)
+	defer streamCancel()
+
 	// 1. Subscribe to room audio.
-	audioStream, err := o.media.SubscribeAudio(ctx, connect.NewRequest(&mediav1.SubscribeAudioRequest{
+	audioStream, err := o.media.SubscribeAudio(streamCtx, connect.NewRequest(&mediav1.SubscribeAudioRequest{
 		RoomId: roomID,
 		PeerId: peerID,
 	}))
@@ -100,7 +110,7 @@ func (o *Orchestrator) HandleNewRoom(ctx context.Context, roomID, peerID, dialog
 	}
 
 	// 2. Start bidi transcription stream.
-	transcribeStream := o.speech.Transcribe(ctx)
+	transcribeStream := o.speech.Transcribe(streamCtx)
 
 	// Send transcription config.
 	// Audio from the SFU is Opus-encoded; the speech handler decodes to 16kHz PCM.
@@ -125,6 +135,8 @@ func (o *Orchestrator) HandleNewRoom(ctx context.Context, roomID, peerID, dialog
 	startResp, err := o.dialog.StartDialog(ctx, connect.NewRequest(&dialogv1.StartDialogRequest{
 		SessionId:  sessionID,
 		DialogName: dialogName,
+		RoomId:     roomID,
+		PeerId:     peerID,
 	}))
 	if err != nil {
 		slog.ErrorContext(ctx, "orchestrator: start dialog failed", slog.String("error", err.Error()))
@@ -140,8 +152,18 @@ func (o *Orchestrator) HandleNewRoom(ctx context.Context, roomID, peerID, dialog
 		}))
 	}()
 
-	// Execute initial actions.
-	o.executeActions(ctx, roomID, sessionID, startResp.Msg.Actions)
+	// The results of events, including state timeouts the dialog handles on
+	// its own, arrive on the session's action stream.
+	actionStream, err := o.dialog.StreamActions(streamCtx, connect.NewRequest(&dialogv1.StreamActionsRequest{
+		SessionId: sessionID,
+	}))
+	if err != nil {
+		slog.ErrorContext(ctx, "orchestrator: stream dialog actions failed", slog.String("error", err.Error()))
+		transcribeStream.CloseRequest()
+		transcribeStream.CloseResponse()
+		return
+	}
+	defer actionStream.Close()
 
 	// 4. Pipe audio from media to speech via worker pool.
 	pipeCtx, pipeCancel := context.WithCancel(ctx)
@@ -168,76 +190,146 @@ func (o *Orchestrator) HandleNewRoom(ctx context.Context, roomID, peerID, dialog
 		transcribeStream.CloseRequest()
 	}
 
-	if o.pool != nil {
-		if err := o.pool.Submit(pipeCtx, pipeFunc); err != nil {
-			slog.ErrorContext(ctx, "orchestrator: submit audio pipe failed", slog.String("error", err.Error()))
-			return
+	// 5. Receive ASR results. Any sign of caller speech interrupts a prompt
+	// played with barge-in; final results are queued for the main loop.
+	pb := &playback{}
+	finals := make(chan *speechv1.TranscribeResponse, 16)
+	receiveFunc := func() {
+		defer close(finals)
+		for {
+			resp, err := transcribeStream.Receive()
+			if err != nil {
+				if !errors.Is(err, io.EOF) && pipeCtx.Err() == nil {
+					slog.ErrorContext(ctx, "orchestrator: receive transcribe failed", slog.String("error", err.Error()))
+				}
+				return
+			}
+			if resp.SpeechStarted || resp.Text != "" {
+				if pb.interrupt() {
+					slog.InfoContext(ctx, "orchestrator: caller barged in", slog.String("session_id", sessionID))
+				}
+			}
+			if !resp.IsFinal {
+				continue
+			}
+			select {
+			case finals <- resp:
+			case <-pipeCtx.Done():
+				return
+			}
 		}
-	} else {
-		go pipeFunc()
 	}
 
-	// 5. Main loop: receive ASR results and forward to dialog.
-	for {
-		select {
-		case <-pipeCtx.Done():
-			// Audio pipe exited (peer left or stream error).
-			return
-		default:
+	steps := make(chan *dialogv1.StreamActionsResponse)
+	stepFunc := func() {
+		defer close(steps)
+		for actionStream.Receive() {
+			select {
+			case steps <- actionStream.Msg():
+			case <-pipeCtx.Done():
+				return
+			}
+		}
+		if err := actionStream.Err(); err != nil && pipeCtx.Err() == nil {
+			slog.ErrorContext(ctx, "orchestrator: dialog action stream failed", slog.String("error", err.Error()))
 		}
+	}
 
-		resp, err := transcribeStream.Receive()
-		if err != nil {
-			if err == io.EOF {
-				break
+	for _, fn := range []func(){pipeFunc, receiveFunc, stepFunc} {
+		if o.pool != nil {
+			if err := o.pool.Submit(pipeCtx, fn); err != nil {
+				slog.ErrorContext(ctx, "orchestrator: submit audio pipe failed", slog.String("error", err.Error()))
+				return
 			}
-			slog.ErrorContext(ctx, "orchestrator: receive transcribe failed", slog.String("error", err.Error()))
-			break
+		} else {
+			go fn()
 		}
+	}
 
-		if !resp.IsFinal {
-			continue
-		}
+	// Execute initial actions.
+	if o.dispatch(ctx, roomID, sessionID, pb, startResp.Msg.Actions, false) {
+		o.leave(ctx, roomID, peerID)
+		return
+	}
 
-		// Forward final ASR result to dialog.
-		eventResp, err := o.dialog.SendEvent(ctx, connect.NewRequest(&dialogv1.SendEventRequest{
-			SessionId: sessionID,
-			EventType: "speech",
-			EventData: resp.Text,
-		}))
-		if err != nil {
-			slog.ErrorContext(ctx, "orchestrator: send dialog event failed", slog.String("error", err.Error()))
-			continue
+	// 6. Main loop: forward final ASR results to dialog and execute the
+	// actions it streams back.
+	for {
+		select {
+		case <-pipeCtx.Done():
+			// Audio pipe exited (peer left or stream error).
+			return
+		case resp, ok := <-finals:
+			if !ok {
+				return
+			}
+			o.sendEvent(ctx, &dialogv1.SendEventRequest{
+				SessionId:  sessionID,
+				EventType:  "speech",
+				EventData:  resp.Text,
+				Confidence: resp.Confidence,
+			})
+		case step, ok := <-steps:
+			if !ok {
+				// The dialog loop stopped.
+				return
+			}
+			if o.dispatch(ctx, roomID, sessionID, pb, step.Actions, step.Terminal) {
+				// Dialog is done. Leave the room.
+				o.leave(ctx, roomID, peerID)
+				return
+			}
 		}
+	}
+}
 
-		// Execute returned actions.
-		o.executeActions(ctx, roomID, sessionID, eventResp.Msg.Actions)
+// dispatch executes the action directives of one dialog step and, when they
+// played a prompt to the end, reports tts_complete back to the dialog; the
+// follow-up actions arrive on the action stream. A prompt interrupted by the
+// caller is not reported: the caller's utterance is the next event. It returns
+// true once the dialog has ended or requested a hangup.
+func (o *Orchestrator) dispatch(ctx context.Context, roomID, sessionID string, pb *playback, actions []*dialogv1.ActionDirective, terminal bool) bool {
+	played, interrupted, hangup := o.executeActions(ctx, roomID, sessionID, pb, actions)
+	if hangup || terminal {
+		return true
+	}
+	if played && !interrupted {
+		o.sendEvent(ctx, &dialogv1.SendEventRequest{SessionId: sessionID, EventType: "tts_complete"})
+	}
+	return false
+}
 
-		if eventResp.Msg.Terminal {
-			// Dialog is done. Leave the room.
-			_, _ = o.media.LeaveRoom(ctx, connect.NewRequest(&mediav1.LeaveRoomRequest{
-				RoomId: roomID,
-				PeerId: peerID,
-			}))
-			break
-		}
+// sendEvent queues an event for the dialog. Failures are logged; the call
+// carries on.
+func (o *Orchestrator) sendEvent(ctx context.Context, ev *dialogv1.SendEventRequest) {
+	_, err := o.dialog.SendEvent(ctx, connect.NewRequest(ev))
+	// Dialogs without a tts_complete transition reject the event.
+	if err == nil || (ev.EventType == "tts_complete" && connect.CodeOf(err) == connect.CodeInvalidArgument) {
+		return
 	}
+	slog.ErrorContext(ctx, "orchestrator: send dialog event failed",
+		slog.String("event_type", ev.EventType), slog.String("error", err.Error()))
 }
 
-// executeActions processes action directives from the dialog engine.
-func (o *Orchestrator) executeActions(ctx context.Context, roomID, sessionID string, actions []*dialogv1.ActionDirective) {
+// executeActions processes action directives from the dialog engine. It
+// reports whether any prompt was played, whether the caller interrupted one,
+// and whether a hangup was requested. Once a prompt is interrupted, the
+// remaining prompts are skipped.
+func (o *Orchestrator) executeActions(ctx context.Context, roomID, sessionID string, pb *playback, actions []*dialogv1.ActionDirective) (played, interrupted, hangup bool) {
 	for _, action := range actions {
 		switch action.Type {
 		case "play_tts":
 			text := action.Params["text"]
-			if text == "" {
+			if text == "" || interrupted {
 				continue
 			}
-			o.playTTS(ctx, roomID, text)
+			bargeIn, _ := strconv.ParseBool(action.Params["barge_in"])
+			interrupted = o.playTTS(ctx, roomID, sessionID, text, pb, bargeIn)
+			played = true
 
 		case "hangup":
 			slog.InfoContext(ctx, "orchestrator: hangup action", slog.String("session_id", sessionID))
-			return
+			return played, interrupted, true
 
 		default:
 			slog.DebugContext(ctx, "orchestrator: unhandled action",
@@ -246,21 +338,46 @@ func (o *Orchestrator) executeActions(ctx context.Context, roomID, sessionID str
 			)
 		}
 	}
+	return played, interrupted, false
+}
+
+// leave removes the orchestrated peer from the room.
+func (o *Orchestrator) leave(ctx context.Context, roomID, peerID string) {
+	_, _ = o.media.LeaveRoom(ctx, connect.NewRequest(&mediav1.LeaveRoomRequest{
+		RoomId: roomID,
+		PeerId: peerID,
+	}))
 }
 
 // playTTS synthesizes text and streams the audio into the room via PlayAudio.
-func (o *Orchestrator) playTTS(ctx context.Context, roomID, text string) {
-	synthStream, err := o.speech.Synthesize(ctx, connect.NewRequest(&speechv1.SynthesizeRequest{
+// With bargeIn, caller speech stops playback; it reports whether it did.
+func (o *Orchestrator) playTTS(ctx context.Context, roomID, sessionID, text string, pb *playback, bargeIn bool) (interrupted bool) {
+	o.emitTTS(ctx, events.TTSStarted, sessionID, &events.TTSEventData{Text: text, BargeIn: bargeIn})
+	defer func() {
+		o.emitTTS(ctx, events.TTSCompleted, sessionID, &events.TTSEventData{Text: text, BargeIn: bargeIn, Interrupted: interrupted})
+	}()
+
+	playCtx, done := pb.start(ctx, bargeIn)
+	defer done()
+
+	synthStream, err := o.speech.Synthesize(playCtx, connect.NewRequest(&speechv1.SynthesizeRequest{
 		Text: text,
 	}))
 	if err != nil {
+		if bargedIn(playCtx, ctx) {
+			return true
+		}
 		slog.ErrorContext(ctx, "orchestrator: synthesize failed", slog.String("error", err.Error()))
-		return
+		return false
 	}
+	defer synthStream.Close()
 
 	playStream := o.media.PlayAudio(ctx)
 
 	for synthStream.Receive() {
+		if playCtx.Err() != nil {
+			break
+		}
 		msg := synthStream.Msg()
 		if msg.Audio == nil {
 			continue
@@ -279,7 +396,65 @@ func (o *Orchestrator) playTTS(ctx context.Context, roomID, text string) {
 		}
 	}
 
+	if bargedIn(playCtx, ctx) {
+		interrupted = true
+		if err := playStream.Send(&mediav1.PlayAudioRequest{RoomId: roomID, Stop: true}); err != nil {
+			slog.ErrorContext(ctx, "orchestrator: play audio stop failed", slog.String("error", err.Error()))
+		}
+	}
+
 	if _, err := playStream.CloseAndReceive(); err != nil {
 		slog.ErrorContext(ctx, "orchestrator: play audio close failed", slog.String("error", err.Error()))
 	}
+	return interrupted
+}
+
+func (o *Orchestrator) emitTTS(ctx context.Context, eventType events.EventType, sessionID string, data *events.TTSEventData) {
+	if o.pub != nil {
+		_ = o.pub.Emit(ctx, eventType, sessionID, data)
+	}
+}
+
+// playback tracks the prompt being played in a call so that caller speech
+// can interrupt it.
+type playback struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// start returns the context a prompt is played under and a func to call once
+// it has finished. With bargeIn, interrupt cancels the context.
+func (p *playback) start(ctx context.Context, bargeIn bool) (context.Context, func()) {
+	if !bargeIn {
+		return ctx, func() {}
+	}
+	playCtx, cancel := context.WithCancel(ctx)
+	p.mu.Lock()
+	p.cancel = cancel
+	p.mu.Unlock()
+	return playCtx, func() {
+		p.mu.Lock()
+		p.cancel = nil
+		p.mu.Unlock()
+		cancel()
+	}
+}
+
+// interrupt stops the prompt being played, if it allows barge-in, and
+// reports whether there was one.
+func (p *playback) interrupt() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel == nil {
+		return false
+	}
+	p.cancel()
+	p.cancel = nil
+	return true
+}
+
+// bargedIn reports whether the prompt context playCtx was cancelled by
+// playback.interrupt rather than by the call ending.
+func bargedIn(playCtx, ctx context.Context) bool {
+	return playCtx.Err() != nil && ctx.Err() == nil
 }
@@ -108,3 +108,39 @@ func rmsEnergy(pcm []byte) float64 {
 
 	return math.Sqrt(sumSquares / float64(numSamples))
 }
+
+// VADWriter runs voice activity detection over 16-bit PCM audio written to
+// it, cutting the stream into frames of the configured size. It calls
+// onSpeechStart each time speech starts. Writes never fail.
+type VADWriter struct {
+	vad           *VAD
+	frameBytes    int
+	buf           []byte
+	onSpeechStart func()
+}
+
+// NewVADWriter creates a VADWriter with the given detection parameters.
+func NewVADWriter(cfg VADConfig, onSpeechStart func()) *VADWriter {
+	vad := NewVAD(cfg)
+	return &VADWriter{
+		vad:           vad,
+		frameBytes:    vad.frameSamples * 2,
+		onSpeechStart: onSpeechStart,
+	}
+}
+
+// Write implements io.Writer.
+func (w *VADWriter) Write(p []byte) (int, error) {
+	if w.frameBytes <= 0 {
+		return len(p), nil
+	}
+	w.buf = append(w.buf, p...)
+	off := 0
+	for ; len(w.buf)-off >= w.frameBytes; off += w.frameBytes {
+		if w.vad.ProcessFrame(w.buf[off:off+w.frameBytes]) == VADSpeechStart {
+			w.onSpeechStart()
+		}
+	}
+	w.buf = w.buf[:copy(w.buf, w.buf[off:])]
+	return len(p), nil
+}
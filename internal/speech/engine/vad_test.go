@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// pcmTone returns ms milliseconds of 16kHz 16-bit PCM at a constant level.
+func pcmTone(ms int, level int16) []byte {
+	pcm := make([]byte, 16*ms*2)
+	for i := 0; i < len(pcm); i += 2 {
+		binary.LittleEndian.PutUint16(pcm[i:], uint16(level))
+	}
+	return pcm
+}
+
+func TestVADWriter(t *testing.T) {
+	starts := 0
+	w := NewVADWriter(DefaultVADConfig(), func() { starts++ })
+
+	// Writes of any size are cut into frames: speech is detected after
+	// 200ms of loud audio, even when written in odd-sized pieces.
+	loud := pcmTone(300, 2000)
+	for len(loud) > 0 {
+		n := min(777, len(loud))
+		if _, err := w.Write(loud[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		loud = loud[n:]
+	}
+	if starts != 1 {
+		t.Fatalf("speech starts = %d after 300ms of speech, want 1", starts)
+	}
+
+	w.Write(pcmTone(1000, 0))
+	w.Write(pcmTone(300, 2000))
+	if starts != 2 {
+		t.Errorf("speech starts = %d after silence and speech, want 2", starts)
+	}
+}
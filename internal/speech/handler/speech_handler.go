@@ -13,6 +13,7 @@ import (
 	speechv1 "github.com/voicetyped/voicetyped/gen/voicetyped/speech/v1"
 	"github.com/voicetyped/voicetyped/gen/voicetyped/speech/v1/speechv1connect"
 	"github.com/voicetyped/voicetyped/internal/speech/codec"
+	"github.com/voicetyped/voicetyped/internal/speech/engine"
 	"github.com/voicetyped/voicetyped/internal/speech/registry"
 )
 
@@ -104,10 +105,22 @@ func (h *SpeechHandler) Transcribe(ctx context.Context, stream *connect.BidiStre
 	// Create a pipe to feed audio from the stream to the ASR engine.
 	pr, pw := io.Pipe()
 
+	// Run voice activity detection on the PCM fed to the engine so the
+	// caller can react to speech before it is transcribed (barge-in).
+	speechStarted := make(chan struct{}, 1)
+	vadCfg := engine.DefaultVADConfig()
+	vadCfg.SampleRate = int(sampleRate)
+	vad := engine.NewVADWriter(vadCfg, func() {
+		select {
+		case speechStarted <- struct{}{}:
+		default:
+		}
+	})
+
 	// If Opus, wrap the pipe writer with a decoder.
-	var audioWriter io.Writer = pw
+	var audioWriter io.Writer = io.MultiWriter(pw, vad)
 	if needsOpusDecode {
-		audioWriter = codec.NewOpusToPCM16Writer(pw)
+		audioWriter = codec.NewOpusToPCM16Writer(audioWriter)
 	}
 
 	// Read audio frames from stream and write to pipe via worker pool.
@@ -141,8 +154,22 @@ func (h *SpeechHandler) Transcribe(ctx context.Context, stream *connect.BidiStre
 		return connect.NewError(connect.CodeInternal, err)
 	}
 
-	// Forward results to the stream.
-	for result := range resultsCh {
+	// Forward results and speech starts to the stream.
+	for {
+		var result engine.ASRResult
+		select {
+		case <-speechStarted:
+			if err := stream.Send(&speechv1.TranscribeResponse{SpeechStarted: true}); err != nil {
+				return err
+			}
+			continue
+		case r, ok := <-resultsCh:
+			if !ok {
+				return nil
+			}
+			result = r
+		}
+
 		segments := make([]*speechv1.TranscribeSegment, 0, len(result.Segments))
 		for _, s := range result.Segments {
 			segments = append(segments, &speechv1.TranscribeSegment{
@@ -163,8 +190,6 @@ func (h *SpeechHandler) Transcribe(ctx context.Context, stream *connect.BidiStre
 			return err
 		}
 	}
-
-	return nil
 }
 
 func (h *SpeechHandler) Synthesize(ctx context.Context, req *connect.Request[speechv1.SynthesizeRequest], stream *connect.ServerStream[speechv1.SynthesizeResponse]) error {
@@ -0,0 +1,2 @@
+-- Dialog version each session is pinned to.
+ALTER TABLE dialog_sessions ADD COLUMN IF NOT EXISTS dialog_version VARCHAR(100);
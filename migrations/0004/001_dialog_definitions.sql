@@ -0,0 +1,19 @@
+-- Dialog definitions managed through the DialogService API. Each row is one
+-- version of a dialog; at most one version per dialog is active.
+CREATE TABLE IF NOT EXISTS dialog_definitions (
+    id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    dialog_version VARCHAR(100) NOT NULL,
+    definition TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ,
+    tenant_id VARCHAR(50) NOT NULL DEFAULT '',
+    partition_id VARCHAR(50) NOT NULL DEFAULT '',
+    access_id VARCHAR(50) NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dialog_definitions_version ON dialog_definitions (name, dialog_version) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dialog_definitions_active ON dialog_definitions (name) WHERE is_active AND deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- Ordered log of caller utterances, prompts, DTMF keys and hook calls.
+ALTER TABLE dialog_sessions ADD COLUMN IF NOT EXISTS transcript JSONB DEFAULT '[]';
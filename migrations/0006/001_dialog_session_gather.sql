@@ -0,0 +1,5 @@
+-- Gather progress: per-state visit and attempt counters and the
+-- collect_digits buffer in progress.
+ALTER TABLE dialog_sessions ADD COLUMN IF NOT EXISTS visits JSONB DEFAULT '{}';
+ALTER TABLE dialog_sessions ADD COLUMN IF NOT EXISTS attempts JSONB DEFAULT '{}';
+ALTER TABLE dialog_sessions ADD COLUMN IF NOT EXISTS digits JSONB;
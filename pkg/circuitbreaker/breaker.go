@@ -0,0 +1,152 @@
+// Package circuitbreaker stops calls to an endpoint that keeps failing, so
+// callers fail fast instead of waiting on it, and lets trial calls through
+// once a reset timeout has passed. It is shared by webhook delivery and
+// dialog hooks.
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// Outcome is how a request let through by Allow ended.
+type Outcome int
+
+const (
+	// Success means the endpoint answered.
+	Success Outcome = iota
+	// Failure means the endpoint failed or could not be reached.
+	Failure
+	// Abandoned means the request ended before reaching the endpoint, for
+	// example because the caller went away. It says nothing about the
+	// endpoint, but frees a half-open trial for another request.
+	Abandoned
+)
+
+// Config holds the parameters for a circuit breaker.
+type Config struct {
+	FailureThreshold int
+	ResetTimeout     time.Duration
+	// HalfOpenMaxAttempts is how many trial requests a half-open breaker
+	// lets through at a time, and how many must succeed to close it.
+	HalfOpenMaxAttempts int
+	// OnStateChange, if set, is called after every state change, outside
+	// the breaker's lock.
+	OnStateChange func(from, to string)
+}
+
+// Breaker implements a per-endpoint circuit breaker pattern.
+type Breaker struct {
+	mu              sync.Mutex
+	state           string
+	failures        int
+	successes       int
+	lastFailureTime time.Time
+	// trials counts half-open requests in flight. halfOpens numbers the
+	// half-open periods, so a trial ending after its period is not counted
+	// against the next one.
+	trials    int
+	halfOpens int
+	config    Config
+}
+
+// New creates a circuit breaker with the given config.
+func New(cfg Config) *Breaker {
+	if cfg.HalfOpenMaxAttempts <= 0 {
+		cfg.HalfOpenMaxAttempts = 1
+	}
+	return &Breaker{
+		state:  StateClosed,
+		config: cfg,
+	}
+}
+
+// Allow reports whether a request should be attempted. If it should, done
+// must be called exactly once with the request's outcome.
+func (cb *Breaker) Allow() (done func(Outcome), ok bool) {
+	cb.mu.Lock()
+	from := cb.state
+	if cb.state == StateOpen && time.Since(cb.lastFailureTime) > cb.config.ResetTimeout {
+		cb.state = StateHalfOpen
+		cb.successes = 0
+		cb.trials = 0
+		cb.halfOpens++
+	}
+	to := cb.state
+
+	trial := -1
+	switch cb.state {
+	case StateOpen:
+		ok = false
+	case StateHalfOpen:
+		ok = cb.trials < cb.config.HalfOpenMaxAttempts
+		if ok {
+			cb.trials++
+			trial = cb.halfOpens
+		}
+	default:
+		ok = true
+	}
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	if !ok {
+		return nil, false
+	}
+	var once sync.Once
+	return func(o Outcome) {
+		once.Do(func() { cb.record(o, trial) })
+	}, true
+}
+
+// record applies the outcome of a request. trial is the half-open period the
+// request was a trial of, or -1.
+func (cb *Breaker) record(o Outcome, trial int) {
+	cb.mu.Lock()
+	from := cb.state
+	if trial == cb.halfOpens && cb.state == StateHalfOpen {
+		cb.trials--
+	}
+	switch o {
+	case Success:
+		cb.failures = 0
+		if cb.state == StateHalfOpen {
+			cb.successes++
+			if cb.successes >= cb.config.HalfOpenMaxAttempts {
+				cb.state = StateClosed
+			}
+		} else {
+			cb.state = StateClosed
+		}
+	case Failure:
+		cb.failures++
+		cb.lastFailureTime = time.Now()
+		if cb.state == StateHalfOpen || cb.failures >= cb.config.FailureThreshold {
+			cb.state = StateOpen
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+}
+
+// State returns the current circuit breaker state.
+func (cb *Breaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+func (cb *Breaker) notify(from, to string) {
+	if from != to && cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(from, to)
+	}
+}
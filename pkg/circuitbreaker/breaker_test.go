@@ -0,0 +1,158 @@
+package circuitbreaker
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// request lets a request through cb, if it may pass, and records its outcome.
+func request(cb *Breaker, o Outcome) bool {
+	done, ok := cb.Allow()
+	if ok {
+		done(o)
+	}
+	return ok
+}
+
+func TestBreakerClosed(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold: 3,
+		ResetTimeout:     time.Second,
+	})
+
+	if _, ok := cb.Allow(); !ok {
+		t.Error("closed breaker should allow requests")
+	}
+	if cb.State() != StateClosed {
+		t.Errorf("state = %q, want %q", cb.State(), StateClosed)
+	}
+}
+
+func TestBreakerOpens(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold: 2,
+		ResetTimeout:     time.Hour,
+	})
+
+	request(cb, Failure)
+	if cb.State() != StateClosed {
+		t.Error("should still be closed after 1 failure")
+	}
+
+	request(cb, Failure)
+	if cb.State() != StateOpen {
+		t.Errorf("state = %q, want %q after threshold", cb.State(), StateOpen)
+	}
+
+	if _, ok := cb.Allow(); ok {
+		t.Error("open breaker should not allow requests")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold:    2,
+		ResetTimeout:        10 * time.Millisecond,
+		HalfOpenMaxAttempts: 1,
+	})
+
+	request(cb, Failure)
+	request(cb, Failure)
+	if cb.State() != StateOpen {
+		t.Fatal("expected open")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	done, ok := cb.Allow()
+	if !ok {
+		t.Fatal("should allow request after reset timeout (half-open)")
+	}
+	if cb.State() != StateHalfOpen {
+		t.Errorf("state = %q, want %q", cb.State(), StateHalfOpen)
+	}
+	if _, ok := cb.Allow(); ok {
+		t.Error("half-open breaker should allow one trial at a time")
+	}
+
+	done(Success)
+	if cb.State() != StateClosed {
+		t.Errorf("state = %q, want %q after success in half-open", cb.State(), StateClosed)
+	}
+}
+
+func TestBreakerHalfOpenFailure(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold: 1,
+		ResetTimeout:     10 * time.Millisecond,
+	})
+
+	request(cb, Failure)
+	time.Sleep(20 * time.Millisecond)
+
+	request(cb, Failure) // the half-open trial
+	if cb.State() != StateOpen {
+		t.Errorf("state = %q, want %q after half-open failure", cb.State(), StateOpen)
+	}
+}
+
+func TestBreakerHalfOpenAbandoned(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold: 1,
+		ResetTimeout:     10 * time.Millisecond,
+	})
+
+	request(cb, Failure)
+	time.Sleep(20 * time.Millisecond)
+
+	done, _ := cb.Allow()
+	done(Abandoned)
+	done(Failure) // done counts once
+	if cb.State() != StateHalfOpen {
+		t.Errorf("state = %q, want %q after an abandoned trial", cb.State(), StateHalfOpen)
+	}
+	if !request(cb, Success) {
+		t.Fatal("abandoned trial should free the trial slot")
+	}
+	if cb.State() != StateClosed {
+		t.Errorf("state = %q, want %q", cb.State(), StateClosed)
+	}
+}
+
+func TestBreakerReset(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold: 3,
+		ResetTimeout:     time.Hour,
+	})
+
+	request(cb, Failure)
+	request(cb, Failure)
+	request(cb, Success) // resets counter
+	request(cb, Failure)
+
+	if cb.State() != StateClosed {
+		t.Error("success should reset failure count")
+	}
+}
+
+func TestBreakerOnStateChange(t *testing.T) {
+	var changes []string
+	cb := New(Config{
+		FailureThreshold: 1,
+		ResetTimeout:     10 * time.Millisecond,
+		OnStateChange: func(from, to string) {
+			changes = append(changes, from+">"+to)
+		},
+	})
+
+	request(cb, Success) // no change
+	request(cb, Failure)
+	time.Sleep(20 * time.Millisecond)
+	request(cb, Success)
+
+	want := "closed>open open>half_open half_open>closed"
+	if got := strings.Join(changes, " "); got != want {
+		t.Errorf("state changes = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,754 @@
+package dialog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity classifies a diagnostic. Errors make a dialog unloadable; warnings
+// only do so in strict mode.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single finding of the dialog analyzer.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	// Code identifies the check, e.g. "unknown-target" or "unreachable-state".
+	Code   string `json:"code"`
+	Dialog string `json:"dialog"`
+	// Path locates the offending element in the definition, e.g.
+	// states.greeting.transitions[0].target.
+	Path    string `json:"path,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic as "file:line:col: severity: path: message".
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	switch {
+	case d.File != "" && d.Line > 0:
+		fmt.Fprintf(&b, "%s:%d:%d: ", d.File, d.Line, d.Column)
+	case d.File != "":
+		fmt.Fprintf(&b, "%s: ", d.File)
+	default:
+		fmt.Fprintf(&b, "dialog %q: ", d.Dialog)
+	}
+	fmt.Fprintf(&b, "%s: ", d.Severity)
+	if d.Path != "" {
+		fmt.Fprintf(&b, "%s: ", d.Path)
+	}
+	fmt.Fprintf(&b, "%s [%s]", d.Message, d.Code)
+	return b.String()
+}
+
+// Diagnostics is the result of analyzing a dialog, ordered by position.
+type Diagnostics []Diagnostic
+
+// Errors returns the error diagnostics.
+func (ds Diagnostics) Errors() Diagnostics {
+	return ds.filter(SeverityError)
+}
+
+// Warnings returns the warning diagnostics.
+func (ds Diagnostics) Warnings() Diagnostics {
+	return ds.filter(SeverityWarning)
+}
+
+func (ds Diagnostics) filter(sev Severity) Diagnostics {
+	var out Diagnostics
+	for _, d := range ds {
+		if d.Severity == sev {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Err returns an *AnalysisError holding the errors, and in strict mode the
+// warnings too, or nil if there are none.
+func (ds Diagnostics) Err(strict bool) error {
+	failing := ds.Errors()
+	if strict {
+		failing = ds
+	}
+	if len(failing) == 0 {
+		return nil
+	}
+	return &AnalysisError{Diagnostics: failing}
+}
+
+// AnalysisError reports the diagnostics that made a dialog fail to load.
+type AnalysisError struct {
+	Diagnostics Diagnostics
+}
+
+func (e *AnalysisError) Error() string {
+	msgs := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		msgs[i] = d.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// actionParams lists the known action types and the params each requires.
+// Other action types are passed through to the caller as directives.
+var actionParams = map[string][]string{
+	"play_tts":       {"text"},
+	"play_audio":     nil,
+	"call_hook":      nil,
+	"collect_digits": nil,
+	"set_variable":   nil,
+	"hangup":         nil,
+	"llm_turn":       nil,
+}
+
+// AnalyzeFile parses a YAML dialog definition and analyzes it. The returned
+// error is only set when the file cannot be read or parsed; problems with the
+// definition itself are reported as diagnostics.
+func AnalyzeFile(path string) (*Dialog, Diagnostics, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, diags, err := AnalyzeSource(data, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.Name == "" {
+		d.Name = filepath.Base(path)
+	}
+	return d, diags, nil
+}
+
+// AnalyzeSource parses a YAML dialog definition read from file, which is only
+// used to label diagnostics and may be empty, and analyzes it.
+func AnalyzeSource(data []byte, file string) (*Dialog, Diagnostics, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("parse YAML: %w", err)
+	}
+	var d Dialog
+	if err := root.Decode(&d); err != nil {
+		return nil, nil, fmt.Errorf("parse YAML: %w", err)
+	}
+	return &d, Analyze(&d, file, &root), nil
+}
+
+// Analyze checks a dialog definition. root is the YAML document d was decoded
+// from and is used to attach line numbers; it may be nil.
+func Analyze(d *Dialog, file string, root *yaml.Node) Diagnostics {
+	a := &analyzer{dialog: d, file: file, root: root}
+	a.run()
+	sort.SliceStable(a.diags, func(i, j int) bool {
+		if a.diags[i].Line != a.diags[j].Line {
+			return a.diags[i].Line < a.diags[j].Line
+		}
+		return a.diags[i].Column < a.diags[j].Column
+	})
+	return a.diags
+}
+
+// analyzer collects diagnostics for one dialog. Paths are given as a list of
+// mapping keys (string) and sequence indexes (int).
+type analyzer struct {
+	dialog *Dialog
+	file   string
+	root   *yaml.Node
+	diags  Diagnostics
+}
+
+func (a *analyzer) errorf(path []any, code, format string, args ...any) {
+	a.report(SeverityError, path, code, format, args...)
+}
+
+func (a *analyzer) warnf(path []any, code, format string, args ...any) {
+	a.report(SeverityWarning, path, code, format, args...)
+}
+
+func (a *analyzer) report(sev Severity, path []any, code, format string, args ...any) {
+	line, col := a.locate(path)
+	a.diags = append(a.diags, Diagnostic{
+		Severity: sev,
+		Code:     code,
+		Dialog:   a.dialog.Name,
+		Path:     formatPath(path),
+		File:     a.file,
+		Line:     line,
+		Column:   col,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// locate returns the position of the deepest node along path that exists in
+// the YAML document. Mapping entries resolve to their key.
+func (a *analyzer) locate(path []any) (line, col int) {
+	if a.root == nil {
+		return 0, 0
+	}
+	n := a.root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	line, col = n.Line, n.Column
+	for _, p := range path {
+		switch key := p.(type) {
+		case string:
+			if n.Kind != yaml.MappingNode {
+				return line, col
+			}
+			var next *yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == key {
+					line, col = n.Content[i].Line, n.Content[i].Column
+					next = n.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return line, col
+			}
+			n = next
+		case int:
+			if n.Kind != yaml.SequenceNode || key >= len(n.Content) {
+				return line, col
+			}
+			n = n.Content[key]
+			line, col = n.Line, n.Column
+		}
+	}
+	return line, col
+}
+
+func formatPath(path []any) string {
+	var b strings.Builder
+	for _, p := range path {
+		switch key := p.(type) {
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(key)
+		case int:
+			b.WriteString("[" + strconv.Itoa(key) + "]")
+		}
+	}
+	return b.String()
+}
+
+func statePath(name string, rest ...any) []any {
+	return append([]any{"states", name}, rest...)
+}
+
+func (a *analyzer) run() {
+	d := a.dialog
+	switch {
+	case d.InitialState == "":
+		a.errorf([]any{"initial_state"}, "initial-state", "initial_state is required")
+	case !a.hasState(d.InitialState):
+		a.errorf([]any{"initial_state"}, "initial-state", "initial_state %q not found in states", d.InitialState)
+	}
+
+	a.variables()
+	a.intents()
+	a.tools()
+	a.hookDefs()
+
+	for _, name := range sortedStates(d) {
+		a.state(name, d.States[name])
+	}
+
+	for i, t := range d.GlobalTransitions {
+		a.transition([]any{"global_transitions", i}, t)
+	}
+	for i, action := range d.OnHangup {
+		a.action([]any{"on_hangup", i}, action)
+	}
+	for i, action := range d.OnError {
+		a.action([]any{"on_error", i}, action)
+	}
+
+	a.graph()
+}
+
+func (a *analyzer) hasState(name string) bool {
+	_, ok := a.dialog.States[name]
+	return ok
+}
+
+// target reports an error unless name is an existing state.
+func (a *analyzer) target(path []any, field, name string) {
+	switch {
+	case name == "":
+		a.errorf(path, "unknown-target", "%s is required", field)
+	case !a.hasState(name):
+		a.errorf(path, "unknown-target", "%s %q not found", field, name)
+	}
+}
+
+func (a *analyzer) state(name string, state State) {
+	switch state.Type {
+	case "":
+	case StateTypeGather:
+		a.gather(name, state)
+	default:
+		a.errorf(statePath(name, "type"), "invalid-state", "unknown type %q", state.Type)
+	}
+
+	for i, action := range state.OnEnter {
+		a.action(statePath(name, "on_enter", i), action)
+	}
+
+	for i, t := range state.Transitions {
+		a.transition(statePath(name, "transitions", i), t)
+	}
+
+	if state.Timeout != "" {
+		if dur, err := time.ParseDuration(state.Timeout); err != nil || dur < 0 {
+			a.errorf(statePath(name, "timeout"), "invalid-timeout", "invalid timeout %q", state.Timeout)
+		}
+	}
+	if state.TimeoutNext != "" {
+		a.target(statePath(name, "timeout_next"), "timeout_next", state.TimeoutNext)
+	}
+
+	global := a.dialog.usesGlobal(state)
+	if state.BargeIn && state.Type != StateTypeGather && !hasEvent(state.Transitions, EventSpeech) &&
+		!(global && hasEvent(a.dialog.GlobalTransitions, EventSpeech)) {
+		a.warnf(statePath(name, "barge_in"), "barge-in-no-speech",
+			"barge_in is set but the state has no speech transition for the interrupting utterance")
+	}
+
+	if !state.Terminal && state.Type != StateTypeGather && len(state.Transitions) == 0 && !global &&
+		(state.Timeout == "" || state.TimeoutNext == "") {
+		a.warnf(statePath(name), "dead-end", "non-terminal state has no transitions and no timeout")
+	}
+}
+
+// transition checks a state or global transition.
+func (a *analyzer) transition(path []any, t Transition) {
+	if t.Target != TargetCurrent {
+		a.target(append(path, "target"), "target", t.Target)
+	}
+	if t.Match != nil {
+		if err := t.Match.validate(); err != nil {
+			a.errorf(append(path, "match"), "invalid-match", "%v", err)
+		} else if t.Match.Regex != "" {
+			re, _ := compileMatchRegex(t.Match.Regex)
+			for _, name := range re.SubexpNames() {
+				if name != "" {
+					a.assignable(append(path, "match", "regex"), name)
+				}
+			}
+		}
+	}
+	if t.Condition != "" {
+		a.template(append(path, "condition"), t.Condition)
+	}
+	for j, action := range t.Actions {
+		a.action(append(path, "actions", j), action)
+	}
+}
+
+// action checks an action's type, required params and param templates.
+func (a *analyzer) action(path []any, action Action) {
+	if action.Type == "" {
+		a.errorf(append(path, "type"), "unknown-action", "action type is required")
+		return
+	}
+	required, known := actionParams[action.Type]
+	if !known {
+		a.warnf(append(path, "type"), "unknown-action",
+			"unknown action type %q is passed to the caller as a directive", action.Type)
+	}
+	for _, p := range required {
+		if action.Params[p] == "" {
+			a.errorf(append(path, "params"), "missing-param", "%s requires param %q", action.Type, p)
+		}
+	}
+
+	if action.Type == "collect_digits" {
+		a.digitParams(append(path, "params"), action.Params)
+		return
+	}
+	if action.Type == "set_variable" {
+		a.assignments(append(path, "params"), action.Params)
+	}
+	if action.Type == "llm_turn" {
+		a.llmParams(append(path, "params"), action.Params)
+	}
+	if action.Type == "call_hook" {
+		a.hookParams(append(path, "params"), action.Params)
+	}
+	if v, ok := action.Params[ParamBargeIn]; ok && !strings.Contains(v, "{{") {
+		if _, err := strconv.ParseBool(v); err != nil {
+			a.errorf(append(path, "params", ParamBargeIn), "invalid-param", "barge_in must be true or false, got %q", v)
+		}
+	}
+	a.params(append(path, "params"), action.Params)
+}
+
+// params checks the templates in action params and reports whether any
+// param is a template.
+func (a *analyzer) params(path []any, params map[string]string) (dynamic bool) {
+	for _, k := range sortedKeys(params) {
+		if v := params[k]; strings.Contains(v, "{{") {
+			dynamic = true
+			a.template(append(path, k), v)
+		}
+	}
+	return dynamic
+}
+
+// digitParams checks collect_digits params. Params without templates are
+// validated now rather than mid-call.
+func (a *analyzer) digitParams(path []any, params map[string]string) {
+	variable := params["variable"]
+	if variable == "" {
+		variable = DefaultDigitsVariable
+	}
+	a.assignable(append(path, "variable"), variable)
+	if a.params(path, params) {
+		return
+	}
+	if _, err := newDigitCollection(params); err != nil {
+		a.errorf(path, "invalid-param", "%v", err)
+	}
+}
+
+// variables checks the variable declarations and their defaults.
+func (a *analyzer) variables() {
+	for _, name := range sortedKeys(a.dialog.Variables) {
+		decl := a.dialog.Variables[name]
+		path := []any{"variables", name}
+		if name == VarError {
+			a.errorf(path, "invalid-variable", "variable %q is reserved for the message of a failed step", name)
+			continue
+		}
+		if _, ok := zeroValues[decl.kind()]; !ok {
+			a.errorf(append(path, "type"), "invalid-variable", "unknown type %q", decl.Type)
+			continue
+		}
+		if decl.Pattern != "" {
+			if _, err := compileVariablePattern(decl.Pattern); err != nil {
+				a.errorf(append(path, "pattern"), "invalid-variable", "%v", err)
+				continue
+			}
+		}
+		if decl.Default != nil {
+			if _, err := decl.encode(decl.Default); err != nil {
+				a.errorf(append(path, "default"), "invalid-variable", "default: %v", err)
+			}
+		}
+	}
+}
+
+// assignable reports an assignment to a readonly variable.
+func (a *analyzer) assignable(path []any, name string) {
+	if decl, ok := a.dialog.Variables[name]; ok && decl.Readonly {
+		a.errorf(path, "readonly-variable", "variable %q is readonly", name)
+	}
+}
+
+// assignments checks set_variable params: the variables must be assignable
+// and values without templates must suit their declaration.
+func (a *analyzer) assignments(path []any, params map[string]string) {
+	for _, name := range sortedKeys(params) {
+		value := params[name]
+		a.assignable(append(path, name), name)
+		decl, ok := a.dialog.Variables[name]
+		if !ok || decl.Readonly || strings.Contains(value, "{{") {
+			continue
+		}
+		if _, err := decl.encode(value); err != nil {
+			a.errorf(append(path, name), "invalid-param", "variable %q: %v", name, err)
+		}
+	}
+}
+
+// template reports a template that does not parse.
+func (a *analyzer) template(path []any, tmpl string) {
+	if _, err := parseTemplate(tmpl); err != nil {
+		a.errorf(path, "template-syntax", "%v", err)
+	}
+}
+
+// graph reports states that cannot be reached from the initial state and
+// reachable states from which no terminal state can be reached. Global
+// transitions count as transitions of every state they apply in. Transitions
+// a hook response directs with next_state are not known statically and are
+// not considered.
+func (a *analyzer) graph() {
+	d := a.dialog
+	if !a.hasState(d.InitialState) {
+		return
+	}
+
+	edges := make(map[string][]string, len(d.States))
+	reverse := make(map[string][]string, len(d.States))
+	var terminals []string
+	for name, state := range d.States {
+		targets := stateTargets(state)
+		if d.usesGlobal(state) {
+			for _, t := range d.GlobalTransitions {
+				targets = append(targets, t.Target)
+			}
+		}
+		for _, target := range targets {
+			if a.hasState(target) {
+				edges[name] = append(edges[name], target)
+				reverse[target] = append(reverse[target], name)
+			}
+		}
+		if state.Terminal {
+			terminals = append(terminals, name)
+		}
+	}
+
+	reachable := walk([]string{d.InitialState}, edges)
+	if len(terminals) == 0 {
+		a.warnf([]any{"states"}, "no-terminal", "dialog has no terminal state")
+	}
+	ending := walk(terminals, reverse)
+
+	for _, name := range sortedStates(d) {
+		switch {
+		case !reachable[name]:
+			a.warnf(statePath(name), "unreachable-state", "state is not reachable from initial_state %q", d.InitialState)
+		case len(terminals) > 0 && !ending[name]:
+			a.warnf(statePath(name), "no-terminal-path", "no terminal state is reachable from this state")
+		}
+	}
+}
+
+// stateTargets returns every state a state can move to on its own.
+func stateTargets(state State) []string {
+	targets := hookErrorTargets(state.OnEnter)
+	for _, t := range state.Transitions {
+		targets = append(targets, t.Target)
+		targets = append(targets, hookErrorTargets(t.Actions)...)
+	}
+	if state.TimeoutNext != "" {
+		targets = append(targets, state.TimeoutNext)
+	}
+	if state.Type == StateTypeGather && state.Gather != nil {
+		targets = append(targets, state.Gather.Next, state.Gather.MaxAttemptsNext)
+	}
+	return targets
+}
+
+// hasEvent reports whether any transition fires on event.
+func hasEvent(transitions []Transition, event string) bool {
+	for _, t := range transitions {
+		if t.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// walk returns the set of nodes reachable from start along edges.
+func walk(start []string, edges map[string][]string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string(nil), start...)
+	for _, s := range start {
+		seen[s] = true
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[n] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
+
+func sortedStates(d *Dialog) []string {
+	names := make([]string, 0, len(d.States))
+	for name := range d.States {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// gather checks a gather state's configuration.
+func (a *analyzer) gather(name string, state State) {
+	g := state.Gather
+	if g == nil {
+		a.errorf(statePath(name, "type"), "invalid-gather", "gather block is required for type gather")
+		return
+	}
+	path := statePath(name, "gather")
+	if g.Prompt == "" {
+		a.errorf(path, "invalid-gather", "gather prompt is required")
+	}
+	switch g.Input {
+	case "", EventSpeech, EventDTMF:
+	default:
+		a.errorf(append(path, "input"), "invalid-gather", "gather input %q must be speech or dtmf", g.Input)
+	}
+	if len(g.Digits) > 0 {
+		if g.Input == EventSpeech {
+			a.errorf(append(path, "digits"), "invalid-gather", "gather digits requires dtmf input")
+		}
+		a.digitParams(append(path, "digits"), g.Digits)
+	}
+	for _, f := range []struct{ field, tmpl string }{
+		{"prompt", g.Prompt}, {"reprompt", g.Reprompt}, {"no_match", g.NoMatch}, {"validate", g.Validate},
+	} {
+		if f.tmpl != "" {
+			a.template(append(path, f.field), f.tmpl)
+		}
+	}
+	if g.Variable != "" {
+		a.assignable(append(path, "variable"), g.Variable)
+	}
+	a.target(append(path, "next"), "gather next", g.Next)
+	a.target(append(path, "max_attempts_next"), "gather max_attempts_next", g.MaxAttemptsNext)
+}
+
+// hookDefs checks the dialog's hook definitions.
+func (a *analyzer) hookDefs() {
+	for _, name := range sortedKeys(a.dialog.Hooks) {
+		if err := a.dialog.Hooks[name].ValidateDefinition(); err != nil {
+			a.errorf([]any{"hooks", name}, "invalid-hook", "%v", err)
+		}
+	}
+}
+
+// hookParams checks call_hook params. Params without templates are validated
+// now rather than mid-call. A hook param naming no hook of the dialog may
+// name one of the engine's registry and is only checked at call time.
+func (a *analyzer) hookParams(path []any, params map[string]string) {
+	if params["url"] == "" && params["hook"] == "" {
+		a.errorf(path, "missing-param", "call_hook requires param \"url\" or \"hook\"")
+	}
+	if params["auth_secret"] != "" {
+		a.warnf(append(path, "auth_secret"), "inline-secret",
+			"auth_secret is part of the dialog; define the hook under hooks: with a secret reference")
+	}
+	static := make(map[string]string, len(params))
+	for k, v := range params {
+		if !strings.Contains(v, "{{") {
+			static[k] = v
+		}
+	}
+	call, err := newHookCall(static, a.dialog.Hooks[static["hook"]])
+	if err != nil {
+		a.errorf(path, "invalid-param", "%v", err)
+		return
+	}
+	if call.errorTarget != "" {
+		a.target(append(path, "on_error"), "on_error transition", call.errorTarget)
+	}
+	for _, k := range sortedKeys(params) {
+		if name, ok := strings.CutPrefix(k, "response_map."); ok {
+			a.assignable(append(path, "response_map", name), name)
+		}
+	}
+}
+
+// tools checks the tool declarations and the tools llm_turn actions name.
+func (a *analyzer) tools() {
+	for _, name := range sortedKeys(a.dialog.Tools) {
+		path := []any{"tools", name}
+		if !toolNamePattern.MatchString(name) {
+			a.errorf(path, "invalid-tool", "tool name must be 1-64 letters, digits, _ or -")
+		}
+		for _, ev := range engineEvents {
+			if name == ev {
+				a.errorf(path, "invalid-tool", "tool name %q is an engine event", name)
+			}
+		}
+		for _, param := range sortedKeys(a.dialog.Tools[name].Parameters) {
+			a.assignable(append(path, "parameters", param), param)
+		}
+	}
+}
+
+// llmParams checks the tools an llm_turn action names.
+func (a *analyzer) llmParams(path []any, params map[string]string) {
+	if strings.TrimSpace(params["tools"]) == "" {
+		return
+	}
+	for _, name := range strings.Split(params["tools"], ",") {
+		if _, ok := a.dialog.Tools[strings.TrimSpace(name)]; !ok {
+			a.errorf(append(path, "tools"), "invalid-tool", "tool %q not declared", strings.TrimSpace(name))
+		}
+	}
+}
+
+// intents checks intent declarations and the transitions using them.
+func (a *analyzer) intents() {
+	d := a.dialog
+	names := make([]string, 0, len(d.Intents))
+	for name := range d.Intents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(d.Intents[name].Examples) == 0 {
+			a.errorf([]any{"intents", name}, "invalid-intent", "at least one example is required")
+		}
+	}
+	for _, name := range sortedStates(d) {
+		for i, t := range d.States[name].Transitions {
+			a.intentTransition(statePath(name, "transitions", i), t)
+		}
+	}
+	for i, t := range d.GlobalTransitions {
+		a.intentTransition([]any{"global_transitions", i}, t)
+	}
+}
+
+// intentTransition checks the intent and min_score of a transition.
+func (a *analyzer) intentTransition(path []any, t Transition) {
+	if t.Intent == "" {
+		return
+	}
+	if _, ok := a.dialog.Intents[t.Intent]; !ok {
+		a.errorf(append(path, "intent"), "invalid-intent", "intent %q not declared", t.Intent)
+	}
+	if t.MinScore < 0 || t.MinScore > 1 {
+		a.errorf(append(path, "min_score"), "invalid-intent", "min_score must be between 0 and 1")
+	}
+}
+
+// hookErrorTargets returns the states call_hook actions move to on failure.
+func hookErrorTargets(actions []Action) []string {
+	var targets []string
+	for _, action := range actions {
+		mode := action.Params["on_error"]
+		if action.Type == "call_hook" && strings.HasPrefix(mode, HookOnErrorTransition) {
+			targets = append(targets, strings.TrimPrefix(mode, HookOnErrorTransition))
+		}
+	}
+	return targets
+}
@@ -0,0 +1,375 @@
+package dialog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const analyzeYAML = `name: analyze-test
+initial_state: start
+states:
+  start:
+    on_enter:
+      - type: play_tts
+        params:
+          voice: "amy"
+      - type: play_tones
+    transitions:
+      - event: speech
+        condition: '{{ if .Event }}'
+        target: ask
+    timeout: "ten seconds"
+    timeout_next: done
+  ask:
+    on_enter:
+      - type: play_tts
+        params:
+          text: "{{ shout .Variables.name }}"
+  loop:
+    transitions:
+      - event: speech
+        target: loop
+  done:
+    terminal: true
+`
+
+func writeDialog(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dialog.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write yaml: %v", err)
+	}
+	return path
+}
+
+func TestAnalyzeFile(t *testing.T) {
+	path := writeDialog(t, analyzeYAML)
+
+	_, diags, err := AnalyzeFile(path)
+	if err != nil {
+		t.Fatalf("AnalyzeFile: %v", err)
+	}
+
+	want := []struct {
+		severity Severity
+		code     string
+		path     string
+		line     int
+	}{
+		{SeverityError, "missing-param", "states.start.on_enter[0].params", 7},
+		{SeverityWarning, "unknown-action", "states.start.on_enter[1].type", 9},
+		{SeverityError, "template-syntax", "states.start.transitions[0].condition", 12},
+		{SeverityError, "invalid-timeout", "states.start.timeout", 14},
+		{SeverityWarning, "dead-end", "states.ask", 16},
+		{SeverityWarning, "no-terminal-path", "states.ask", 16},
+		{SeverityError, "template-syntax", "states.ask.on_enter[0].params.text", 20},
+		{SeverityWarning, "unreachable-state", "states.loop", 21},
+	}
+	if len(diags) != len(want) {
+		for _, d := range diags {
+			t.Log(d)
+		}
+		t.Fatalf("got %d diagnostics, want %d", len(diags), len(want))
+	}
+	for i, w := range want {
+		d := diags[i]
+		if d.Severity != w.severity || d.Code != w.code || d.Path != w.path || d.Line != w.line {
+			t.Errorf("diagnostic %d = %s, want %s %s at %s line %d", i, d, w.severity, w.code, w.path, w.line)
+		}
+		if d.File != path || d.Dialog != "analyze-test" {
+			t.Errorf("diagnostic %d file/dialog = %q/%q", i, d.File, d.Dialog)
+		}
+	}
+}
+
+func TestAnalyzeCollectDigitsParams(t *testing.T) {
+	d := digitsDialog(map[string]string{"max_digits": "many"})
+	diags := Analyze(d, "", nil)
+	if len(diags) != 1 || diags[0].Code != "invalid-param" {
+		t.Fatalf("diagnostics = %v", diags)
+	}
+
+	// Templated params can only be checked when the action runs.
+	d = digitsDialog(map[string]string{"max_digits": "{{ .Variables.length }}"})
+	if diags := Analyze(d, "", nil); len(diags) != 0 {
+		t.Fatalf("diagnostics = %v", diags)
+	}
+}
+
+func TestAnalyzeExampleDialog(t *testing.T) {
+	_, diags, err := AnalyzeFile(filepath.Join("..", "..", "dialogs", "example.yaml"))
+	if err != nil {
+		t.Fatalf("AnalyzeFile: %v", err)
+	}
+	for _, d := range diags {
+		t.Errorf("unexpected diagnostic: %s", d)
+	}
+}
+
+func TestLoaderStrictMode(t *testing.T) {
+	path := writeDialog(t, `name: strict-test
+initial_state: start
+states:
+  start:
+    transitions:
+      - event: speech
+        target: done
+  orphan:
+    terminal: true
+  done:
+    terminal: true
+`)
+	dir := filepath.Dir(path)
+
+	if _, err := NewLoader(dir).LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	_, err := NewLoader(dir, StrictMode()).LoadAll()
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) {
+		t.Fatalf("strict LoadAll error = %v, want *AnalysisError", err)
+	}
+	if len(analysisErr.Diagnostics) != 1 || analysisErr.Diagnostics[0].Code != "unreachable-state" {
+		t.Errorf("diagnostics = %v", analysisErr.Diagnostics)
+	}
+}
+
+func TestAnalyzeBargeIn(t *testing.T) {
+	d := parseDialog(t, `name: barge-in
+initial_state: menu
+states:
+  menu:
+    barge_in: true
+    on_enter:
+      - type: play_tts
+        params: {text: "Hello", barge_in: "sometimes"}
+    transitions:
+      - event: tts_complete
+        target: done
+  done:
+    terminal: true
+`)
+	var got []string
+	for _, diag := range Analyze(d, "", nil) {
+		got = append(got, diag.Code+" "+diag.Path)
+	}
+	want := []string{
+		"invalid-param states.menu.on_enter[0].params.barge_in",
+		"barge-in-no-speech states.menu.barge_in",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("diagnostics = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeGlobalTransitions(t *testing.T) {
+	d := parseDialog(t, `name: global
+initial_state: menu
+global_transitions:
+  - event: dtmf
+    target: operator
+  - event: dtmf
+    target: $current
+  - event: speech
+    target: nowhere
+on_hangup:
+  - type: call_hook
+on_error:
+  - type: play_tts
+    params: {text: "{{ .Variables.error"}
+states:
+  menu:
+    barge_in: true
+    on_enter:
+      - type: play_tts
+        params: {text: "Hello"}
+  operator:
+    terminal: true
+  pinned:
+    ignore_global_transitions: true
+`)
+	var got []string
+	for _, diag := range Analyze(d, "", nil) {
+		got = append(got, diag.Code+" "+diag.Path)
+	}
+	// menu is neither a dead end nor missing a speech transition, and
+	// operator is reachable, through the global transitions; pinned opts out.
+	want := []string{
+		"dead-end states.pinned",
+		"unknown-target global_transitions[2].target",
+		"missing-param on_hangup[0].params",
+		"template-syntax on_error[0].params.text",
+		"unreachable-state states.pinned",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("diagnostics = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeVariables(t *testing.T) {
+	d := parseDialog(t, `name: variables
+initial_state: start
+variables:
+  amount: {type: float}
+  count: {type: int, default: many}
+  error: {type: string}
+  account: {type: string, pattern: '(\d'}
+  tier: {type: string, default: gold, readonly: true}
+  vip: {type: bool}
+states:
+  start:
+    on_enter:
+      - type: set_variable
+        params: {tier: silver, vip: "maybe", count: "{{ .Event }}"}
+      - type: collect_digits
+        params: {variable: tier}
+    transitions:
+      - event: speech
+        match: {regex: '(?P<tier>\w+)'}
+        target: done
+  done:
+    terminal: true
+`)
+	var got []string
+	for _, diag := range Analyze(d, "", nil) {
+		got = append(got, diag.Code+" "+diag.Path)
+	}
+	want := []string{
+		"invalid-variable variables.account.pattern",
+		"invalid-variable variables.amount.type",
+		"invalid-variable variables.count.default",
+		"invalid-variable variables.error",
+		"readonly-variable states.start.on_enter[0].params.tier",
+		"invalid-param states.start.on_enter[0].params.vip",
+		"readonly-variable states.start.on_enter[1].params.variable",
+		"readonly-variable states.start.transitions[0].match.regex",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("diagnostics = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeTools(t *testing.T) {
+	d := parseDialog(t, `name: tools
+initial_state: start
+variables:
+  tier: {type: string, default: gold, readonly: true}
+tools:
+  record_order:
+    parameters:
+      item: {required: true}
+  set_tier:
+    parameters:
+      tier: {}
+  speech: {}
+  "bad name": {}
+states:
+  start:
+    on_enter:
+      - type: llm_turn
+        params: {tools: "record_order, transfer"}
+    transitions:
+      - event: record_order
+        target: done
+  done:
+    terminal: true
+`)
+	var got []string
+	for _, diag := range Analyze(d, "", nil) {
+		got = append(got, diag.Code+" "+diag.Path)
+	}
+	want := []string{
+		"invalid-tool tools.bad name",
+		"readonly-variable tools.set_tier.parameters.tier",
+		"invalid-tool tools.speech",
+		"invalid-tool states.start.on_enter[0].params.tools",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("diagnostics = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeHookParams(t *testing.T) {
+	d := parseDialog(t, `name: hooks
+initial_state: start
+variables:
+  tier: {type: string, default: gold, readonly: true}
+states:
+  start:
+    on_enter:
+      - type: call_hook
+        params:
+          url: http://hooks.example.com/lookup
+          on_error: transition:apology
+          response_map: {tier: $.tier, name: $.name}
+      - type: call_hook
+        params: {url: http://hooks.example.com/a, method: TRACE}
+      - type: call_hook
+        params: {url: http://hooks.example.com/b, on_error: transition:nowhere}
+      - type: call_hook
+        params: {url: http://hooks.example.com/c, retries: "{{ .Variables.retries }}", timeout: soon}
+    transitions:
+      - event: hook_result
+        target: done
+  apology:
+    terminal: true
+  done:
+    terminal: true
+`)
+	var got []string
+	for _, diag := range Analyze(d, "", nil) {
+		got = append(got, diag.Code+" "+diag.Path)
+	}
+	want := []string{
+		"readonly-variable states.start.on_enter[0].params.response_map.tier",
+		"invalid-param states.start.on_enter[1].params",
+		"unknown-target states.start.on_enter[2].params.on_error",
+		"invalid-param states.start.on_enter[3].params",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("diagnostics = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeHookDefinitions(t *testing.T) {
+	d := parseDialog(t, `name: hook-defs
+initial_state: start
+hooks:
+  crm: {url: https://crm.example.com, secret: {env: CRM_TOKEN}}
+  inline: {url: https://crm.example.com, auth_secret: hunter2}
+states:
+  start:
+    on_enter:
+      - type: call_hook
+        params: {hook: crm, url: https://other.example.com}
+      - type: call_hook
+        params: {url: https://crm.example.com, auth_type: bearer, auth_secret: hunter2}
+      - type: call_hook
+        params: {hook: shared, retries: "9"}
+      - type: call_hook
+        params: {method: GET}
+    transitions:
+      - event: hook_result
+        target: done
+  done:
+    terminal: true
+`)
+	var got []string
+	for _, diag := range Analyze(d, "", nil) {
+		got = append(got, string(diag.Severity)+" "+diag.Code+" "+diag.Path)
+	}
+	want := []string{
+		"error invalid-hook hooks.inline",
+		"error invalid-param states.start.on_enter[0].params",
+		"warning inline-secret states.start.on_enter[1].params.auth_secret",
+		"error invalid-param states.start.on_enter[2].params",
+		"error missing-param states.start.on_enter[3].params",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("diagnostics = %v, want %v", got, want)
+	}
+}
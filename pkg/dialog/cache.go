@@ -0,0 +1,69 @@
+package dialog
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Bounds of the template and regex caches. Dialogs are reloaded and created
+// through the API, and regexMatch takes its pattern from a template, so the
+// strings they are keyed by are not bounded by the loaded dialogs.
+const (
+	maxCachedTemplates = 4096
+	maxCachedRegexes   = 1024
+)
+
+// lruCache is a concurrency-safe cache keyed by string that evicts its least
+// recently used entry when full.
+type lruCache[V any] struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any](max int) *lruCache[V] {
+	return &lruCache[V]{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the value cached for key, marking it as recently used.
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*lruEntry[V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// add caches value for key, evicting the least recently used entry if the
+// cache is full.
+func (c *lruCache[V]) add(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry[V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[V]).key)
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value})
+}
+
+// len returns the number of cached entries.
+func (c *lruCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,39 @@
+package dialog
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache[int](2)
+	c.add("a", 1)
+	c.add("b", 2)
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Fatalf("get(a) = %d, %v", v, ok)
+	}
+	c.add("c", 3) // evicts b, used less recently than a
+
+	if _, ok := c.get("b"); ok {
+		t.Error("b still cached, want it evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.get(key); !ok || v != want {
+			t.Errorf("get(%s) = %d, %v, want %d", key, v, ok, want)
+		}
+	}
+	if n := c.len(); n != 2 {
+		t.Errorf("len = %d, want 2", n)
+	}
+}
+
+func TestTemplateCacheBounded(t *testing.T) {
+	for i := range maxCachedTemplates + 10 {
+		if _, err := parseTemplate(fmt.Sprintf("{{ .Event }} %d", i)); err != nil {
+			t.Fatalf("parseTemplate: %v", err)
+		}
+	}
+	if n := templateCache.len(); n > maxCachedTemplates {
+		t.Errorf("template cache holds %d entries, want at most %d", n, maxCachedTemplates)
+	}
+}
@@ -0,0 +1,269 @@
+package dialog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Dialog sources, as reported by Origin.
+const (
+	SourceFile     = "file"
+	SourceDatabase = "database"
+)
+
+// Origin records where a loaded dialog version came from.
+type Origin struct {
+	// Source is SourceFile or SourceDatabase.
+	Source string
+	// File is the path of a file dialog; ID is the record ID of a stored one.
+	File string
+	ID   string
+	// CreatedAt is the file's modification time or the record's creation time.
+	CreatedAt time.Time
+	// Definition is the YAML the version was loaded from.
+	Definition string
+}
+
+// DefinitionStore persists dialog versions managed through the API.
+// *Repository implements it.
+type DefinitionStore interface {
+	ListDefinitions(ctx context.Context) ([]DefinitionRecord, error)
+	CreateDefinition(ctx context.Context, rec *DefinitionRecord) error
+	ActivateDefinition(ctx context.Context, name, version string) error
+	DeleteDefinitions(ctx context.Context, name, version string) error
+}
+
+// Errors returned by the dialog management methods of Loader.
+var (
+	ErrNoDefinitionStore = errors.New("dialog definition store not configured")
+	// ErrInvalidDefinition wraps parse errors and the *AnalysisError of a
+	// definition that does not pass analysis.
+	ErrInvalidDefinition = errors.New("invalid dialog definition")
+	ErrDialogExists      = errors.New("dialog already exists")
+	ErrDialogNotFound    = errors.New("dialog not found")
+	// ErrFileDialog is returned when changing a dialog defined by files in
+	// the dialog directory; those are edited on disk.
+	ErrFileDialog = errors.New("dialog is defined by files")
+)
+
+// Definitions makes the loader serve the dialog versions kept in store next
+// to the file dialogs, and enables CreateDialog and the other management
+// methods.
+func Definitions(store DefinitionStore) LoaderOption {
+	return func(l *Loader) {
+		l.store = store
+	}
+}
+
+// LoadStored loads the dialog versions kept in the definition store,
+// replacing those loaded before. A stored version that no longer passes
+// analysis is skipped with a warning. A version also defined by a file is
+// served from the file.
+func (l *Loader) LoadStored(ctx context.Context) error {
+	if l.store == nil {
+		return nil
+	}
+	records, err := l.store.ListDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("list dialog definitions: %w", err)
+	}
+
+	stored := make(map[string]*StateMachine, len(records))
+	active := make(map[string]string)
+	for i := range records {
+		rec := &records[i]
+		sm, err := l.parseDefinition([]byte(rec.Definition))
+		if err != nil {
+			slog.Warn("skipping stored dialog definition",
+				slog.String("dialog", rec.Name), slog.String("version", rec.DialogVersion),
+				slog.String("error", err.Error()))
+			continue
+		}
+		sm.origin = Origin{
+			Source:     SourceDatabase,
+			ID:         rec.ID,
+			CreatedAt:  rec.CreatedAt,
+			Definition: rec.Definition,
+		}
+		stored[rec.ID] = sm
+		if rec.IsActive {
+			active[rec.Name] = rec.DialogVersion
+		}
+	}
+
+	l.mu.Lock()
+	l.stored = stored
+	l.active = active
+	l.index()
+	l.mu.Unlock()
+	return nil
+}
+
+// WatchStored reloads the stored dialog versions every interval, picking up
+// changes made through other instances, until ctx is cancelled.
+func (l *Loader) WatchStored(ctx context.Context, interval time.Duration) {
+	if l.store == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.LoadStored(ctx); err != nil {
+				slog.Warn("reloading stored dialogs failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// CreateDialog validates a YAML definition and stores it as the first version
+// of a new dialog, which becomes the dialog's active version. The name must
+// not be used by any loaded dialog.
+func (l *Loader) CreateDialog(ctx context.Context, definition []byte) (*StateMachine, error) {
+	if l.store == nil {
+		return nil, ErrNoDefinitionStore
+	}
+	sm, err := l.parseDefinition(definition)
+	if err != nil {
+		return nil, err
+	}
+	d := sm.Dialog()
+	if _, ok := l.Get(d.Name); ok {
+		return nil, fmt.Errorf("%w: %q", ErrDialogExists, d.Name)
+	}
+	return l.storeVersion(ctx, d, definition, true)
+}
+
+// UpdateDialog validates a YAML definition and stores it as a new version of
+// a stored dialog. Versions are immutable, so the definition must carry a
+// version the dialog does not have yet. The new version becomes the default
+// for new sessions only when activate is set.
+func (l *Loader) UpdateDialog(ctx context.Context, name string, definition []byte, activate bool) (*StateMachine, error) {
+	if err := l.checkStored(name); err != nil {
+		return nil, err
+	}
+	sm, err := l.parseDefinition(definition)
+	if err != nil {
+		return nil, err
+	}
+	d := sm.Dialog()
+	if d.Name != name {
+		return nil, fmt.Errorf("%w: definition is for dialog %q, not %q", ErrInvalidDefinition, d.Name, name)
+	}
+	if _, ok := l.GetVersion(name, d.Version); ok {
+		return nil, fmt.Errorf("%w: dialog %q version %q", ErrDialogExists, name, d.Version)
+	}
+	return l.storeVersion(ctx, d, definition, activate)
+}
+
+// ActivateDialogVersion makes a stored version the default for new sessions
+// of its dialog. Running sessions keep the version they started with.
+func (l *Loader) ActivateDialogVersion(ctx context.Context, name, version string) (*StateMachine, error) {
+	if err := l.checkStored(name); err != nil {
+		return nil, err
+	}
+	sm, ok := l.GetVersion(name, version)
+	if !ok || sm.Origin().Source != SourceDatabase {
+		return nil, fmt.Errorf("%w: %q version %q", ErrDialogNotFound, name, version)
+	}
+	if err := l.store.ActivateDefinition(ctx, name, version); err != nil {
+		return nil, fmt.Errorf("activate dialog version: %w", err)
+	}
+	if err := l.LoadStored(ctx); err != nil {
+		return nil, err
+	}
+	// LoadStored rebuilt the stored state machines.
+	if sm, ok = l.GetVersion(name, version); !ok {
+		return nil, fmt.Errorf("dialog %q version %q was activated but not loaded", name, version)
+	}
+	return sm, nil
+}
+
+// DeleteDialog deletes one stored version of a dialog, or all of them when
+// version is empty, and returns the deleted versions. Deleting the active
+// version makes the highest remaining version the default. Running sessions
+// keep the version they started with.
+func (l *Loader) DeleteDialog(ctx context.Context, name, version string) ([]string, error) {
+	if err := l.checkStored(name); err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for _, v := range l.Versions(name) {
+		sm, ok := l.GetVersion(name, v)
+		if ok && sm.Origin().Source == SourceDatabase && (version == "" || v == version) {
+			deleted = append(deleted, v)
+		}
+	}
+	if len(deleted) == 0 {
+		return nil, fmt.Errorf("%w: %q version %q", ErrDialogNotFound, name, version)
+	}
+	if err := l.store.DeleteDefinitions(ctx, name, version); err != nil {
+		return nil, fmt.Errorf("delete dialog: %w", err)
+	}
+	if err := l.LoadStored(ctx); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// checkStored reports whether name is a dialog the management methods may
+// change: one with at least one stored version.
+func (l *Loader) checkStored(name string) error {
+	if l.store == nil {
+		return ErrNoDefinitionStore
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	byVersion, ok := l.versions[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrDialogNotFound, name)
+	}
+	for _, sm := range byVersion {
+		if sm.Origin().Source == SourceDatabase {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrFileDialog, name)
+}
+
+// parseDefinition analyzes a YAML definition submitted through the API.
+// Stored dialogs must be named and versioned.
+func (l *Loader) parseDefinition(definition []byte) (*StateMachine, error) {
+	d, diags, err := AnalyzeSource(definition, "")
+	if err == nil {
+		err = diags.Err(l.strict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDefinition, err)
+	}
+	if d.Name == "" || d.Version == "" {
+		return nil, fmt.Errorf("%w: name and version are required", ErrInvalidDefinition)
+	}
+	return NewStateMachine(d), nil
+}
+
+func (l *Loader) storeVersion(ctx context.Context, d *Dialog, definition []byte, activate bool) (*StateMachine, error) {
+	rec := &DefinitionRecord{
+		Name:          d.Name,
+		DialogVersion: d.Version,
+		Definition:    string(definition),
+		IsActive:      activate,
+	}
+	if err := l.store.CreateDefinition(ctx, rec); err != nil {
+		return nil, fmt.Errorf("store dialog definition: %w", err)
+	}
+	if err := l.LoadStored(ctx); err != nil {
+		return nil, err
+	}
+	sm, ok := l.GetVersion(d.Name, d.Version)
+	if !ok {
+		return nil, fmt.Errorf("dialog %q version %q was stored but not loaded", d.Name, d.Version)
+	}
+	return sm, nil
+}
@@ -0,0 +1,255 @@
+package dialog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryDefinitionStore is an in-memory DefinitionStore for tests.
+type memoryDefinitionStore struct {
+	mu      sync.Mutex
+	records []DefinitionRecord
+	nextID  int
+}
+
+func (m *memoryDefinitionStore) ListDefinitions(_ context.Context) ([]DefinitionRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DefinitionRecord(nil), m.records...), nil
+}
+
+func (m *memoryDefinitionStore) CreateDefinition(_ context.Context, rec *DefinitionRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.records {
+		if r.Name == rec.Name && r.DialogVersion == rec.DialogVersion {
+			return fmt.Errorf("duplicate %s@%s", rec.Name, rec.DialogVersion)
+		}
+	}
+	m.nextID++
+	rec.ID = fmt.Sprintf("def-%d", m.nextID)
+	rec.CreatedAt = time.Date(2025, 1, 1, 0, 0, m.nextID, 0, time.UTC)
+	if rec.IsActive {
+		m.deactivate(rec.Name)
+	}
+	m.records = append(m.records, *rec)
+	return nil
+}
+
+func (m *memoryDefinitionStore) ActivateDefinition(_ context.Context, name, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deactivate(name)
+	for i := range m.records {
+		if m.records[i].Name == name && m.records[i].DialogVersion == version {
+			m.records[i].IsActive = true
+		}
+	}
+	return nil
+}
+
+func (m *memoryDefinitionStore) DeleteDefinitions(_ context.Context, name, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.records[:0]
+	for _, r := range m.records {
+		if r.Name != name || (version != "" && r.DialogVersion != version) {
+			kept = append(kept, r)
+		}
+	}
+	m.records = kept
+	return nil
+}
+
+func (m *memoryDefinitionStore) deactivate(name string) {
+	for i := range m.records {
+		if m.records[i].Name == name {
+			m.records[i].IsActive = false
+		}
+	}
+}
+
+func defaultVersion(t *testing.T, l *Loader, name string) string {
+	t.Helper()
+	sm, ok := l.Get(name)
+	if !ok {
+		t.Fatalf("dialog %q not loaded", name)
+	}
+	return sm.Dialog().Version
+}
+
+func TestLoaderManageDialogs(t *testing.T) {
+	ctx := t.Context()
+	store := &memoryDefinitionStore{}
+	l := NewLoader(t.TempDir(), Definitions(store))
+
+	sm, err := l.CreateDialog(ctx, []byte(versionedDialog("1.0", "one")))
+	if err != nil {
+		t.Fatalf("CreateDialog: %v", err)
+	}
+	if o := sm.Origin(); o.Source != SourceDatabase || o.ID == "" || o.CreatedAt.IsZero() || !strings.Contains(o.Definition, "one") {
+		t.Errorf("origin = %+v", o)
+	}
+	if _, err := l.CreateDialog(ctx, []byte(versionedDialog("2.0", "two"))); !errors.Is(err, ErrDialogExists) {
+		t.Errorf("CreateDialog existing: got %v, want ErrDialogExists", err)
+	}
+
+	// An inactive update is served only when pinned.
+	if _, err := l.UpdateDialog(ctx, "versioned", []byte(versionedDialog("2.0", "two")), false); err != nil {
+		t.Fatalf("UpdateDialog: %v", err)
+	}
+	if got := defaultVersion(t, l, "versioned"); got != "1.0" {
+		t.Errorf("default after inactive update = %s, want 1.0", got)
+	}
+	if _, ok := l.GetVersion("versioned", "2.0"); !ok {
+		t.Error("version 2.0 not loaded")
+	}
+	if _, err := l.UpdateDialog(ctx, "versioned", []byte(versionedDialog("2.0", "again")), true); !errors.Is(err, ErrDialogExists) {
+		t.Errorf("UpdateDialog existing version: got %v, want ErrDialogExists", err)
+	}
+
+	if _, err := l.ActivateDialogVersion(ctx, "versioned", "2.0"); err != nil {
+		t.Fatalf("ActivateDialogVersion: %v", err)
+	}
+	if got := defaultVersion(t, l, "versioned"); got != "2.0" {
+		t.Errorf("default after activate = %s, want 2.0", got)
+	}
+	if _, err := l.ActivateDialogVersion(ctx, "versioned", "9.0"); !errors.Is(err, ErrDialogNotFound) {
+		t.Errorf("ActivateDialogVersion unknown: got %v, want ErrDialogNotFound", err)
+	}
+
+	// Deleting the active version falls back to the highest remaining one.
+	if _, err := l.UpdateDialog(ctx, "versioned", []byte(versionedDialog("1.5", "one and a half")), false); err != nil {
+		t.Fatalf("UpdateDialog: %v", err)
+	}
+	deleted, err := l.DeleteDialog(ctx, "versioned", "2.0")
+	if err != nil {
+		t.Fatalf("DeleteDialog: %v", err)
+	}
+	if fmt.Sprint(deleted) != "[2.0]" {
+		t.Errorf("deleted = %v, want [2.0]", deleted)
+	}
+	if got := defaultVersion(t, l, "versioned"); got != "1.5" {
+		t.Errorf("default after delete = %s, want 1.5", got)
+	}
+
+	deleted, err = l.DeleteDialog(ctx, "versioned", "")
+	if err != nil {
+		t.Fatalf("DeleteDialog all: %v", err)
+	}
+	if fmt.Sprint(deleted) != "[1.0 1.5]" {
+		t.Errorf("deleted = %v, want [1.0 1.5]", deleted)
+	}
+	if _, ok := l.Get("versioned"); ok {
+		t.Error("dialog still loaded after deleting all versions")
+	}
+}
+
+func TestLoaderManageDialogsErrors(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{"versioned.yaml": versionedDialog("1.0", "file")})
+	l := NewLoader(dir, Definitions(&memoryDefinitionStore{}))
+	if _, err := l.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "file dialog",
+			err:  second(l.UpdateDialog(ctx, "versioned", []byte(versionedDialog("2.0", "x")), true)),
+			want: ErrFileDialog,
+		},
+		{
+			name: "unknown dialog",
+			err:  second(l.ActivateDialogVersion(ctx, "missing", "1.0")),
+			want: ErrDialogNotFound,
+		},
+		{
+			name: "invalid yaml",
+			err:  second(l.CreateDialog(ctx, []byte("states: ["))),
+			want: ErrInvalidDefinition,
+		},
+		{
+			name: "no version",
+			err:  second(l.CreateDialog(ctx, []byte(strings.Replace(versionedDialog("1.0", "x"), `version: "1.0"`, "", 1)))),
+			want: ErrInvalidDefinition,
+		},
+		{
+			name: "analysis error",
+			err:  second(l.CreateDialog(ctx, []byte(strings.Replace(versionedDialog("1.0", "x"), "target: done", "target: nowhere", 1)))),
+			want: ErrInvalidDefinition,
+		},
+		{
+			name: "no store",
+			err:  second(NewLoader(dir).CreateDialog(ctx, []byte(versionedDialog("1.0", "x")))),
+			want: ErrNoDefinitionStore,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.want) {
+				t.Errorf("got %v, want %v", tt.err, tt.want)
+			}
+		})
+	}
+}
+
+func second[T any](_ T, err error) error { return err }
+
+func TestLoaderStoredAndFileVersions(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{"versioned.yaml": versionedDialog("1.0", "file")})
+
+	store := &memoryDefinitionStore{}
+	for _, v := range []string{"1.0", "2.0"} {
+		if err := store.CreateDefinition(ctx, &DefinitionRecord{
+			Name:          "versioned",
+			DialogVersion: v,
+			Definition:    versionedDialog(v, "stored"),
+			IsActive:      v == "1.0",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := NewLoader(dir, Definitions(store))
+	if _, err := l.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if err := l.LoadStored(ctx); err != nil {
+		t.Fatalf("LoadStored: %v", err)
+	}
+
+	// The file wins a version defined both ways; the active stored version
+	// is the default even though a higher one exists.
+	sm, _ := l.GetVersion("versioned", "1.0")
+	if o := sm.Origin(); o.Source != SourceFile || !strings.HasSuffix(o.File, "versioned.yaml") {
+		t.Errorf("1.0 origin = %+v, want the file", o)
+	}
+	sm, _ = l.GetVersion("versioned", "2.0")
+	if sm.Origin().Source != SourceDatabase {
+		t.Errorf("2.0 source = %s, want %s", sm.Origin().Source, SourceDatabase)
+	}
+	if got := defaultVersion(t, l, "versioned"); got != "1.0" {
+		t.Errorf("default = %s, want the active 1.0", got)
+	}
+
+	// Reloading the files keeps the stored versions.
+	if _, err := l.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if _, ok := l.GetVersion("versioned", "2.0"); !ok {
+		t.Error("stored version lost after LoadAll")
+	}
+}
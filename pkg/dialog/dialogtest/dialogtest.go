@@ -0,0 +1,41 @@
+// Package dialogtest runs dialog scenario files from go test. It is kept out
+// of package dialog so that importing dialog does not pull in testing.
+package dialogtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/voicetyped/voicetyped/pkg/dialog"
+)
+
+// RunScenarioTests runs the scenarios in files, or in the scenario files of
+// directories, as subtests of t. Call it from a test next to your dialogs:
+//
+//	func TestDialogs(t *testing.T) {
+//		dialogtest.RunScenarioTests(t, "dialogs")
+//	}
+func RunScenarioTests(t *testing.T, paths ...string) {
+	t.Helper()
+	files, err := dialog.FindScenarioFiles(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no scenario files in %v", paths)
+	}
+	for _, file := range files {
+		results, err := dialog.RunScenarioFile(t.Context(), file)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		for _, res := range results {
+			t.Run(filepath.Base(file)+"/"+res.Name, func(t *testing.T) {
+				for _, f := range res.Failures {
+					t.Error(f)
+				}
+			})
+		}
+	}
+}
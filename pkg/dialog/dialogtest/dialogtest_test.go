@@ -0,0 +1,10 @@
+package dialogtest
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExampleScenarios(t *testing.T) {
+	RunScenarioTests(t, filepath.Join("..", "..", "..", "dialogs"))
+}
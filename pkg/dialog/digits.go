@@ -0,0 +1,111 @@
+package dialog
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Defaults for the collect_digits action.
+const (
+	DefaultDigitsVariable    = "digits"
+	DefaultFirstDigitTimeout = 5 * time.Second
+	DefaultInterDigitTimeout = 3 * time.Second
+)
+
+// DigitCollection is an in-progress collect_digits action. While a session
+// has one, DTMF events are buffered instead of evaluated as transitions.
+type DigitCollection struct {
+	MaxDigits         int           `json:"max_digits,omitempty"`
+	Terminator        string        `json:"terminator,omitempty"`
+	FirstDigitTimeout time.Duration `json:"first_digit_timeout"`
+	InterDigitTimeout time.Duration `json:"inter_digit_timeout"`
+	Variable          string        `json:"variable"`
+	Digits            string        `json:"digits"`
+}
+
+// Timeout returns how long to wait for the next digit.
+func (c DigitCollection) Timeout() time.Duration {
+	if c.Digits == "" {
+		return c.FirstDigitTimeout
+	}
+	return c.InterDigitTimeout
+}
+
+// newDigitCollection builds a collection from rendered collect_digits params.
+func newDigitCollection(params map[string]string) (*DigitCollection, error) {
+	c := &DigitCollection{
+		Terminator:        params["terminator"],
+		FirstDigitTimeout: DefaultFirstDigitTimeout,
+		InterDigitTimeout: DefaultInterDigitTimeout,
+		Variable:          params["variable"],
+	}
+	if c.Variable == "" {
+		c.Variable = DefaultDigitsVariable
+	}
+	if v := params["max_digits"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("collect_digits: invalid max_digits %q", v)
+		}
+		c.MaxDigits = n
+	}
+	for key, dst := range map[string]*time.Duration{
+		"first_digit_timeout": &c.FirstDigitTimeout,
+		"inter_digit_timeout": &c.InterDigitTimeout,
+	} {
+		v := params[key]
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("collect_digits: invalid %s %q", key, v)
+		}
+		*dst = d
+	}
+	return c, nil
+}
+
+// IsDTMFDigit reports whether r is a valid DTMF key (0-9, *, #, A-D).
+func IsDTMFDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '*' || r == '#' || (r >= 'A' && r <= 'D')
+}
+
+// splitDTMF expands a DTMF event carrying several digits as a string into
+// one event per digit. Other events are returned unchanged.
+func splitDTMF(ev Event) []Event {
+	s, ok := ev.Data.(string)
+	if ev.Type != EventDTMF || !ok {
+		return []Event{ev}
+	}
+	out := make([]Event, 0, len(s))
+	for _, r := range s {
+		out = append(out, Event{Type: EventDTMF, Data: r})
+	}
+	return out
+}
+
+// collectDigit adds a DTMF digit to the session's collection, finishing it
+// when the terminator is pressed or max_digits is reached.
+func (e *Engine) collectDigit(session *Session, c DigitCollection, ev Event, res *StepResult) {
+	digit := eventTrigger(ev)
+	if c.Terminator != "" && digit == c.Terminator {
+		e.finishDigits(session, c.Digits, c.Variable, res)
+		return
+	}
+	digits := session.AppendDigit(digit)
+	if c.MaxDigits > 0 && len(digits) >= c.MaxDigits {
+		e.finishDigits(session, digits, c.Variable, res)
+	}
+}
+
+// finishDigits ends collection, stores the digits and raises digits_collected
+// with the digits as event data. Digits the variable's declaration rejects,
+// such as none at all for an int, are not stored; the event still carries
+// them.
+func (e *Engine) finishDigits(session *Session, digits, variable string, res *StepResult) {
+	session.StopDigitCollection()
+	_ = assign(session, variable, digits)
+	res.raise(Event{Type: EventDigitsCollected, Data: digits})
+}
@@ -0,0 +1,192 @@
+package dialog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func digitsDialog(params map[string]string) *Dialog {
+	return &Dialog{
+		Name:         "digits-test",
+		InitialState: "ask",
+		States: map[string]State{
+			"ask": {
+				OnEnter: []Action{{Type: "collect_digits", Params: params}},
+				Transitions: []Transition{
+					{Event: "digits_collected", Condition: `{{ ne .Event "" }}`, Target: "confirm"},
+					{Event: "digits_collected", Target: "retry"},
+					{Event: "dtmf", Target: "wrong"},
+					{Event: "speech", Target: "operator"},
+				},
+			},
+			"confirm": {
+				OnEnter:  []Action{{Type: "play_tts", Params: map[string]string{"text": "You entered {{ .Variables.account }}."}}},
+				Terminal: true,
+			},
+			"retry":    {Terminal: true},
+			"wrong":    {Terminal: true},
+			"operator": {Terminal: true},
+		},
+	}
+}
+
+func startDigits(t *testing.T, params map[string]string) (*Engine, *Session) {
+	t.Helper()
+	d := digitsDialog(params)
+	engine := NewEngine(map[string]*StateMachine{d.Name: NewStateMachine(d)}, nil, nil)
+	session := NewSession("s1", d.Name, d.InitialState)
+	if _, err := engine.Start(t.Context(), session); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return engine, session
+}
+
+func TestCollectDigitsTerminator(t *testing.T) {
+	engine, session := startDigits(t, map[string]string{"variable": "account", "terminator": "#"})
+
+	res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventDTMF, Data: "1234#"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "confirm" {
+		t.Fatalf("state = %q, want confirm", res.CurrentState)
+	}
+	if got := session.GetVariable("account"); got != "1234" {
+		t.Errorf("account = %q, want 1234", got)
+	}
+	if len(res.Directives) != 1 || res.Directives[0].Params["text"] != "You entered 1234." {
+		t.Errorf("directives = %+v", res.Directives)
+	}
+}
+
+func TestCollectDigitsMaxDigits(t *testing.T) {
+	engine, session := startDigits(t, map[string]string{"variable": "account", "max_digits": "3"})
+
+	for i, digit := range []rune{'9', '8'} {
+		res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventDTMF, Data: digit})
+		if err != nil {
+			t.Fatalf("HandleEvent %d: %v", i, err)
+		}
+		if res.CurrentState != "ask" {
+			t.Fatalf("state after digit %d = %q, want ask", i, res.CurrentState)
+		}
+	}
+
+	res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventDTMF, Data: '7'})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "confirm" {
+		t.Errorf("state = %q, want confirm", res.CurrentState)
+	}
+	if got := session.GetVariable("account"); got != "987" {
+		t.Errorf("account = %q, want 987", got)
+	}
+	if _, ok := session.DigitCollection(); ok {
+		t.Error("collection still active after digits_collected")
+	}
+}
+
+func TestCollectDigitsTimeouts(t *testing.T) {
+	tests := []struct {
+		name   string
+		digits string
+		want   string
+	}{
+		{name: "inter-digit", digits: "42", want: "confirm"},
+		{name: "first-digit", digits: "", want: "retry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, session := startDigits(t, map[string]string{
+				"variable":            "account",
+				"max_digits":          "10",
+				"first_digit_timeout": "50ms",
+				"inter_digit_timeout": "50ms",
+			})
+
+			eventCh := make(chan Event, 1)
+			if tt.digits != "" {
+				eventCh <- Event{Type: EventDTMF, Data: tt.digits}
+			}
+
+			ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+			defer cancel()
+
+			if err := engine.Run(ctx, session, eventCh, nil); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := session.GetCurrentState(); got != tt.want {
+				t.Errorf("state = %q, want %q", got, tt.want)
+			}
+			if got := session.GetVariable("account"); got != tt.digits {
+				t.Errorf("account = %q, want %q", got, tt.digits)
+			}
+		})
+	}
+}
+
+func TestCollectDigitsCancelledByTransition(t *testing.T) {
+	engine, session := startDigits(t, map[string]string{"terminator": "#"})
+
+	res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "agent please"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "operator" {
+		t.Errorf("state = %q, want operator", res.CurrentState)
+	}
+	if _, ok := session.DigitCollection(); ok {
+		t.Error("collection still active after leaving the state")
+	}
+}
+
+func TestCollectDigitsInvalidParams(t *testing.T) {
+	for _, params := range []map[string]string{
+		{"max_digits": "many"},
+		{"inter_digit_timeout": "soon"},
+		{"first_digit_timeout": "-1s"},
+	} {
+		d := digitsDialog(params)
+		engine := NewEngine(map[string]*StateMachine{d.Name: NewStateMachine(d)}, nil, nil)
+		session := NewSession("s1", d.Name, d.InitialState)
+		if _, err := engine.Start(t.Context(), session); err == nil {
+			t.Errorf("params %v: expected error", params)
+		}
+	}
+}
+
+func TestCollectDigitsSurvivesResume(t *testing.T) {
+	engine, session := startDigits(t, map[string]string{"variable": "account", "terminator": "#"})
+	if _, err := engine.HandleEvent(t.Context(), session, Event{Type: EventDTMF, Data: "12"}); err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+
+	// Round-trip the record's gather progress as the database would.
+	rec := NewSessionRecord(session, "", "")
+	value, err := rec.Digits.Value()
+	if err != nil {
+		t.Fatalf("Value: %v", err)
+	}
+	rec.Digits = &DigitsJSON{}
+	if err := rec.Digits.Scan(value); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	resumed := rec.Session()
+	if got := resumed.CopyVisits()["ask"]; got != 1 {
+		t.Errorf("visits of ask = %d, want 1", got)
+	}
+
+	res, err := engine.HandleEvent(t.Context(), resumed, Event{Type: EventDTMF, Data: "3#"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "confirm" {
+		t.Fatalf("state = %q, want confirm", res.CurrentState)
+	}
+	if got := resumed.GetVariable("account"); got != "123" {
+		t.Errorf("account = %q, want 123", got)
+	}
+}
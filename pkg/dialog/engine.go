@@ -19,38 +19,343 @@ type ASRResult struct {
 	IsFinal    bool
 }
 
+// Event types understood by the engine.
+const (
+	EventSpeech      = "speech"
+	EventDTMF        = "dtmf"
+	EventTimeout     = "timeout"
+	EventHookResult  = "hook_result"
+	EventHookError   = "hook_error"
+	EventTTSComplete = "tts_complete"
+
+	EventDigitsCollected = "digits_collected"
+)
+
+// VarError is the session variable holding the message of the error that
+// made the engine run the dialog's on_error actions.
+const VarError = "error"
+
+// ParamBargeIn is the play_tts and play_audio param that lets caller speech
+// interrupt the prompt ("true") or not ("false"). See State.BargeIn.
+const ParamBargeIn = "barge_in"
+
+// maxInternalEvents bounds the chain of engine-raised events handled in a
+// single step, guarding against hook_result/hook_error loops in a dialog.
+const maxInternalEvents = 32
+
+// Event is an input delivered to a running dialog. Data is the speech text
+// for speech events and the digit rune for DTMF events; a DTMF event whose
+// Data is a string is handled as one event per digit. Internal events such
+// as hook_result carry no data and leave the session's last event untouched.
+type Event struct {
+	Type string
+	Data any
+	// Confidence is the recognition confidence of a speech event, if known.
+	Confidence float32
+
+	// target forces a transition to the named state, bypassing the
+	// current state's transitions. Set for hook-directed transitions.
+	target string
+}
+
+// StepResult is the outcome of starting a dialog or processing one event.
+type StepResult struct {
+	PreviousState string
+	CurrentState  string
+	Terminal      bool
+	// Directives are rendered actions the engine does not perform itself
+	// (play_tts, play_audio, hangup, ...) and that the caller must carry out.
+	Directives []Action
+
+	// pending holds events raised by actions during this step.
+	pending []Event
+}
+
+func (r *StepResult) raise(ev Event) {
+	r.pending = append(r.pending, ev)
+}
+
+// StepFunc receives the result of every event processed by Run.
+type StepFunc func(*StepResult) error
+
+// DialogSource resolves dialog state machines by name. *Loader implements it.
+// Sessions are pinned to the state machine they first resolve to, so later
+// changes in the source only affect new sessions.
+type DialogSource interface {
+	Get(name string) (*StateMachine, bool)
+}
+
+type staticSource map[string]*StateMachine
+
+func (s staticSource) Get(name string) (*StateMachine, bool) {
+	sm, ok := s[name]
+	return sm, ok
+}
+
+// HookExecutor calls hook endpoints for call_hook actions. *hooks.Executor
+// implements it; scenario tests substitute canned responses.
+type HookExecutor interface {
+	Execute(ctx context.Context, cfg hooks.HookConfig, req hooks.HookRequest) (*hooks.HookResponse, error)
+}
+
 // Engine runs dialog state machines for active calls.
 type Engine struct {
-	dialogs   map[string]*StateMachine
-	hooks     *hooks.Executor
-	publisher *events.Publisher
+	dialogs      DialogSource
+	hooks        HookExecutor
+	hookRegistry hooks.Registry
+	llm          LLMClient
+	publisher    *events.Publisher
+}
+
+// EngineOption configures an Engine.
+type EngineOption func(*Engine)
+
+// UseHooks makes the engine call hooks through h instead of the executor
+// passed to the constructor, e.g. to answer them from stubs.
+func UseHooks(h HookExecutor) EngineOption {
+	return func(e *Engine) {
+		e.hooks = h
+	}
+}
+
+// UseHookRegistry lets call_hook actions name the hooks defined in r, in
+// addition to those their dialog defines.
+func UseHookRegistry(r hooks.Registry) EngineOption {
+	return func(e *Engine) {
+		e.hookRegistry = r
+	}
+}
+
+// UseLLM makes the engine complete llm_turn actions through c. Without it
+// they raise llm_error.
+func UseLLM(c LLMClient) EngineOption {
+	return func(e *Engine) {
+		e.llm = c
+	}
 }
 
-// NewEngine creates a new dialog engine.
-func NewEngine(dialogs map[string]*StateMachine, hookExec *hooks.Executor, pub *events.Publisher) *Engine {
-	return &Engine{
-		dialogs:   dialogs,
-		hooks:     hookExec,
+// NewEngine creates a new dialog engine over a fixed set of dialogs.
+func NewEngine(dialogs map[string]*StateMachine, hookExec *hooks.Executor, pub *events.Publisher, opts ...EngineOption) *Engine {
+	return NewEngineWithSource(staticSource(dialogs), hookExec, pub, opts...)
+}
+
+// NewEngineWithSource creates a dialog engine that resolves dialogs through src,
+// typically a *Loader so hot-reloaded definitions are picked up.
+func NewEngineWithSource(src DialogSource, hookExec *hooks.Executor, pub *events.Publisher, opts ...EngineOption) *Engine {
+	e := &Engine{
+		dialogs:   src,
 		publisher: pub,
 	}
+	if hookExec != nil {
+		e.hooks = hookExec
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
-// RunDialog is the core event loop for a single call.
-func (e *Engine) RunDialog(ctx context.Context, session *Session, speechCh <-chan ASRResult, dtmfCh <-chan rune, speakFn SpeakFunc) error {
-	sm, ok := e.dialogs[session.DialogName]
-	if !ok {
-		return fmt.Errorf("dialog %q not found", session.DialogName)
+// Start gives the dialog's declared variables that are not set yet their
+// default, then enters the session's current state and runs its on_enter
+// actions.
+func (e *Engine) Start(ctx context.Context, session *Session) (*StepResult, error) {
+	prev := session.GetCurrentState()
+	res, err := e.start(ctx, session)
+	if err != nil {
+		return e.recoverStep(ctx, session, prev, err)
 	}
+	return res, nil
+}
 
-	state, ok := sm.GetState(session.GetCurrentState())
-	if !ok {
-		return fmt.Errorf("state %q not found in dialog %q", session.GetCurrentState(), session.DialogName)
+func (e *Engine) start(ctx context.Context, session *Session) (*StepResult, error) {
+	sm, state, err := e.resolve(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := setDefaults(session, sm); err != nil {
+		return nil, err
+	}
+
+	res := &StepResult{PreviousState: session.GetCurrentState()}
+	if err := e.enter(ctx, session, session.GetCurrentState(), state, res); err != nil {
+		return nil, err
+	}
+	return e.settle(ctx, session, res)
+}
+
+// HandleEvent evaluates the current state's transitions for ev and, on a
+// match, runs the transition actions and enters the target state. Events
+// raised along the way (hook_result, hook_error) are handled before it returns.
+// A step that fails runs the dialog's on_error actions, see recoverStep.
+func (e *Engine) HandleEvent(ctx context.Context, session *Session, ev Event) (*StepResult, error) {
+	prev := session.GetCurrentState()
+	res, err := e.handleEvent(ctx, session, ev)
+	if err != nil {
+		return e.recoverStep(ctx, session, prev, err)
+	}
+	return res, nil
+}
+
+func (e *Engine) handleEvent(ctx context.Context, session *Session, ev Event) (*StepResult, error) {
+	res := &StepResult{PreviousState: session.GetCurrentState()}
+	recordInput(session, ev)
+	for _, ev := range splitDTMF(ev) {
+		if err := e.handle(ctx, session, ev, res); err != nil {
+			return nil, err
+		}
+		if _, err := e.settle(ctx, session, res); err != nil {
+			return nil, err
+		}
+		if res.Terminal {
+			break
+		}
+	}
+	return res, nil
+}
+
+// recoverStep handles err, which aborted a step that began in state prev. The
+// error is published and, when the dialog has on_error actions, stored in the
+// error variable and handled by running them: their result is returned in
+// place of err and the session carries on in whatever state the failed step
+// left it. Events the on_error actions raise are not handled. Without
+// on_error actions, or when they fail too, err is returned.
+func (e *Engine) recoverStep(ctx context.Context, session *Session, prev string, err error) (*StepResult, error) {
+	sm := session.Machine()
+	handled := sm != nil && len(sm.Dialog().OnError) > 0
+	if e.publisher != nil {
+		_ = e.publisher.Emit(ctx, events.SystemError, session.ID, &events.DialogErrorData{
+			DialogName: session.DialogName,
+			State:      session.GetCurrentState(),
+			Error:      err.Error(),
+			Handled:    handled,
+		})
+	}
+	if !handled {
+		return nil, err
+	}
+
+	session.SetVariable(VarError, err.Error())
+	res := &StepResult{PreviousState: prev}
+	if herr := e.executeActions(ctx, session, sm.Dialog().OnError, res); herr != nil {
+		return nil, fmt.Errorf("%w (on_error: %w)", err, herr)
+	}
+	_, state, rerr := e.resolve(session)
+	if rerr != nil {
+		return nil, err
+	}
+	res.pending = nil
+	res.CurrentState = session.GetCurrentState()
+	res.Terminal = state.Terminal
+	return res, nil
+}
+
+// End runs the dialog's on_hangup actions for a session that is ending,
+// whether the caller hung up, the dialog reached a terminal state or the
+// session was abandoned. The actions run once per session; later calls
+// return an empty result. Events the actions raise are not handled, and since
+// the call is gone the caller may ignore the returned directives.
+func (e *Engine) End(ctx context.Context, session *Session) (*StepResult, error) {
+	current := session.GetCurrentState()
+	res := &StepResult{PreviousState: current, CurrentState: current}
+	sm := session.Machine()
+	if sm == nil || !session.markEnded() {
+		return res, nil
+	}
+	if err := e.executeActions(ctx, session, sm.Dialog().OnHangup, res); err != nil {
+		return nil, fmt.Errorf("dialog %q on_hangup: %w", session.DialogName, err)
+	}
+	res.pending = nil
+	return res, nil
+}
+
+func (e *Engine) handle(ctx context.Context, session *Session, ev Event, res *StepResult) error {
+	sm, state, err := e.resolve(session)
+	if err != nil {
+		return err
+	}
+
+	if c, ok := session.DigitCollection(); ok && ev.target == "" {
+		switch ev.Type {
+		case EventDTMF:
+			e.collectDigit(session, c, ev, res)
+			return nil
+		case EventTimeout:
+			e.finishDigits(session, c.Digits, c.Variable, res)
+			return nil
+		}
+	}
+
+	var (
+		target  string
+		actions []Action
+	)
+	switch {
+	case ev.target != "":
+		target = ev.target
+	case ev.Type == EventTimeout:
+		if state.Type != StateTypeGather {
+			target = state.TimeoutNext
+		}
+	default:
+		if ev.Data != nil {
+			session.SetLastEvent(ev.Data)
+		}
+		target, actions, err = sm.EvaluateTransitions(state, ev.Type, session)
+		if err != nil {
+			return err
+		}
+	}
+
+	if target == "" {
+		if state.Type == StateTypeGather && state.Gather.accepts(ev) {
+			return e.gather(ctx, session, sm, state, ev, res)
+		}
+		return nil
+	}
+	if err := e.executeActions(ctx, session, actions, res); err != nil {
+		return err
+	}
+	return e.transition(ctx, session, sm, target, eventTrigger(ev), res)
+}
+
+// settle processes the events raised during a step until none remain or the
+// dialog reaches a terminal state, then records where the session ended up.
+func (e *Engine) settle(ctx context.Context, session *Session, res *StepResult) (*StepResult, error) {
+	for handled := 0; ; handled++ {
+		_, state, err := e.resolve(session)
+		if err != nil {
+			return nil, err
+		}
+		if state.Terminal || len(res.pending) == 0 {
+			res.pending = nil
+			res.CurrentState = session.GetCurrentState()
+			res.Terminal = state.Terminal
+			return res, nil
+		}
+		if handled == maxInternalEvents {
+			return nil, fmt.Errorf("dialog %q: more than %d internal events in one step",
+				session.DialogName, maxInternalEvents)
+		}
+
+		ev := res.pending[0]
+		res.pending = res.pending[1:]
+		if err := e.handle(ctx, session, ev, res); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	// Execute on_enter actions for initial state.
-	if err := e.executeActions(ctx, session, state.OnEnter, speakFn); err != nil {
+// Run processes events for a started session until the dialog reaches a
+// terminal state, the events channel is closed or ctx is cancelled. State
+// timeouts are handled internally and reported to onStep like any other event.
+func (e *Engine) Run(ctx context.Context, session *Session, eventCh <-chan Event, onStep StepFunc) error {
+	_, state, err := e.resolve(session)
+	if err != nil {
 		return err
 	}
+	if state.Terminal {
+		return nil
+	}
 
 	var timer *time.Timer
 	defer func() {
@@ -61,7 +366,7 @@ func (e *Engine) RunDialog(ctx context.Context, session *Session, speechCh <-cha
 
 	for {
 		// Set up timeout channel.
-		if dur, err := time.ParseDuration(state.Timeout); err == nil && dur > 0 {
+		if dur := timeoutFor(session, state); dur > 0 {
 			if timer == nil {
 				timer = time.NewTimer(dur)
 			} else {
@@ -83,138 +388,176 @@ func (e *Engine) RunDialog(ctx context.Context, session *Session, speechCh <-cha
 			timeoutCh = timer.C
 		}
 
+		var ev Event
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 
-		case result, ok := <-speechCh:
+		case in, ok := <-eventCh:
 			if !ok {
 				return nil
 			}
-			if !result.IsFinal {
-				continue
-			}
-			session.SetLastEvent(result.Text)
-			nextState, actions, err := sm.EvaluateTransitions(state, "speech", session)
-			if err != nil {
+			ev = in
+
+		case <-timeoutCh:
+			ev = Event{Type: EventTimeout}
+		}
+
+		res, err := e.HandleEvent(ctx, session, ev)
+		if err != nil {
+			return err
+		}
+		if onStep != nil {
+			if err := onStep(res); err != nil {
 				return err
 			}
-			if nextState != "" {
-				if err := e.executeActions(ctx, session, actions, speakFn); err != nil {
-					return err
-				}
-				state, err = e.transition(ctx, session, sm, nextState, speakFn)
-				if err != nil {
+		}
+		if res.Terminal {
+			return nil
+		}
+
+		_, state, err = e.resolve(session)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// RunDialog is the core event loop for a single call. It starts the session,
+// feeds final speech results and DTMF digits to Run, and plays every play_tts
+// directive through speakFn.
+func (e *Engine) RunDialog(ctx context.Context, session *Session, speechCh <-chan ASRResult, dtmfCh <-chan rune, speakFn SpeakFunc) error {
+	speak := func(res *StepResult) error {
+		for _, d := range res.Directives {
+			if d.Type == "play_tts" && speakFn != nil {
+				if err := speakFn(d.Params["text"]); err != nil {
 					return err
 				}
 			}
+		}
+		return nil
+	}
 
-		case digit, ok := <-dtmfCh:
-			if !ok {
-				return nil
-			}
-			session.SetLastEvent(digit)
-			nextState, actions, err := sm.EvaluateTransitions(state, "dtmf", session)
-			if err != nil {
-				return err
-			}
-			if nextState != "" {
-				if err := e.executeActions(ctx, session, actions, speakFn); err != nil {
-					return err
+	res, err := e.Start(ctx, session)
+	if err != nil {
+		return err
+	}
+	if err := speak(res); err != nil {
+		return err
+	}
+	if res.Terminal {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	eventCh := make(chan Event)
+	go func() {
+		defer close(eventCh)
+		for {
+			var ev Event
+			select {
+			case <-ctx.Done():
+				return
+			case result, ok := <-speechCh:
+				if !ok {
+					return
 				}
-				state, err = e.transition(ctx, session, sm, nextState, speakFn)
-				if err != nil {
-					return err
+				if !result.IsFinal {
+					continue
 				}
-			}
-
-		case <-timeoutCh:
-			if state.TimeoutNext != "" {
-				var err error
-				state, err = e.transition(ctx, session, sm, state.TimeoutNext, speakFn)
-				if err != nil {
-					return err
+				ev = Event{Type: EventSpeech, Data: result.Text, Confidence: result.Confidence}
+			case digit, ok := <-dtmfCh:
+				if !ok {
+					return
 				}
+				ev = Event{Type: EventDTMF, Data: digit}
+			}
+			select {
+			case eventCh <- ev:
+			case <-ctx.Done():
+				return
 			}
 		}
+	}()
 
-		if state.Terminal {
-			return nil
+	return e.Run(ctx, session, eventCh, speak)
+}
+
+// resolve returns the session's state machine and current state. A session
+// not yet pinned to a state machine is pinned to the source's current one.
+func (e *Engine) resolve(session *Session) (*StateMachine, State, error) {
+	sm := session.Machine()
+	if sm == nil {
+		var ok bool
+		sm, ok = e.dialogs.Get(session.DialogName)
+		if !ok {
+			return nil, State{}, fmt.Errorf("dialog %q not found", session.DialogName)
 		}
+		session.Pin(sm)
+	}
+	state, ok := sm.GetState(session.GetCurrentState())
+	if !ok {
+		return nil, State{}, fmt.Errorf("state %q not found in dialog %q", session.GetCurrentState(), session.DialogName)
 	}
+	return sm, state, nil
 }
 
-func (e *Engine) transition(ctx context.Context, session *Session, sm *StateMachine, target string, speakFn SpeakFunc) (State, error) {
+func (e *Engine) transition(ctx context.Context, session *Session, sm *StateMachine, target, trigger string, res *StepResult) error {
+	state, ok := sm.GetState(target)
+	if !ok {
+		return fmt.Errorf("state %q not found", target)
+	}
+
 	from := session.GetCurrentState()
-	session.RecordTransition(from, target, fmt.Sprintf("%v", session.GetLastEvent()))
+	session.RecordTransition(from, target, trigger)
+	session.StopDigitCollection()
 
 	if e.publisher != nil {
 		_ = e.publisher.Emit(ctx, events.StateTransition, session.ID, &events.StateTransitionData{
-			FromState:    from,
-			ToState:      target,
-			TriggerEvent: fmt.Sprintf("%v", session.GetLastEvent()),
-			DialogName:   session.DialogName,
+			FromState:     from,
+			ToState:       target,
+			TriggerEvent:  trigger,
+			DialogName:    session.DialogName,
+			DialogVersion: session.GetDialogVersion(),
 		})
 	}
 
-	state, ok := sm.GetState(target)
-	if !ok {
-		return State{}, fmt.Errorf("state %q not found", target)
-	}
-
-	if err := e.executeActions(ctx, session, state.OnEnter, speakFn); err != nil {
-		return state, err
-	}
-
-	return state, nil
+	return e.enter(ctx, session, target, state, res)
 }
 
-func (e *Engine) executeActions(ctx context.Context, session *Session, actions []Action, speakFn SpeakFunc) error {
+func (e *Engine) executeActions(ctx context.Context, session *Session, actions []Action, res *StepResult) error {
 	for _, action := range actions {
-		if err := e.executeAction(ctx, session, action, speakFn); err != nil {
+		if err := e.executeAction(ctx, session, action, res); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (e *Engine) executeAction(ctx context.Context, session *Session, action Action, speakFn SpeakFunc) error {
+func (e *Engine) executeAction(ctx context.Context, session *Session, action Action, res *StepResult) error {
 	switch action.Type {
-	case "play_tts":
-		text, err := RenderParam(action.Params["text"], session)
-		if err != nil {
-			return fmt.Errorf("render TTS text: %w", err)
-		}
-		if speakFn != nil {
-			return speakFn(text)
-		}
-
 	case "call_hook":
-		if e.hooks == nil {
-			return nil
-		}
-		cfg := hooks.HookConfig{
-			URL:        action.Params["url"],
-			AuthType:   action.Params["auth_type"],
-			AuthSecret: action.Params["auth_secret"],
-			TimeoutSec: 10,
+		if err := e.callHook(ctx, session, action, res); err != nil {
+			return err
 		}
-		req := hooks.HookRequest{
-			SessionID: session.ID,
-			State:     session.GetCurrentState(),
-			Event:     fmt.Sprintf("%v", session.GetLastEvent()),
-			Variables: session.CopyVariables(),
+
+	case "llm_turn":
+		if err := e.llmTurn(ctx, session, action, res); err != nil {
+			return err
 		}
-		resp, err := e.hooks.Execute(ctx, cfg, req)
+
+	case "collect_digits":
+		rendered, err := renderAction(action, session)
 		if err != nil {
-			return nil // hook errors are non-fatal by default
+			return err
 		}
-		if resp != nil {
-			for k, v := range resp.Variables {
-				session.SetVariable(k, v)
-			}
-			session.SetLastResult(resp.Data)
+		c, err := newDigitCollection(rendered.Params)
+		if err != nil {
+			return err
 		}
+		session.StartDigitCollection(c)
 
 	case "set_variable":
 		for k, v := range action.Params {
@@ -222,24 +565,104 @@ func (e *Engine) executeAction(ctx context.Context, session *Session, action Act
 			if err != nil {
 				return fmt.Errorf("render variable %q: %w", k, err)
 			}
-			session.SetVariable(k, rendered)
+			if err := assign(session, k, rendered); err != nil {
+				return err
+			}
 		}
 
-	case "hangup":
-		// Signal hangup by cancelling context (handled by caller).
-		return nil
-
-	case "play_audio":
-		// Placeholder for audio file playback.
-		return nil
+	default:
+		// play_tts, play_audio, hangup and any custom actions are carried out
+		// by the caller; render their params against the current session.
+		directive, err := renderAction(action, session)
+		if err != nil {
+			return err
+		}
+		recordPrompt(session, directive)
+		res.Directives = append(res.Directives, withBargeIn(session, directive))
 	}
 
 	if e.publisher != nil {
 		_ = e.publisher.Emit(ctx, events.ActionExecuted, session.ID, &events.ActionExecutedData{
 			ActionType: action.Type,
-			Params:     action.Params,
+			Params:     redactParams(action),
 		})
 	}
 
 	return nil
 }
+
+// renderAction returns a copy of action with every param rendered.
+func renderAction(action Action, session *Session) (Action, error) {
+	out := Action{Type: action.Type}
+	if len(action.Params) == 0 {
+		return out, nil
+	}
+	out.Params = make(map[string]string, len(action.Params))
+	for k, v := range action.Params {
+		rendered, err := RenderParam(v, session)
+		if err != nil {
+			return Action{}, fmt.Errorf("render %s param %q: %w", action.Type, k, err)
+		}
+		out.Params[k] = rendered
+	}
+	return out, nil
+}
+
+// withBargeIn sets the barge_in param of a prompt directive played in a
+// barge_in state, unless the action sets it itself. The caller stops playback
+// when the caller starts speaking over a prompt with barge_in "true".
+func withBargeIn(session *Session, directive Action) Action {
+	if directive.Type != "play_tts" && directive.Type != "play_audio" {
+		return directive
+	}
+	if _, ok := directive.Params[ParamBargeIn]; ok {
+		return directive
+	}
+	sm := session.Machine()
+	if sm == nil {
+		return directive
+	}
+	if state, ok := sm.GetState(session.GetCurrentState()); !ok || !state.BargeIn {
+		return directive
+	}
+	params := make(map[string]string, len(directive.Params)+1)
+	for k, v := range directive.Params {
+		params[k] = v
+	}
+	params[ParamBargeIn] = "true"
+	directive.Params = params
+	return directive
+}
+
+// timeoutFor returns how long Run waits for the next event in state: the
+// digit timeout while collecting digits, otherwise the state's timeout.
+func timeoutFor(session *Session, state State) time.Duration {
+	if c, ok := session.DigitCollection(); ok {
+		return c.Timeout()
+	}
+	dur, err := time.ParseDuration(state.Timeout)
+	if err != nil {
+		if state.Type == StateTypeGather {
+			return DefaultGatherTimeout
+		}
+		return 0
+	}
+	return dur
+}
+
+// eventTrigger returns the history trigger recorded for a transition caused by ev.
+func eventTrigger(ev Event) string {
+	switch d := ev.Data.(type) {
+	case nil:
+		return ev.Type
+	case rune:
+		return string(d)
+	case string:
+		if d == "" {
+			return ev.Type
+		}
+		return d
+	default:
+		return fmt.Sprintf("%v", d)
+	}
+}
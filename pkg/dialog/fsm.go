@@ -4,45 +4,25 @@ import "fmt"
 
 // StateMachine validates and provides access to dialog states.
 type StateMachine struct {
-	dialog *Dialog
+	dialog  *Dialog
+	intents *IntentClassifier
+	origin  Origin
 }
 
-// NewStateMachine creates a state machine from a dialog definition.
+// NewStateMachine creates a state machine from a dialog definition, training
+// an intent classifier when the dialog declares intents.
 func NewStateMachine(d *Dialog) *StateMachine {
-	return &StateMachine{dialog: d}
+	sm := &StateMachine{dialog: d}
+	if len(d.Intents) > 0 {
+		sm.intents = NewIntentClassifier(d.Intents)
+	}
+	return sm
 }
 
-// Validate checks the dialog definition for consistency.
+// Validate analyzes the dialog definition and returns an *AnalysisError if
+// it has errors. Warnings are ignored; use Analyze to see them.
 func (sm *StateMachine) Validate() error {
-	if sm.dialog.InitialState == "" {
-		return fmt.Errorf("dialog %q: initial_state is required", sm.dialog.Name)
-	}
-
-	if _, ok := sm.dialog.States[sm.dialog.InitialState]; !ok {
-		return fmt.Errorf("dialog %q: initial_state %q not found in states",
-			sm.dialog.Name, sm.dialog.InitialState)
-	}
-
-	for name, state := range sm.dialog.States {
-		for i, t := range state.Transitions {
-			if t.Target == "" {
-				return fmt.Errorf("dialog %q state %q transition %d: target is required",
-					sm.dialog.Name, name, i)
-			}
-			if _, ok := sm.dialog.States[t.Target]; !ok {
-				return fmt.Errorf("dialog %q state %q transition %d: target %q not found",
-					sm.dialog.Name, name, i, t.Target)
-			}
-		}
-		if state.TimeoutNext != "" {
-			if _, ok := sm.dialog.States[state.TimeoutNext]; !ok {
-				return fmt.Errorf("dialog %q state %q: timeout_next %q not found",
-					sm.dialog.Name, name, state.TimeoutNext)
-			}
-		}
-	}
-
-	return nil
+	return Analyze(sm.dialog, "", nil).Err(false)
 }
 
 // GetState returns the state definition for the given name.
@@ -61,21 +41,129 @@ func (sm *StateMachine) Dialog() *Dialog {
 	return sm.dialog
 }
 
-// EvaluateTransitions checks all transitions for the given event type
-// and returns the first matching target state.
+// Origin returns where the loader got the dialog definition from. It is the
+// zero Origin for state machines built directly with NewStateMachine.
+func (sm *StateMachine) Origin() Origin {
+	return sm.origin
+}
+
+// TargetCurrent is a transition target that re-enters the current state,
+// running its on_enter actions again. It lets a global transition repeat
+// the prompt of whichever state the caller is in.
+const TargetCurrent = "$current"
+
+// EvaluateTransitions checks all transitions for the given event type and
+// returns the best matching target state. Transitions with a match block or
+// intent are ranked by score above those without; ties go to the transition
+// declared first. If none of the state's transitions match, the dialog's
+// global transitions are evaluated the same way unless the state ignores
+// them. Regex captures of the chosen transition are stored as session
+// variables. Speech is classified against the dialog's intents first and the
+// ranking is stored on the session for templates.
 func (sm *StateMachine) EvaluateTransitions(state State, event string, session *Session) (string, []Action, error) {
-	for _, t := range state.Transitions {
+	var intents []IntentScore
+	text := eventText(session.GetLastEvent())
+	if event == EventSpeech && sm.intents != nil {
+		intents = sm.intents.Classify(text)
+		session.SetIntents(intents[:min(len(intents), IntentTopN)])
+	}
+
+	best, captures, err := bestTransition(state.Transitions, event, text, intents, session)
+	if err != nil {
+		return "", nil, err
+	}
+	if best == nil && sm.dialog.usesGlobal(state) {
+		best, captures, err = bestTransition(sm.dialog.GlobalTransitions, event, text, intents, session)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	if best == nil {
+		return "", nil, nil
+	}
+	for k, v := range captures {
+		if err := assign(session, k, v); err != nil {
+			return "", nil, err
+		}
+	}
+	if best.Target == TargetCurrent {
+		return session.GetCurrentState(), best.Actions, nil
+	}
+	return best.Target, best.Actions, nil
+}
+
+// bestTransition returns the highest-ranked transition for event whose match,
+// intent and condition pass, with its regex captures, or nil.
+func bestTransition(transitions []Transition, event, text string, intents []IntentScore, session *Session) (*Transition, map[string]string, error) {
+	var (
+		best      *Transition
+		bestScore = -1.0
+		captures  map[string]string
+	)
+	for i := range transitions {
+		t := &transitions[i]
 		if t.Event != event {
 			continue
 		}
 
+		score := 0.0
+		var caps map[string]string
+		if t.Match != nil {
+			s, c, ok, err := t.Match.Score(text)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				continue
+			}
+			score, caps = s, c
+		}
+		if t.Intent != "" {
+			s, ok := intentScore(intents, t.Intent)
+			minScore := t.MinScore
+			if minScore == 0 {
+				minScore = DefaultIntentMinScore
+			}
+			if !ok || s < minScore {
+				continue
+			}
+			if t.Match == nil || s < score {
+				score = s
+			}
+		}
+		if score <= bestScore {
+			continue
+		}
+
 		match, err := EvalCondition(t.Condition, session)
 		if err != nil {
-			return "", nil, fmt.Errorf("eval condition %q: %w", t.Condition, err)
+			return nil, nil, fmt.Errorf("eval condition %q: %w", t.Condition, err)
 		}
 		if match {
-			return t.Target, t.Actions, nil
+			best, bestScore, captures = t, score, caps
+		}
+	}
+	return best, captures, nil
+}
+
+// usesGlobal reports whether the dialog's global transitions apply in state.
+func (d *Dialog) usesGlobal(state State) bool {
+	return len(d.GlobalTransitions) > 0 && !state.Terminal && !state.IgnoreGlobalTransitions
+}
+
+// HandlesEvent reports whether any state in the dialog, or its global
+// transitions, has a transition triggered by the given event type.
+func (sm *StateMachine) HandlesEvent(event string) bool {
+	if hasEvent(sm.dialog.GlobalTransitions, event) {
+		return true
+	}
+	for _, state := range sm.dialog.States {
+		for _, t := range state.Transitions {
+			if t.Event == event {
+				return true
+			}
 		}
 	}
-	return "", nil, nil
+	return false
 }
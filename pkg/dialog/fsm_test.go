@@ -77,6 +77,89 @@ func TestStateMachineValidateErrors(t *testing.T) {
 				d.States["greeting"] = s
 			},
 		},
+		{
+			name: "invalid timeout",
+			modify: func(d *Dialog) {
+				s := d.States["greeting"]
+				s.Timeout = "soon"
+				d.States["greeting"] = s
+			},
+		},
+		{
+			name: "condition template syntax",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Transitions: []Transition{{Event: "speech", Condition: "{{ .Event", Target: "process"}}}
+			},
+		},
+		{
+			name: "play_tts without text",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{OnEnter: []Action{{Type: "play_tts"}}}
+			},
+		},
+		{
+			name: "unknown state type",
+			modify: func(d *Dialog) {
+				s := d.States["menu"]
+				s.Type = "menu"
+				d.States["menu"] = s
+			},
+		},
+		{
+			name: "match without keywords or regex",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Transitions: []Transition{{Event: "speech", Match: &Match{}, Target: "process"}}}
+			},
+		},
+		{
+			name: "match regex invalid",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Transitions: []Transition{{Event: "speech", Match: &Match{Regex: "("}, Target: "process"}}}
+			},
+		},
+		{
+			name: "intent not declared",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Transitions: []Transition{{Event: "speech", Intent: "billing", Target: "process"}}}
+			},
+		},
+		{
+			name: "intent without examples",
+			modify: func(d *Dialog) {
+				d.Intents = map[string]Intent{"billing": {}}
+			},
+		},
+		{
+			name: "gather without block",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Type: StateTypeGather}
+			},
+		},
+		{
+			name: "gather next not found",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Type: StateTypeGather, Gather: &Gather{
+					Prompt: "Choose", Next: "missing", MaxAttemptsNext: "goodbye",
+				}}
+			},
+		},
+		{
+			name: "gather without max_attempts_next",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Type: StateTypeGather, Gather: &Gather{
+					Prompt: "Choose", Next: "process",
+				}}
+			},
+		},
+		{
+			name: "gather digits with speech input",
+			modify: func(d *Dialog) {
+				d.States["menu"] = State{Type: StateTypeGather, Gather: &Gather{
+					Prompt: "Choose", Input: "speech", Digits: map[string]string{"max_digits": "4"},
+					Next: "process", MaxAttemptsNext: "goodbye",
+				}}
+			},
+		},
 	}
 
 	for _, tt := range tests {
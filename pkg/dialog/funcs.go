@@ -0,0 +1,149 @@
+package dialog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// timeNow is the clock behind the now template function.
+var timeNow = time.Now
+
+// templateFuncs is the function library available to conditions and params.
+// It is deliberately small: nothing here touches the network or has side
+// effects on the session, and the only environment or filesystem access is
+// inTimezone loading the host's time zone database (ZONEINFO or zoneinfo).
+var templateFuncs = template.FuncMap{
+	// Strings.
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trim":       strings.TrimSpace,
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"digitsOnly": digitsOnly,
+	"regexMatch": regexMatch,
+
+	// Conversion and defaults.
+	"atoi":    atoi,
+	"default": defaultValue,
+	"json":    toJSON,
+	"len":     length,
+
+	// Time.
+	"now":        func() time.Time { return timeNow() },
+	"inTimezone": inTimezone,
+	"formatTime": func(layout string, t time.Time) string { return t.Format(layout) },
+}
+
+// digitsOnly strips everything but ASCII digits, e.g. for spoken numbers.
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func regexMatch(pattern, s string) (bool, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+// atoi converts a string to an int, returning 0 when it is not a number.
+func atoi(v any) int {
+	n, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(v)))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// defaultValue returns val unless it is empty, in which case def is returned.
+// It is written `default "fallback" .Variables.name`.
+func defaultValue(def, val any) any {
+	if isEmpty(val) {
+		return def
+	}
+	return val
+}
+
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// length is a nil-safe len that counts characters, not bytes, in strings.
+func length(v any) (int, error) {
+	if v == nil {
+		return 0, nil
+	}
+	if s, ok := v.(string); ok {
+		return utf8.RuneCountInString(s), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.Chan:
+		return rv.Len(), nil
+	}
+	return 0, fmt.Errorf("len of type %T", v)
+}
+
+// inTimezone converts t to the named IANA time zone, e.g.
+// `(inTimezone "America/New_York" now).Hour`.
+func inTimezone(name string, t time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
+// compileRegex compiles and caches a regular expression.
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.get(expr); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.add(expr, re)
+	return re, nil
+}
@@ -0,0 +1,71 @@
+package dialog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	orig := timeNow
+	timeNow = func() time.Time { return time.Date(2025, 3, 10, 14, 30, 0, 0, time.UTC) }
+	defer func() { timeNow = orig }()
+
+	session := NewSession("s1", "funcs", "start")
+	session.SetVariable("name", "Ada")
+	session.SetVariable("count", "42")
+	session.SetVariable("empty", "")
+	session.SetLastEvent("My number is 555-12 34, thanks!")
+	session.SetLastResult(map[string]any{"tier": "gold", "items": []any{"a", "b"}})
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{tmpl: `{{ lower .Variables.name }} {{ upper .Variables.name }}`, want: "ada ADA"},
+		{tmpl: `{{ trim "  hi  " }}`, want: "hi"},
+		{tmpl: `{{ .Event | lower | contains "number" }}`, want: "true"},
+		{tmpl: `{{ hasPrefix "My" .Event }} {{ hasSuffix "?" .Event }}`, want: "true false"},
+		{tmpl: `{{ replace "-" "" "555-12" }}`, want: "55512"},
+		{tmpl: `{{ digitsOnly .Event }}`, want: "5551234"},
+		{tmpl: `{{ regexMatch "^[0-9]{7}$" (digitsOnly .Event) }}`, want: "true"},
+		{tmpl: `{{ gt (atoi .Variables.count) 40 }} {{ atoi "abc" }}`, want: "true 0"},
+		{tmpl: `{{ default "friend" .Variables.empty }} {{ default "friend" .Variables.name }}`, want: "friend Ada"},
+		{tmpl: `{{ default "none" .Variables.missing }}`, want: "none"},
+		{tmpl: `{{ json .Result }}`, want: `{"items":["a","b"],"tier":"gold"}`},
+		{tmpl: `{{ len "héllo" }} {{ len .Variables }} {{ len .Variables.missing }}`, want: "5 3 0"},
+		{tmpl: `{{ formatTime "15:04" now }}`, want: "14:30"},
+		{tmpl: `{{ (inTimezone "America/New_York" now).Hour }}`, want: "10"},
+		{tmpl: `{{ (now).Before (now) }}`, want: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			got, err := RenderParam(tt.tmpl, session)
+			if err != nil {
+				t.Fatalf("RenderParam: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncsErrors(t *testing.T) {
+	session := NewSession("s1", "funcs", "start")
+	for _, tmpl := range []string{
+		`{{ regexMatch "(" "x" }}`,
+		`{{ inTimezone "Not/AZone" now }}`,
+		`{{ env "HOME" }}`,
+		`{{ len 3 }}`,
+		// .Session is read-only.
+		`{{ .Session.SetVariable "tier" "gold" }}`,
+	} {
+		if _, err := RenderParam(tmpl, session); err == nil {
+			t.Errorf("%s: expected error", tmpl)
+		}
+	}
+	if v := session.GetVariable("tier"); v != "" {
+		t.Errorf("template set tier = %q", v)
+	}
+}
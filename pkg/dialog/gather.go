@@ -0,0 +1,117 @@
+package dialog
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StateTypeGather marks a prompt-and-collect state driven by its Gather block.
+const StateTypeGather = "gather"
+
+// TriggerMaxAttempts is the history trigger recorded when a gather state
+// gives up after max_attempts.
+const TriggerMaxAttempts = "max_attempts"
+
+// Defaults for gather states.
+const (
+	DefaultGatherMaxAttempts = 3
+	DefaultGatherTimeout     = 5 * time.Second
+)
+
+// Gather configures a gather state: prompt the caller, wait for input,
+// validate it and reprompt until max_attempts is reached.
+type Gather struct {
+	Prompt   string `yaml:"prompt"    json:"prompt"`
+	Reprompt string `yaml:"reprompt"  json:"reprompt,omitempty"` // after no input
+	NoMatch  string `yaml:"no_match"  json:"no_match,omitempty"` // after failed validation
+	// Input restricts accepted input to "speech" or "dtmf"; empty accepts both.
+	Input       string `yaml:"input"        json:"input,omitempty"`
+	Validate    string `yaml:"validate"     json:"validate,omitempty"`
+	Variable    string `yaml:"variable"     json:"variable,omitempty"`
+	MaxAttempts int    `yaml:"max_attempts" json:"max_attempts,omitempty"`
+	// Digits, when set, collects multi-digit DTMF input with collect_digits params.
+	Digits          map[string]string `yaml:"digits"            json:"digits,omitempty"`
+	Next            string            `yaml:"next"              json:"next"`
+	MaxAttemptsNext string            `yaml:"max_attempts_next" json:"max_attempts_next"`
+}
+
+func (g *Gather) maxAttempts() int {
+	if g.MaxAttempts > 0 {
+		return g.MaxAttempts
+	}
+	return DefaultGatherMaxAttempts
+}
+
+// accepts reports whether ev is input the gather state should consume.
+func (g *Gather) accepts(ev Event) bool {
+	switch ev.Type {
+	case EventSpeech:
+		return g.Input == "" || g.Input == EventSpeech
+	case EventDTMF, EventDigitsCollected:
+		return g.Input == "" || g.Input == EventDTMF
+	case EventTimeout:
+		return true
+	}
+	return false
+}
+
+// enter records a visit to state, runs its on_enter actions and, for gather
+// states, plays the initial prompt.
+func (e *Engine) enter(ctx context.Context, session *Session, name string, state State, res *StepResult) error {
+	session.RecordVisit(name)
+	if err := e.executeActions(ctx, session, state.OnEnter, res); err != nil {
+		return err
+	}
+	if state.Type == StateTypeGather && session.GetCurrentState() == name {
+		return e.prompt(ctx, session, state.Gather, state.Gather.Prompt, res)
+	}
+	return nil
+}
+
+// prompt plays text and, when the gather collects digits, restarts collection.
+func (e *Engine) prompt(ctx context.Context, session *Session, g *Gather, text string, res *StepResult) error {
+	if text == "" {
+		text = g.Prompt
+	}
+	if err := e.executeAction(ctx, session, Action{Type: "play_tts", Params: map[string]string{"text": text}}, res); err != nil {
+		return err
+	}
+	if len(g.Digits) > 0 {
+		return e.executeAction(ctx, session, Action{Type: "collect_digits", Params: g.Digits}, res)
+	}
+	return nil
+}
+
+// gather handles input (or its absence) in a gather state that no explicit
+// transition matched. Valid input is stored and moves the dialog to next;
+// missing or invalid input is reprompted until max_attempts is reached.
+func (e *Engine) gather(ctx context.Context, session *Session, sm *StateMachine, state State, ev Event, res *StepResult) error {
+	g := state.Gather
+	input := eventText(ev.Data)
+
+	if input != "" {
+		// Validation sees the input as a string, whatever the event type.
+		session.SetLastEvent(input)
+		valid, err := EvalCondition(g.Validate, session)
+		if err != nil {
+			return fmt.Errorf("eval gather validate %q: %w", g.Validate, err)
+		}
+		if valid && g.Variable != "" {
+			// Input the variable's type or pattern rejects is invalid too.
+			valid = assign(session, g.Variable, input) == nil
+		}
+		if valid {
+			return e.transition(ctx, session, sm, g.Next, input, res)
+		}
+	}
+
+	current := session.GetCurrentState()
+	if session.IncrementAttempts(current) >= g.maxAttempts() {
+		return e.transition(ctx, session, sm, g.MaxAttemptsNext, TriggerMaxAttempts, res)
+	}
+	if input == "" {
+		return e.prompt(ctx, session, g, g.Reprompt, res)
+	}
+	return e.prompt(ctx, session, g, g.NoMatch, res)
+}
@@ -0,0 +1,180 @@
+package dialog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func gatherDialog(g Gather) *Dialog {
+	g.Next = "confirm"
+	g.MaxAttemptsNext = "operator"
+	return &Dialog{
+		Name:         "gather-test",
+		InitialState: "ask_zip",
+		States: map[string]State{
+			"ask_zip": {
+				Type:   StateTypeGather,
+				Gather: &g,
+				Transitions: []Transition{
+					{Event: "speech", Condition: `{{ eq .Event "agent" }}`, Target: "operator"},
+				},
+			},
+			"confirm":  {Terminal: true},
+			"operator": {Terminal: true},
+		},
+	}
+}
+
+func startGather(t *testing.T, g Gather) (*Engine, *Session, *StepResult) {
+	t.Helper()
+	d := gatherDialog(g)
+	sm := NewStateMachine(d)
+	if err := sm.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	engine := NewEngine(map[string]*StateMachine{d.Name: sm}, nil, nil)
+	session := NewSession("s1", d.Name, d.InitialState)
+	res, err := engine.Start(t.Context(), session)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return engine, session, res
+}
+
+// spoken returns the text of every play_tts directive in res.
+func spoken(res *StepResult) []string {
+	var out []string
+	for _, d := range res.Directives {
+		if d.Type == "play_tts" {
+			out = append(out, d.Params["text"])
+		}
+	}
+	return out
+}
+
+var zipGather = Gather{
+	Prompt:   "What is your zip code?",
+	Reprompt: "Sorry, I didn't hear you. What is your zip code?",
+	NoMatch:  "Attempt {{ index .Attempts \"ask_zip\" }}: {{ .Event }} is not a zip code.",
+	Validate: `{{ eq (len .Event) 5 }}`,
+	Variable: "zip",
+}
+
+func TestGatherValidInput(t *testing.T) {
+	engine, session, res := startGather(t, zipGather)
+	if got := spoken(res); len(got) != 1 || got[0] != zipGather.Prompt {
+		t.Errorf("start prompts = %q", got)
+	}
+
+	res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "94107"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "confirm" {
+		t.Errorf("state = %q, want confirm", res.CurrentState)
+	}
+	if got := session.GetVariable("zip"); got != "94107" {
+		t.Errorf("zip = %q, want 94107", got)
+	}
+}
+
+func TestGatherRetries(t *testing.T) {
+	engine, session, _ := startGather(t, zipGather)
+
+	res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "123"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "ask_zip" {
+		t.Fatalf("state = %q, want ask_zip", res.CurrentState)
+	}
+	if got := spoken(res); len(got) != 1 || got[0] != "Attempt 1: 123 is not a zip code." {
+		t.Errorf("no-match prompts = %q", got)
+	}
+
+	res, err = engine.HandleEvent(t.Context(), session, Event{Type: EventTimeout})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if got := spoken(res); len(got) != 1 || got[0] != zipGather.Reprompt {
+		t.Errorf("reprompts = %q", got)
+	}
+
+	res, err = engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "nope"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "operator" {
+		t.Errorf("state = %q, want operator after max attempts", res.CurrentState)
+	}
+	history := session.CopyHistory()
+	if len(history) != 1 || history[0].Trigger != TriggerMaxAttempts {
+		t.Errorf("history = %+v, want one %q transition", history, TriggerMaxAttempts)
+	}
+	if got := session.CopyVisits()["ask_zip"]; got != 1 {
+		t.Errorf("ask_zip visits = %d, want 1", got)
+	}
+}
+
+func TestGatherExplicitTransitionWins(t *testing.T) {
+	engine, session, _ := startGather(t, zipGather)
+
+	res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "agent"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "operator" {
+		t.Errorf("state = %q, want operator", res.CurrentState)
+	}
+	if got := session.CopyAttempts()["ask_zip"]; got != 0 {
+		t.Errorf("attempts = %d, want 0", got)
+	}
+}
+
+func TestGatherDigits(t *testing.T) {
+	g := Gather{
+		Prompt:   "Enter your PIN followed by pound.",
+		Input:    EventDTMF,
+		Digits:   map[string]string{"terminator": "#", "first_digit_timeout": "50ms"},
+		Validate: `{{ eq (len .Event) 4 }}`,
+		Variable: "pin",
+	}
+
+	t.Run("collected", func(t *testing.T) {
+		engine, session, _ := startGather(t, g)
+		res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventDTMF, Data: "4321#"})
+		if err != nil {
+			t.Fatalf("HandleEvent: %v", err)
+		}
+		if res.CurrentState != "confirm" || session.GetVariable("pin") != "4321" {
+			t.Errorf("state = %q pin = %q", res.CurrentState, session.GetVariable("pin"))
+		}
+	})
+
+	t.Run("speech ignored", func(t *testing.T) {
+		engine, session, _ := startGather(t, g)
+		res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "4321"})
+		if err != nil {
+			t.Fatalf("HandleEvent: %v", err)
+		}
+		if res.CurrentState != "ask_zip" || len(res.Directives) != 0 {
+			t.Errorf("state = %q directives = %+v", res.CurrentState, res.Directives)
+		}
+	})
+
+	t.Run("no input", func(t *testing.T) {
+		engine, session, _ := startGather(t, g)
+		ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+		defer cancel()
+		if err := engine.Run(ctx, session, make(chan Event), nil); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if got := session.GetCurrentState(); got != "operator" {
+			t.Errorf("state = %q, want operator", got)
+		}
+		if got := session.CopyAttempts()["ask_zip"]; got != DefaultGatherMaxAttempts {
+			t.Errorf("attempts = %d, want %d", got, DefaultGatherMaxAttempts)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package dialog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// graphEdge is a labeled edge of a dialog's state graph.
+type graphEdge struct {
+	From, To, Label string
+}
+
+// graphAny is the pseudo node global transitions leave from. A global
+// transition to TargetCurrent is drawn as a loop on it.
+const graphAny = "(any state)"
+
+// graphEdges returns the edges of d's state graph in state order:
+// transitions labeled by event and their condition, match or intent,
+// state timeouts and gather exits, followed by the global transitions.
+func graphEdges(d *Dialog) []graphEdge {
+	var edges []graphEdge
+	for _, name := range sortedStates(d) {
+		state := d.States[name]
+		for _, t := range state.Transitions {
+			to := t.Target
+			if to == TargetCurrent {
+				to = name
+			}
+			edges = append(edges, graphEdge{From: name, To: to, Label: transitionLabel(t)})
+		}
+		if state.TimeoutNext != "" {
+			label := "timeout"
+			if state.Timeout != "" {
+				label += " " + state.Timeout
+			}
+			edges = append(edges, graphEdge{From: name, To: state.TimeoutNext, Label: label})
+		}
+		if state.Type == StateTypeGather && state.Gather != nil {
+			if state.Gather.Next != "" {
+				edges = append(edges, graphEdge{From: name, To: state.Gather.Next, Label: "gathered"})
+			}
+			if state.Gather.MaxAttemptsNext != "" {
+				edges = append(edges, graphEdge{From: name, To: state.Gather.MaxAttemptsNext, Label: TriggerMaxAttempts})
+			}
+		}
+	}
+	for _, t := range d.GlobalTransitions {
+		to := t.Target
+		if to == TargetCurrent {
+			to = graphAny
+		}
+		edges = append(edges, graphEdge{From: graphAny, To: to, Label: transitionLabel(t)})
+	}
+	return edges
+}
+
+func transitionLabel(t Transition) string {
+	parts := []string{t.Event}
+	if t.Intent != "" {
+		parts = append(parts, "intent="+t.Intent)
+	}
+	if m := t.Match; m != nil {
+		if len(m.Keywords) > 0 {
+			parts = append(parts, "keywords="+strings.Join(m.Keywords, ","))
+		}
+		if m.Regex != "" {
+			parts = append(parts, "regex="+m.Regex)
+		}
+	}
+	if c := strings.TrimSpace(t.Condition); c != "" {
+		parts = append(parts, "["+c+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// WriteDOT writes d's state graph in Graphviz DOT. The initial state is
+// entered from a point node, terminal states are drawn with a double border
+// and global transitions leave from a plain "(any state)" node.
+func WriteDOT(w io.Writer, d *Dialog) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(d.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	b.WriteString("  __start [shape=point];\n")
+	for _, name := range sortedStates(d) {
+		state := d.States[name]
+		var attrs []string
+		if state.Type != "" {
+			attrs = append(attrs, "label="+dotQuote(name+"\n("+state.Type+")"))
+		}
+		if state.Terminal {
+			attrs = append(attrs, "peripheries=2")
+		}
+		fmt.Fprintf(&b, "  %s", dotQuote(name))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	if len(d.GlobalTransitions) > 0 {
+		fmt.Fprintf(&b, "  %s [shape=plaintext];\n", dotQuote(graphAny))
+	}
+	if d.InitialState != "" {
+		fmt.Fprintf(&b, "  __start -> %s;\n", dotQuote(d.InitialState))
+	}
+	for _, e := range graphEdges(d) {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Label))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// WriteMermaid writes d's state graph as a Mermaid state diagram. States are
+// given generated ids so that any state name can be used.
+func WriteMermaid(w io.Writer, d *Dialog) error {
+	names := sortedStates(d)
+	ids := make(map[string]string, len(names))
+	id := func(name string) string {
+		if v, ok := ids[name]; ok {
+			return v
+		}
+		// Targets of unknown states still get a node.
+		v := fmt.Sprintf("s%d", len(ids))
+		ids[name] = v
+		return v
+	}
+
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	for _, name := range names {
+		label := name
+		if t := d.States[name].Type; t != "" {
+			label += " (" + t + ")"
+		}
+		fmt.Fprintf(&b, "  state \"%s\" as %s\n", mermaidEscape(label), id(name))
+	}
+	if len(d.GlobalTransitions) > 0 {
+		fmt.Fprintf(&b, "  state \"%s\" as %s\n", mermaidEscape(graphAny), id(graphAny))
+	}
+	if d.InitialState != "" {
+		fmt.Fprintf(&b, "  [*] --> %s\n", id(d.InitialState))
+	}
+	for _, e := range graphEdges(d) {
+		fmt.Fprintf(&b, "  %s --> %s : %s\n", id(e.From), id(e.To), mermaidEscape(e.Label))
+	}
+	for _, name := range names {
+		if d.States[name].Terminal {
+			fmt.Fprintf(&b, "  %s --> [*]\n", id(name))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidEscape replaces characters that end or break a Mermaid label with
+// entity codes.
+func mermaidEscape(s string) string {
+	r := strings.NewReplacer("#", "#35;", `"`, "#quot;", ":", "#58;", ";", "#59;", "\n", " ")
+	return r.Replace(s)
+}
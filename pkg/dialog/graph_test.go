@@ -0,0 +1,122 @@
+package dialog
+
+import (
+	"strings"
+	"testing"
+)
+
+const graphYAML = `name: graph-test
+initial_state: menu
+states:
+  menu:
+    transitions:
+      - event: speech
+        intent: sales
+        target: sales
+      - event: dtmf
+        condition: '{{ eq (printf "%c" .Event) "1" }}'
+        target: sales
+    timeout: "10s"
+    timeout_next: ask
+  ask:
+    type: gather
+    gather:
+      prompt: "Your account number?"
+      next: sales
+      max_attempts_next: done
+  sales:
+    transitions:
+      - event: speech
+        match:
+          keywords: [bye, done]
+        target: done
+  done:
+    terminal: true
+`
+
+func TestWriteDOT(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDOT(&b, parseDialog(t, graphYAML)); err != nil {
+		t.Fatalf("WriteDOT: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`digraph "graph-test" {`,
+		`__start -> "menu";`,
+		`"ask" [label="ask\n(gather)"];`,
+		`"done" [peripheries=2];`,
+		`"menu" -> "sales" [label="speech intent=sales"];`,
+		`"menu" -> "sales" [label="dtmf [{{ eq (printf \"%c\" .Event) \"1\" }}]"];`,
+		`"menu" -> "ask" [label="timeout 10s"];`,
+		`"ask" -> "sales" [label="gathered"];`,
+		`"ask" -> "done" [label="max_attempts"];`,
+		`"sales" -> "done" [label="speech keywords=bye,done"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %s\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMermaid(&b, parseDialog(t, graphYAML)); err != nil {
+		t.Fatalf("WriteMermaid: %v", err)
+	}
+	out := b.String()
+	// States are numbered in name order: ask, done, menu, sales.
+	for _, want := range []string{
+		"stateDiagram-v2\n",
+		`state "ask (gather)" as s0`,
+		"[*] --> s2\n",
+		"s2 --> s3 : speech intent=sales\n",
+		"s2 --> s3 : dtmf [{{ eq (printf #quot;%c#quot; .Event) #quot;1#quot; }}]\n",
+		"s2 --> s0 : timeout 10s\n",
+		"s1 --> [*]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Mermaid output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestGraphGlobalTransitions(t *testing.T) {
+	d := parseDialog(t, `name: global
+initial_state: menu
+global_transitions:
+  - event: dtmf
+    target: operator
+  - event: dtmf
+    target: $current
+states:
+  menu:
+    transitions:
+      - event: speech
+        target: $current
+  operator:
+    terminal: true
+`)
+	var b strings.Builder
+	if err := WriteDOT(&b, d); err != nil {
+		t.Fatalf("WriteDOT: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`"(any state)" [shape=plaintext];`,
+		`"(any state)" -> "operator" [label="dtmf"];`,
+		`"(any state)" -> "(any state)" [label="dtmf"];`,
+		`"menu" -> "menu" [label="speech"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %s\n%s", want, out)
+		}
+	}
+
+	b.Reset()
+	if err := WriteMermaid(&b, d); err != nil {
+		t.Fatalf("WriteMermaid: %v", err)
+	}
+	if !strings.Contains(b.String(), `state "(any state)" as s2`) {
+		t.Errorf("Mermaid output has no (any state) node\n%s", b.String())
+	}
+}
@@ -0,0 +1,372 @@
+package dialog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/events"
+	"github.com/voicetyped/voicetyped/pkg/hooks"
+)
+
+// TriggerHook is the history trigger recorded for transitions forced by a
+// hook response's next_state.
+const TriggerHook = "hook"
+
+// hookActionTypes are the action types a hook response may inject.
+var hookActionTypes = map[string]bool{
+	"play_tts":     true,
+	"play_audio":   true,
+	"set_variable": true,
+	"hangup":       true,
+}
+
+// Hook error handling modes, set with a call_hook action's on_error param.
+// Without one a failure raises hook_error.
+const (
+	// HookOnErrorContinue stores the failure as the last result and carries
+	// on without raising an event.
+	HookOnErrorContinue = "continue"
+	// HookOnErrorFail fails the step, handing the error to the dialog's
+	// on_error actions.
+	HookOnErrorFail = "fail"
+	// HookOnErrorTransition, followed by a state name, moves to that state.
+	HookOnErrorTransition = "transition:"
+)
+
+// hookCall is a call_hook action with its params parsed.
+type hookCall struct {
+	cfg hooks.HookConfig
+	// name is the hook definition called, if any.
+	name string
+	// onError is empty, HookOnErrorContinue or HookOnErrorFail; errorTarget
+	// is the state of a transition: mode.
+	onError     string
+	errorTarget string
+	// responseMap maps variables to the response fields they are set from.
+	// When set the response is not read as a hook protocol response.
+	responseMap map[string]*jsonPath
+}
+
+// hookDefinedParams are the params a call_hook naming a hook definition
+// takes from it and may not set itself.
+var hookDefinedParams = []string{"url", "auth_type", "auth_secret"}
+
+// newHookCall parses rendered call_hook params on top of def, the hook
+// definition the hook param names, if any. Nested params, such as the
+// headers and response_map mappings, arrive flattened to dotted keys.
+func newHookCall(params map[string]string, def hooks.HookConfig) (*hookCall, error) {
+	c := &hookCall{cfg: def, name: params["hook"]}
+	if name := c.name; name != "" {
+		for _, p := range hookDefinedParams {
+			if params[p] != "" {
+				return nil, fmt.Errorf("call_hook: %s is set by hook %q and may not be a param", p, name)
+			}
+		}
+	} else {
+		c.cfg.URL = params["url"]
+		c.cfg.AuthType = params["auth_type"]
+		c.cfg.AuthSecret = params["auth_secret"]
+	}
+	if v := params["method"]; v != "" {
+		c.cfg.Method = strings.ToUpper(v)
+	}
+	for key, dst := range map[string]*time.Duration{
+		"timeout":       &c.cfg.Timeout,
+		"retry_backoff": &c.cfg.RetryBackoff,
+	} {
+		v := params[key]
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("call_hook: invalid %s %q", key, v)
+		}
+		*dst = d
+	}
+	if v := params["retries"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("call_hook: invalid retries %q", v)
+		}
+		c.cfg.Retries = n
+	}
+	if err := c.cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("call_hook: %w", err)
+	}
+
+	switch mode := params["on_error"]; {
+	case mode == "", mode == HookOnErrorContinue, mode == HookOnErrorFail:
+		c.onError = mode
+	case strings.HasPrefix(mode, HookOnErrorTransition) && len(mode) > len(HookOnErrorTransition):
+		c.errorTarget = strings.TrimPrefix(mode, HookOnErrorTransition)
+	default:
+		return nil, fmt.Errorf("call_hook: on_error must be continue, fail or transition:<state>, got %q", mode)
+	}
+
+	// Header params add to a copy of the definition's headers.
+	headers := maps.Clone(c.cfg.Headers)
+	for k, v := range params {
+		switch {
+		case strings.HasPrefix(k, "headers."):
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[strings.TrimPrefix(k, "headers.")] = v
+		case strings.HasPrefix(k, "response_map."):
+			path, err := compileJSONPath(v)
+			if err != nil {
+				return nil, fmt.Errorf("call_hook: response_map %q: %w", strings.TrimPrefix(k, "response_map."), err)
+			}
+			if c.responseMap == nil {
+				c.responseMap = make(map[string]*jsonPath)
+			}
+			c.responseMap[strings.TrimPrefix(k, "response_map.")] = path
+		}
+	}
+	c.cfg.Headers = headers
+	c.cfg.RawResponse = c.responseMap != nil
+	return c, nil
+}
+
+// String names the call where its URL could leak secrets, in transcripts and
+// errors: a definition by its name, an inline URL without its credentials
+// and query.
+func (c *hookCall) String() string {
+	if c.name != "" {
+		return fmt.Sprintf("hook %q", c.name)
+	}
+	return "hook " + hooks.Endpoint(c.cfg.URL)
+}
+
+// hookDefinition returns the hook definition named by a call_hook action's
+// hook param: the dialog's own, else the engine's registry.
+func (e *Engine) hookDefinition(session *Session, name string) (hooks.HookConfig, error) {
+	if name == "" {
+		return hooks.HookConfig{}, nil
+	}
+	sm, _, err := e.resolve(session)
+	if err != nil {
+		return hooks.HookConfig{}, err
+	}
+	if def, ok := sm.dialog.Hooks[name]; ok {
+		return def, nil
+	}
+	if def, ok := e.hookRegistry[name]; ok {
+		return def, nil
+	}
+	return hooks.HookConfig{}, fmt.Errorf("call_hook: hook %q not defined", name)
+}
+
+// callHook executes a call_hook action. Unless its on_error param says
+// otherwise, failures are not fatal to the dialog: they are stored as the
+// session's last result and raised as hook_error.
+//
+// A hook protocol response has its variables, data and actions applied, then
+// either forces a transition to next_state or raises hook_result. With a
+// response_map the response is any JSON document instead: the mapped fields
+// are set as variables, an object response is stored as the last result and
+// hook_result is raised.
+func (e *Engine) callHook(ctx context.Context, session *Session, action Action, res *StepResult) error {
+	rendered, err := renderAction(action, session)
+	if err != nil {
+		return err
+	}
+	def, err := e.hookDefinition(session, rendered.Params["hook"])
+	if err != nil {
+		return err
+	}
+	call, err := newHookCall(rendered.Params, def)
+	if err != nil {
+		return err
+	}
+	if e.hooks == nil {
+		return e.hookFailed(session, call, "hook executor not configured", res)
+	}
+	req := hooks.HookRequest{
+		SessionID:  session.ID,
+		State:      session.GetCurrentState(),
+		Event:      fmt.Sprintf("%v", session.GetLastEvent()),
+		Variables:  session.CopyVariables(),
+		Transcript: hookTranscript(session),
+	}
+	if digit, ok := session.GetLastEvent().(rune); ok {
+		req.Digit = string(digit)
+	}
+	resp, err := e.hooks.Execute(ctx, call.cfg, req)
+	var vars map[string]string
+	if err == nil {
+		if call.responseMap != nil {
+			vars, err = mapHookResponse(session, call.responseMap, resp.Raw)
+		} else {
+			vars, err = e.validateHookResponse(session, resp)
+		}
+	}
+	recordHookCall(session, call, err)
+	if err != nil {
+		return e.hookFailed(session, call, err.Error(), res)
+	}
+
+	for k, v := range vars {
+		session.SetVariable(k, v)
+	}
+	session.SetLastResult(resp.Data)
+	if call.responseMap != nil {
+		res.raise(Event{Type: EventHookResult})
+		return nil
+	}
+
+	for _, ha := range resp.Actions {
+		e.applyHookAction(ctx, session, ha, res)
+	}
+
+	if resp.NextState != "" {
+		res.raise(Event{Type: TriggerHook, target: resp.NextState})
+	} else {
+		res.raise(Event{Type: EventHookResult})
+	}
+	return nil
+}
+
+// hookFailed stores a failed call as the session's last result and handles
+// it as the call's on_error mode says.
+func (e *Engine) hookFailed(session *Session, call *hookCall, msg string, res *StepResult) error {
+	session.SetLastResult(map[string]any{"error": msg})
+	switch {
+	case call.onError == HookOnErrorContinue:
+	case call.onError == HookOnErrorFail:
+		return fmt.Errorf("%s: %s", call, msg)
+	case call.errorTarget != "":
+		res.raise(Event{Type: EventHookError, target: call.errorTarget})
+	default:
+		res.raise(Event{Type: EventHookError})
+	}
+	return nil
+}
+
+// mapHookResponse returns the stored form of the variables a response_map
+// sets from a response body. A field missing from the response or rejected
+// by the variable's declaration fails the call, and nothing is applied.
+func mapHookResponse(session *Session, responseMap map[string]*jsonPath, body []byte) (map[string]string, error) {
+	var doc any
+	if len(body) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("unmarshal hook response: %w", err)
+		}
+	}
+	vars := make(map[string]string, len(responseMap))
+	for name, path := range responseMap {
+		v, ok := path.lookup(doc)
+		if !ok {
+			return nil, fmt.Errorf("response_map %q: %s not found in hook response", name, path)
+		}
+		s, err := encodeVariable(session, name, v)
+		if err != nil {
+			return nil, fmt.Errorf("response_map: %w", err)
+		}
+		vars[name] = s
+	}
+	return vars, nil
+}
+
+// validateHookResponse rejects responses naming an unknown next_state, an
+// action type hooks are not allowed to inject, or variables the dialog's
+// declarations reject. It returns the stored form of the response variables.
+// Nothing from an invalid response is applied to the session.
+func (e *Engine) validateHookResponse(session *Session, resp *hooks.HookResponse) (map[string]string, error) {
+	if resp.NextState != "" {
+		sm, _, err := e.resolve(session)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := sm.GetState(resp.NextState); !ok {
+			return nil, fmt.Errorf("hook next_state %q not found in dialog %q", resp.NextState, session.DialogName)
+		}
+	}
+	vars := make(map[string]string, len(resp.Variables))
+	for k, v := range resp.Variables {
+		s, err := encodeVariable(session, k, v)
+		if err != nil {
+			return nil, fmt.Errorf("hook variables: %w", err)
+		}
+		vars[k] = s
+	}
+	for i, ha := range resp.Actions {
+		if !hookActionTypes[ha.Type] {
+			return nil, fmt.Errorf("hook action %d: type %q not allowed", i, ha.Type)
+		}
+		if ha.Type != "set_variable" {
+			continue
+		}
+		for k, v := range ha.Params {
+			if _, err := encodeVariable(session, k, v); err != nil {
+				return nil, fmt.Errorf("hook action %d: %w", i, err)
+			}
+		}
+	}
+	return vars, nil
+}
+
+// applyHookAction carries out an action injected by a hook response. Hook
+// params are used verbatim and never rendered as templates.
+func (e *Engine) applyHookAction(ctx context.Context, session *Session, ha hooks.HookAction, res *StepResult) {
+	action := Action{Type: ha.Type, Params: ha.Params}
+	if action.Type == "set_variable" {
+		for k, v := range action.Params {
+			// validateHookResponse has checked the params.
+			_ = assign(session, k, v)
+		}
+	} else {
+		recordPrompt(session, action)
+		res.Directives = append(res.Directives, withBargeIn(session, action))
+	}
+
+	if e.publisher != nil {
+		_ = e.publisher.Emit(ctx, events.ActionExecuted, session.ID, &events.ActionExecutedData{
+			ActionType: action.Type,
+			Params:     redactParams(action),
+		})
+	}
+}
+
+// RedactedParam replaces secret-bearing params in emitted events.
+const RedactedParam = "[redacted]"
+
+// secretHeaderWords mark header names whose values are treated as secrets.
+var secretHeaderWords = []string{"auth", "token", "secret", "key", "password", "cookie"}
+
+// redactParams returns action's params as emitted in events: call_hook
+// secrets, headers that look like credentials and passwords in URLs are
+// replaced. Other actions' params are returned as they are.
+func redactParams(action Action) map[string]string {
+	if action.Type != "call_hook" {
+		return action.Params
+	}
+	out := make(map[string]string, len(action.Params))
+	for k, v := range action.Params {
+		switch {
+		case k == "auth_secret":
+			v = RedactedParam
+		case k == "url":
+			v = hooks.RedactURL(v)
+		case strings.HasPrefix(k, "headers."):
+			name := strings.ToLower(strings.TrimPrefix(k, "headers."))
+			for _, w := range secretHeaderWords {
+				if strings.Contains(name, w) {
+					v = RedactedParam
+					break
+				}
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
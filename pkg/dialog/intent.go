@@ -0,0 +1,135 @@
+package dialog
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultIntentMinScore is the score an intent must reach for an intent
+// transition to fire when the transition sets no min_score.
+const DefaultIntentMinScore = 0.3
+
+// IntentTopN is the number of ranked intents kept on the session and exposed
+// to templates as .Intents.
+const IntentTopN = 3
+
+// Intent declares an intent by example utterances.
+type Intent struct {
+	Examples []string `yaml:"examples" json:"examples"`
+}
+
+// IntentScore is an intent and how well an utterance matched it, from 0 to 1.
+type IntentScore struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// IntentClassifier scores utterances against example-trained intents using
+// TF-IDF weighted cosine similarity. An intent's score is its best-matching
+// example's similarity. It runs in-process and is safe for concurrent use.
+type IntentClassifier struct {
+	idf      map[string]float64
+	maxIDF   float64
+	examples []intentExample
+	names    []string
+}
+
+type intentExample struct {
+	intent string
+	vec    map[string]float64
+	norm   float64
+}
+
+// NewIntentClassifier trains a classifier over the intents' examples.
+func NewIntentClassifier(intents map[string]Intent) *IntentClassifier {
+	c := &IntentClassifier{idf: make(map[string]float64)}
+
+	var docs [][]string
+	var owners []string
+	for name, intent := range intents {
+		c.names = append(c.names, name)
+		for _, ex := range intent.Examples {
+			docs = append(docs, normalizeWords(ex))
+			owners = append(owners, name)
+		}
+	}
+	sort.Strings(c.names)
+
+	df := make(map[string]int)
+	for _, doc := range docs {
+		seen := make(map[string]bool)
+		for _, w := range doc {
+			if !seen[w] {
+				seen[w] = true
+				df[w]++
+			}
+		}
+	}
+	n := float64(len(docs))
+	for w, f := range df {
+		c.idf[w] = math.Log((1+n)/(1+float64(f))) + 1
+	}
+	// Words never seen in training weigh as much as the rarest known word.
+	c.maxIDF = math.Log(1+n) + 1
+
+	for i, doc := range docs {
+		vec, norm := c.vectorize(doc)
+		c.examples = append(c.examples, intentExample{intent: owners[i], vec: vec, norm: norm})
+	}
+	return c
+}
+
+func (c *IntentClassifier) vectorize(words []string) (map[string]float64, float64) {
+	vec := make(map[string]float64, len(words))
+	for _, w := range words {
+		vec[w]++
+	}
+	var sum float64
+	for w, tf := range vec {
+		idf, ok := c.idf[w]
+		if !ok {
+			idf = c.maxIDF
+		}
+		vec[w] = tf * idf
+		sum += vec[w] * vec[w]
+	}
+	return vec, math.Sqrt(sum)
+}
+
+// Classify returns every intent ranked by score, highest first. Ties are
+// ordered by intent name.
+func (c *IntentClassifier) Classify(text string) []IntentScore {
+	best := make(map[string]float64, len(c.names))
+	vec, norm := c.vectorize(normalizeWords(text))
+	if norm > 0 {
+		for _, ex := range c.examples {
+			if ex.norm == 0 {
+				continue
+			}
+			var dot float64
+			for w, v := range vec {
+				dot += v * ex.vec[w]
+			}
+			if s := dot / (norm * ex.norm); s > best[ex.intent] {
+				best[ex.intent] = s
+			}
+		}
+	}
+
+	scores := make([]IntentScore, 0, len(c.names))
+	for _, name := range c.names {
+		scores = append(scores, IntentScore{Name: name, Score: best[name]})
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores
+}
+
+// intentScore returns the score of the named intent in scores.
+func intentScore(scores []IntentScore, name string) (float64, bool) {
+	for _, s := range scores {
+		if s.Name == name {
+			return s.Score, true
+		}
+	}
+	return 0, false
+}
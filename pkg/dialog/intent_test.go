@@ -0,0 +1,159 @@
+package dialog
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntentClassifier(t *testing.T) {
+	loader := NewLoader(filepath.Join("..", "..", "dialogs"))
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	sm, ok := loader.Get("example-ivr")
+	if !ok {
+		t.Fatal("example-ivr not loaded")
+	}
+	c := NewIntentClassifier(sm.Dialog().Intents)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Hi, I'd like to buy a new plan", want: "sales"},
+		{text: "How much does the premium tier cost?", want: "sales"},
+		{text: "my internet is not working", want: "support"},
+		{text: "I need some help with my account", want: "support"},
+		{text: "what's the weather like", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			scores := c.Classify(tt.text)
+			if len(scores) != 2 {
+				t.Fatalf("got %d scores, want 2", len(scores))
+			}
+			top := scores[0]
+			if tt.want == "" {
+				if top.Score >= DefaultIntentMinScore {
+					t.Errorf("top = %+v, want below %v", top, DefaultIntentMinScore)
+				}
+				return
+			}
+			if top.Name != tt.want || top.Score < DefaultIntentMinScore {
+				t.Errorf("top = %+v, want %q above %v", top, tt.want, DefaultIntentMinScore)
+			}
+		})
+	}
+}
+
+func TestEvaluateTransitionsIntent(t *testing.T) {
+	d := &Dialog{
+		Name:         "intent-test",
+		InitialState: "menu",
+		Intents: map[string]Intent{
+			"billing":  {Examples: []string{"I have a question about my bill", "pay my invoice"}},
+			"cancel":   {Examples: []string{"cancel my subscription", "I want to quit"}},
+			"greeting": {Examples: []string{"hello there", "good morning"}},
+		},
+		States: map[string]State{
+			"menu": {
+				Transitions: []Transition{
+					{Event: "speech", Target: "fallback"},
+					{Event: "speech", Intent: "billing", Target: "billing"},
+					{Event: "speech", Intent: "cancel", MinScore: 0.9, Target: "cancel"},
+					{Event: "speech", Match: &Match{Keywords: []string{"operator"}}, Target: "operator"},
+				},
+			},
+			"fallback": {Terminal: true},
+			"billing":  {Terminal: true},
+			"cancel":   {Terminal: true},
+			"operator": {Terminal: true},
+		},
+	}
+	sm := NewStateMachine(d)
+	if err := sm.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	state, _ := sm.GetState("menu")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "question about my bill", want: "billing"},
+		{text: "please cancel it", want: "fallback"}, // below the 0.9 min_score
+		{text: "operator, my bill is wrong", want: "operator"},
+		{text: "what time is it", want: "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			session := NewSession("s1", d.Name, "menu")
+			session.SetLastEvent(tt.text)
+			target, _, err := sm.EvaluateTransitions(state, EventSpeech, session)
+			if err != nil {
+				t.Fatalf("EvaluateTransitions: %v", err)
+			}
+			if target != tt.want {
+				t.Errorf("target = %q, want %q", target, tt.want)
+			}
+		})
+	}
+
+	session := NewSession("s1", d.Name, "menu")
+	session.SetLastEvent("question about my bill")
+	if _, _, err := sm.EvaluateTransitions(state, EventSpeech, session); err != nil {
+		t.Fatalf("EvaluateTransitions: %v", err)
+	}
+	if got := len(session.CopyIntents()); got != IntentTopN {
+		t.Errorf("kept %d intents, want %d", got, IntentTopN)
+	}
+	out, err := RenderParam(`{{ .Intent }} {{ gt .IntentScore 0.5 }} {{ len .Intents }}`, session)
+	if err != nil {
+		t.Fatalf("RenderParam: %v", err)
+	}
+	if out != "billing true 3" {
+		t.Errorf("rendered %q, want %q", out, "billing true 3")
+	}
+}
+
+func TestAnalyzeIntents(t *testing.T) {
+	d := parseDialog(t, `name: intents
+initial_state: start
+intents:
+  billing:
+    examples: [pay my bill]
+  empty: {}
+global_transitions:
+  - event: speech
+    intent: operater
+    target: done
+  - event: speech
+    intent: billing
+    min_score: 2
+    target: done
+states:
+  start:
+    transitions:
+      - event: speech
+        intent: cancel
+        target: done
+  done:
+    terminal: true
+`)
+	var got []string
+	for _, diag := range Analyze(d, "", nil) {
+		if diag.Code == "invalid-intent" {
+			got = append(got, diag.Path)
+		}
+	}
+	want := []string{
+		"intents.empty",
+		"states.start.transitions[0].intent",
+		"global_transitions[0].intent",
+		"global_transitions[1].min_score",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("invalid-intent diagnostics = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package dialog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled JSONPath that selects a single value: $ followed by
+// .name, ['name'] and [index] steps. Negative indexes count from the end.
+// Wildcards, slices and filters are not supported.
+type jsonPath struct {
+	expr  string
+	steps []any // string member names and int indexes
+}
+
+func compileJSONPath(expr string) (*jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", expr)
+	}
+	p := &jsonPath{expr: expr}
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("JSONPath %q: empty member name", expr)
+			}
+			p.steps = append(p.steps, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q: unclosed [", expr)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p.steps = append(p.steps, inner[1:len(inner)-1])
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("JSONPath %q: [%s] is not an index or quoted name", expr, inner)
+			}
+			p.steps = append(p.steps, i)
+		default:
+			return nil, fmt.Errorf("JSONPath %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return p, nil
+}
+
+// lookup returns the value the path selects in v, a decoded JSON document.
+func (p *jsonPath) lookup(v any) (any, bool) {
+	for _, step := range p.steps {
+		switch key := step.(type) {
+		case string:
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[key]; !ok {
+				return nil, false
+			}
+		case int:
+			list, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			if key < 0 {
+				key += len(list)
+			}
+			if key < 0 || key >= len(list) {
+				return nil, false
+			}
+			v = list[key]
+		}
+	}
+	return v, true
+}
+
+func (p *jsonPath) String() string { return p.expr }
@@ -0,0 +1,49 @@
+package dialog
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"a": {"b c": [1, {"d": "x"}]}, "list": ["p", "q"]}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr   string
+		want   any
+		found  bool
+		badErr bool
+	}{
+		{expr: "$.a['b c'][1].d", want: "x", found: true},
+		{expr: `$["list"][-1]`, want: "q", found: true},
+		{expr: "$.list[0]", want: "p", found: true},
+		{expr: "$.a.missing", found: false},
+		{expr: "$.list[2]", found: false},
+		{expr: "$.list.x", found: false},
+		{expr: "$", want: doc, found: true},
+		{expr: "a.b", badErr: true},
+		{expr: "$.a[", badErr: true},
+		{expr: "$..a", badErr: true},
+		{expr: "$.list[*]", badErr: true},
+	}
+	for _, tt := range tests {
+		p, err := compileJSONPath(tt.expr)
+		if tt.badErr {
+			if err == nil {
+				t.Errorf("compileJSONPath(%q): expected error", tt.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("compileJSONPath(%q): %v", tt.expr, err)
+			continue
+		}
+		got, found := p.lookup(doc)
+		if found != tt.found || (found && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("lookup(%q) = %v, %v, want %v, %v", tt.expr, got, found, tt.want, tt.found)
+		}
+	}
+}
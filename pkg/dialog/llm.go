@@ -0,0 +1,263 @@
+package dialog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/voicetyped/voicetyped/pkg/llm"
+)
+
+// EventLLMError is raised when an llm_turn action fails or the model's reply
+// is rejected.
+const EventLLMError = "llm_error"
+
+// Tool declares a function the model may call during an llm_turn. A call
+// stores its arguments in the session variables of the same names, then
+// raises an event named after the tool, so transitions on that event act on
+// it.
+type Tool struct {
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Parameters are the tool's arguments. Each is stored in the session
+	// variable of the same name, and its JSON type is that variable's
+	// declared type.
+	Parameters map[string]ToolParam `yaml:"parameters" json:"parameters,omitempty"`
+}
+
+// ToolParam describes one argument of a tool.
+type ToolParam struct {
+	Description string `yaml:"description" json:"description,omitempty"`
+	Required    bool   `yaml:"required"    json:"required,omitempty"`
+}
+
+// toolNamePattern is what chat completion APIs accept as a function name.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// engineEvents are the events the engine raises itself, which tool names
+// must not shadow.
+var engineEvents = []string{
+	EventSpeech, EventDTMF, EventTimeout, EventHookResult, EventHookError,
+	EventTTSComplete, EventDigitsCollected, EventLLMError, TriggerHook,
+}
+
+// jsonTypes maps variable types to JSON schema types.
+var jsonTypes = map[string]string{
+	VarTypeString: "string",
+	VarTypeInt:    "integer",
+	VarTypeBool:   "boolean",
+	VarTypeList:   "array",
+	VarTypeMap:    "object",
+}
+
+// LLMClient completes chats for llm_turn actions. *llm.Client implements it.
+type LLMClient interface {
+	Chat(ctx context.Context, req llm.ChatRequest) (*llm.Message, error)
+}
+
+// toolCall is a validated tool call: the tool, the model's ID for the call
+// and its arguments as sent and in their stored form. result is what the
+// model is told the call did.
+type toolCall struct {
+	name   string
+	id     string
+	args   string
+	vars   map[string]string
+	result string
+}
+
+// llmTurn executes an llm_turn action: the transcript is sent to the model
+// with the rendered system_prompt and the declared tools, the reply text is
+// played as a play_tts directive and each tool call sets its variables,
+// is recorded in the transcript with its result and raises its event. Like hook failures, a failed call or rejected reply is
+// stored as the session's last result and raised as llm_error.
+func (e *Engine) llmTurn(ctx context.Context, session *Session, action Action, res *StepResult) error {
+	if e.llm == nil {
+		e.llmFailed(session, "llm client not configured", res)
+		return nil
+	}
+	prompt, err := RenderParam(action.Params["system_prompt"], session)
+	if err != nil {
+		return fmt.Errorf("render llm_turn system_prompt: %w", err)
+	}
+	model, err := RenderParam(action.Params["model"], session)
+	if err != nil {
+		return fmt.Errorf("render llm_turn model: %w", err)
+	}
+	sm, _, err := e.resolve(session)
+	if err != nil {
+		return err
+	}
+	tools, err := offeredTools(sm.dialog, action.Params["tools"])
+	if err != nil {
+		return err
+	}
+
+	req := llm.ChatRequest{
+		Model:    model,
+		Messages: chatMessages(prompt, session.CopyTranscript()),
+	}
+	for _, name := range sortedKeys(tools) {
+		req.Tools = append(req.Tools, llm.Tool{
+			Type: "function",
+			Function: llm.Function{
+				Name:        name,
+				Description: tools[name].Description,
+				Parameters:  tools[name].schema(sm.dialog),
+			},
+		})
+	}
+
+	reply, err := e.llm.Chat(ctx, req)
+	var calls []toolCall
+	if err == nil {
+		calls, err = validateToolCalls(session, tools, reply.ToolCalls)
+	}
+	if err != nil {
+		e.llmFailed(session, err.Error(), res)
+		return nil
+	}
+
+	for _, c := range calls {
+		for k, v := range c.vars {
+			session.SetVariable(k, v)
+		}
+	}
+	if text := strings.TrimSpace(reply.Content); text != "" {
+		directive := Action{Type: "play_tts", Params: map[string]string{"text": text}}
+		recordPrompt(session, directive)
+		res.Directives = append(res.Directives, withBargeIn(session, directive))
+	}
+	for _, c := range calls {
+		recordToolCall(session, c)
+	}
+	for _, c := range calls {
+		res.raise(Event{Type: c.name})
+	}
+	return nil
+}
+
+func (e *Engine) llmFailed(session *Session, msg string, res *StepResult) {
+	session.SetLastResult(map[string]any{"error": msg})
+	res.raise(Event{Type: EventLLMError})
+}
+
+// offeredTools returns the tools named in an llm_turn's comma-separated tools
+// param, or all declared tools when the param is not set.
+func offeredTools(d *Dialog, names string) (map[string]Tool, error) {
+	if strings.TrimSpace(names) == "" {
+		return d.Tools, nil
+	}
+	tools := make(map[string]Tool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		t, ok := d.Tools[name]
+		if !ok {
+			return nil, fmt.Errorf("llm_turn tool %q not declared", name)
+		}
+		tools[name] = t
+	}
+	return tools, nil
+}
+
+// schema returns the JSON schema of the tool's arguments.
+func (t Tool) schema(d *Dialog) map[string]any {
+	props := make(map[string]any, len(t.Parameters))
+	required := []string{}
+	for _, name := range sortedKeys(t.Parameters) {
+		p := t.Parameters[name]
+		prop := map[string]any{"type": jsonTypes[d.Variables[name].kind()]}
+		if p.Description != "" {
+			prop["description"] = p.Description
+		}
+		props[name] = prop
+		if p.Required {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{"type": "object", "properties": props, "required": required}
+}
+
+// chatMessages turns the transcript into chat messages after the system
+// prompt: caller utterances and DTMF keys are user messages and prompts are
+// assistant messages. The tool calls of a reply become an assistant message
+// calling them, followed by a tool message with the result of each. Hook
+// calls are left out.
+func chatMessages(prompt string, transcript []Turn) []llm.Message {
+	var msgs []llm.Message
+	if prompt != "" {
+		msgs = append(msgs, llm.Message{Role: llm.RoleSystem, Content: prompt})
+	}
+	for i := 0; i < len(transcript); i++ {
+		t := transcript[i]
+		switch t.Kind {
+		case TurnCaller:
+			msgs = append(msgs, llm.Message{Role: llm.RoleUser, Content: t.Text})
+		case TurnDTMF:
+			msgs = append(msgs, llm.Message{Role: llm.RoleUser, Content: "(pressed " + t.Text + ")"})
+		case TurnSystem:
+			msgs = append(msgs, llm.Message{Role: llm.RoleAssistant, Content: t.Text})
+		case TurnTool:
+			// The calls of one reply are recorded one after the other.
+			j := i
+			for j < len(transcript) && transcript[j].Kind == TurnTool {
+				j++
+			}
+			call := llm.Message{Role: llm.RoleAssistant}
+			var results []llm.Message
+			for _, tt := range transcript[i:j] {
+				tc := llm.ToolCall{ID: tt.ToolCallID, Type: "function"}
+				tc.Function.Name, tc.Function.Arguments = tt.Tool, tt.Arguments
+				call.ToolCalls = append(call.ToolCalls, tc)
+				results = append(results, llm.Message{Role: llm.RoleTool, ToolCallID: tt.ToolCallID, Content: tt.Text})
+			}
+			msgs = append(msgs, call)
+			msgs = append(msgs, results...)
+			i = j - 1
+		}
+	}
+	return msgs
+}
+
+// validateToolCalls rejects calls of tools that were not offered, arguments
+// the tool does not declare, missing required arguments and values the
+// variable declarations reject. Nothing from a rejected reply is applied.
+func validateToolCalls(session *Session, tools map[string]Tool, calls []llm.ToolCall) ([]toolCall, error) {
+	out := make([]toolCall, 0, len(calls))
+	for _, call := range calls {
+		name := call.Function.Name
+		tool, ok := tools[name]
+		if !ok {
+			return nil, fmt.Errorf("llm called unknown tool %q", name)
+		}
+		args := map[string]any{}
+		if s := strings.TrimSpace(call.Function.Arguments); s != "" {
+			if err := json.Unmarshal([]byte(s), &args); err != nil {
+				return nil, fmt.Errorf("tool %q arguments: %w", name, err)
+			}
+		}
+		vars := make(map[string]string, len(args))
+		for k, v := range args {
+			if _, ok := tool.Parameters[k]; !ok {
+				return nil, fmt.Errorf("tool %q: unknown argument %q", name, k)
+			}
+			s, err := encodeVariable(session, k, v)
+			if err != nil {
+				return nil, fmt.Errorf("tool %q: %w", name, err)
+			}
+			vars[k] = s
+		}
+		for k, p := range tool.Parameters {
+			if _, ok := args[k]; p.Required && !ok {
+				return nil, fmt.Errorf("tool %q: missing argument %q", name, k)
+			}
+		}
+		result, err := json.Marshal(map[string]any{"stored": vars})
+		if err != nil {
+			return nil, fmt.Errorf("tool %q: %w", name, err)
+		}
+		out = append(out, toolCall{name: name, id: call.ID, args: call.Function.Arguments, vars: vars, result: string(result)})
+	}
+	return out, nil
+}
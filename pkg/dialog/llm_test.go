@@ -0,0 +1,199 @@
+package dialog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/voicetyped/voicetyped/pkg/llm"
+)
+
+const llmDialog = `name: llm
+initial_state: chat
+variables:
+  shop: Pizza Co
+  quantity: {type: int}
+tools:
+  record_order:
+    description: Record what the caller orders
+    parameters:
+      item: {description: The pizza, required: true}
+      quantity: {}
+  transfer:
+    description: Hand the call to a person
+states:
+  chat:
+    on_enter:
+      - type: llm_turn
+        params:
+          system_prompt: "You take orders for {{ .Variables.shop }}."
+    transitions:
+      - event: speech
+        target: $current
+      - event: transfer
+        target: agent
+      - event: llm_error
+        target: failed
+  agent:
+    terminal: true
+  failed:
+    terminal: true
+`
+
+// chatStub serves chat completions, answering each request with the next
+// reply and recording the requests.
+type chatStub struct {
+	replies  []string
+	requests []llm.ChatRequest
+}
+
+func (s *chatStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req llm.ChatRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	s.requests = append(s.requests, req)
+	if len(s.replies) == 0 {
+		http.Error(w, "no reply queued", http.StatusInternalServerError)
+		return
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	w.Write([]byte(`{"choices": [{"index": 0, "message": ` + reply + `}]}`))
+}
+
+func newLLMEngine(t *testing.T, stub *chatStub) (*Engine, *Session) {
+	t.Helper()
+	ts := httptest.NewServer(stub)
+	t.Cleanup(ts.Close)
+	d := parseDialog(t, llmDialog)
+	client := llm.NewClient(ts.URL, "", llm.WithModel("test-model"))
+	engine := NewEngine(map[string]*StateMachine{d.Name: NewStateMachine(d)}, nil, nil, UseLLM(client))
+	return engine, NewSession("s1", d.Name, d.InitialState)
+}
+
+func TestEngineLLMTurn(t *testing.T) {
+	stub := &chatStub{replies: []string{
+		`{"role": "assistant", "content": "Hi, what would you like?"}`,
+		`{"role": "assistant", "content": "Two margheritas. Anything else?", "tool_calls": [
+			{"id": "1", "type": "function", "function": {"name": "record_order", "arguments": "{\"item\": \"margherita\", \"quantity\": 2}"}}]}`,
+		`{"role": "assistant", "content": null, "tool_calls": [
+			{"id": "2", "type": "function", "function": {"name": "transfer", "arguments": "{}"}}]}`,
+	}}
+	engine, session := newLLMEngine(t, stub)
+
+	res, err := engine.Start(t.Context(), session)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if len(res.Directives) != 1 || res.Directives[0].Params["text"] != "Hi, what would you like?" {
+		t.Errorf("start directives = %+v", res.Directives)
+	}
+	first := stub.requests[0]
+	if first.Model != "test-model" || len(first.Messages) != 1 || first.Messages[0].Content != "You take orders for Pizza Co." {
+		t.Errorf("first request = %+v", first)
+	}
+	if len(first.Tools) != 2 || first.Tools[0].Function.Name != "record_order" || first.Tools[1].Function.Name != "transfer" {
+		t.Fatalf("tools = %+v", first.Tools)
+	}
+	schema, _ := json.Marshal(first.Tools[0].Function.Parameters)
+	if want := `{"properties":{"item":{"description":"The pizza","type":"string"},"quantity":{"type":"integer"}},"required":["item"],"type":"object"}`; string(schema) != want {
+		t.Errorf("record_order schema = %s, want %s", schema, want)
+	}
+
+	res, err = engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "two margheritas"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	var roles []string
+	for _, m := range stub.requests[1].Messages {
+		roles = append(roles, m.Role+": "+m.Content)
+	}
+	if got, want := strings.Join(roles, " | "), "system: You take orders for Pizza Co. | assistant: Hi, what would you like? | user: two margheritas"; got != want {
+		t.Errorf("messages = %s, want %s", got, want)
+	}
+	if res.CurrentState != "chat" || len(res.Directives) != 1 || res.Directives[0].Params["text"] != "Two margheritas. Anything else?" {
+		t.Errorf("step = %+v", res)
+	}
+	if session.GetVariable("item") != "margherita" || session.GetVariable("quantity") != "2" {
+		t.Errorf("variables = %v", session.CopyVariables())
+	}
+
+	res, err = engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "let me talk to someone"})
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if res.CurrentState != "agent" || !res.Terminal || len(res.Directives) != 0 {
+		t.Errorf("step = %+v, want terminal agent without prompts", res)
+	}
+
+	// The earlier tool call is sent back as the model's call and its result.
+	roles = nil
+	for _, m := range stub.requests[2].Messages[3:] {
+		roles = append(roles, m.Role+": "+m.Content)
+	}
+	if got, want := strings.Join(roles, " | "), `assistant: Two margheritas. Anything else? | assistant:  | tool: {"stored":{"item":"margherita","quantity":"2"}} | user: let me talk to someone`; got != want {
+		t.Errorf("messages = %s, want %s", got, want)
+	}
+	call, result := stub.requests[2].Messages[4], stub.requests[2].Messages[5]
+	if len(call.ToolCalls) != 1 || call.ToolCalls[0].ID != "1" || call.ToolCalls[0].Function.Name != "record_order" ||
+		call.ToolCalls[0].Function.Arguments != `{"item": "margherita", "quantity": 2}` || result.ToolCallID != "1" {
+		t.Errorf("tool call = %+v, result = %+v", call, result)
+	}
+	transcript := session.CopyTranscript()
+	if last := transcript[len(transcript)-1]; last.Kind != TurnTool || last.Tool != "transfer" || last.ToolCallID != "2" {
+		t.Errorf("last turn = %+v, want the transfer call", last)
+	}
+	history := session.CopyHistory()
+	if last := history[len(history)-1]; last.Trigger != "transfer" {
+		t.Errorf("last transition = %+v, want trigger transfer", last)
+	}
+}
+
+func TestEngineLLMTurnErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  string
+	}{
+		{name: "http error", want: "HTTP 500"},
+		{
+			name:  "unknown tool",
+			reply: `{"role": "assistant", "tool_calls": [{"id": "1", "type": "function", "function": {"name": "refund", "arguments": "{}"}}]}`,
+			want:  `unknown tool "refund"`,
+		},
+		{
+			name:  "invalid argument",
+			reply: `{"role": "assistant", "content": "OK", "tool_calls": [{"id": "1", "type": "function", "function": {"name": "record_order", "arguments": "{\"item\": \"margherita\", \"quantity\": \"lots\"}"}}]}`,
+			want:  "want an int",
+		},
+		{
+			name:  "missing argument",
+			reply: `{"role": "assistant", "tool_calls": [{"id": "1", "type": "function", "function": {"name": "record_order", "arguments": "{}"}}]}`,
+			want:  `missing argument "item"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &chatStub{}
+			if tt.reply != "" {
+				stub.replies = []string{tt.reply}
+			}
+			engine, session := newLLMEngine(t, stub)
+
+			res, err := engine.Start(t.Context(), session)
+			if err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if res.CurrentState != "failed" || len(res.Directives) != 0 {
+				t.Errorf("step = %+v, want failed without prompts", res)
+			}
+			if msg, _ := session.GetLastResult()["error"].(string); !strings.Contains(msg, tt.want) {
+				t.Errorf("error = %q, want %q", msg, tt.want)
+			}
+			if session.GetVariable("item") != "" {
+				t.Error("rejected reply set variables")
+			}
+		})
+	}
+}
@@ -1,61 +1,230 @@
 package dialog
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
-	"gopkg.in/yaml.v3"
+	"github.com/voicetyped/voicetyped/pkg/events"
 )
 
-// Loader loads and optionally hot-reloads dialog definitions from YAML files.
+// Loader loads and optionally hot-reloads dialog definitions from YAML files,
+// and serves dialogs kept in a DefinitionStore next to them.
 type Loader struct {
-	dir string
+	dir       string
+	strict    bool
+	publisher *events.Publisher
+	debounce  time.Duration
+	store     DefinitionStore
 
-	mu      sync.RWMutex
-	dialogs map[string]*StateMachine
+	mu sync.RWMutex
+	// files holds the last good state machine loaded from each file and
+	// stored the versions loaded from the store, by record ID. active is
+	// the activated stored version of each dialog. versions indexes both
+	// by dialog name and version; dialogs holds the default version of
+	// each dialog: the active one, else the highest.
+	files    map[string]*StateMachine
+	stored   map[string]*StateMachine
+	active   map[string]string
+	versions map[string]map[string]*StateMachine
+	dialogs  map[string]*StateMachine
+}
+
+// LoaderOption configures a Loader.
+type LoaderOption func(*Loader)
+
+// StrictMode makes the loader reject dialogs with analyzer warnings, not
+// just errors.
+func StrictMode() LoaderOption {
+	return func(l *Loader) {
+		l.strict = true
+	}
+}
+
+// ReloadEvents makes the loader emit dialog.reloaded and dialog.reload_failed
+// events through pub when WatchAndReload reloads a file.
+func ReloadEvents(pub *events.Publisher) LoaderOption {
+	return func(l *Loader) {
+		l.publisher = pub
+	}
+}
+
+// ReloadDebounce sets how long WatchAndReload waits for changes to a file to
+// settle before reloading it.
+func ReloadDebounce(d time.Duration) LoaderOption {
+	return func(l *Loader) {
+		l.debounce = d
+	}
 }
 
 // NewLoader creates a new dialog loader for the given directory.
-func NewLoader(dir string) *Loader {
-	return &Loader{
-		dir:     dir,
-		dialogs: make(map[string]*StateMachine),
+func NewLoader(dir string, opts ...LoaderOption) *Loader {
+	l := &Loader{
+		dir:      dir,
+		debounce: DefaultReloadDebounce,
+		files:    make(map[string]*StateMachine),
+		versions: make(map[string]map[string]*StateMachine),
+		dialogs:  make(map[string]*StateMachine),
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
-// LoadAll loads all .yaml and .yml files from the configured directory.
+// LoadAll loads all .yaml and .yml files from the configured directory and
+// returns the default version of each dialog. Several files may define the
+// same dialog with different versions. A file that fails to load keeps its
+// last good definition, if any, and does not stop the other files loading;
+// the failures are returned together. Sessions already running keep the
+// state machine they were started with.
 func (l *Loader) LoadAll() (map[string]*StateMachine, error) {
 	entries, err := os.ReadDir(l.dir)
 	if err != nil {
 		return nil, fmt.Errorf("read dialog dir %q: %w", l.dir, err)
 	}
 
-	result := make(map[string]*StateMachine)
+	l.mu.RLock()
+	prevFiles := l.files
+	l.mu.RUnlock()
+
+	files := make(map[string]*StateMachine)
+	var errs []error
 	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		ext := filepath.Ext(entry.Name())
-		if ext != ".yaml" && ext != ".yml" {
+		if entry.IsDir() || !isDialogFile(entry.Name()) {
 			continue
 		}
 
 		path := filepath.Join(l.dir, entry.Name())
 		sm, err := l.loadFile(path)
+		if err == nil {
+			if other := conflictingFile(files, path, sm); other != "" {
+				err = conflictError(sm, other)
+			}
+		}
 		if err != nil {
-			return nil, fmt.Errorf("load %q: %w", path, err)
+			errs = append(errs, fmt.Errorf("load %q: %w", path, err))
+			if prev, ok := prevFiles[path]; ok && conflictingFile(files, path, prev) == "" {
+				files[path] = prev
+			}
+			continue
 		}
-		result[sm.Dialog().Name] = sm
+		files[path] = sm
 	}
 
 	l.mu.Lock()
-	l.dialogs = result
+	l.files = files
+	l.index()
 	l.mu.Unlock()
 
-	return result, nil
+	return l.All(), errors.Join(errs...)
+}
+
+// index rebuilds versions and dialogs from files and stored. A version
+// defined both by a file and in the store is served from the file. l.mu must
+// be held.
+func (l *Loader) index() {
+	versions := make(map[string]map[string]*StateMachine)
+	add := func(sm *StateMachine) {
+		d := sm.Dialog()
+		if versions[d.Name] == nil {
+			versions[d.Name] = make(map[string]*StateMachine)
+		}
+		if _, ok := versions[d.Name][d.Version]; ok {
+			slog.Warn("stored dialog version shadowed by a file",
+				slog.String("dialog", d.Name), slog.String("version", d.Version))
+			return
+		}
+		versions[d.Name][d.Version] = sm
+	}
+	for _, sm := range l.files {
+		add(sm)
+	}
+	for _, sm := range l.stored {
+		add(sm)
+	}
+
+	dialogs := make(map[string]*StateMachine, len(versions))
+	for name, byVersion := range versions {
+		if sm, ok := byVersion[l.active[name]]; ok && l.active[name] != "" {
+			dialogs[name] = sm
+			continue
+		}
+		dialogs[name] = byVersion[latestVersion(byVersion)]
+	}
+	l.versions = versions
+	l.dialogs = dialogs
+}
+
+// conflictingFile returns the file other than path in files that already
+// defines sm's dialog name and version, or "".
+func conflictingFile(files map[string]*StateMachine, path string, sm *StateMachine) string {
+	d := sm.Dialog()
+	for other, o := range files {
+		if other != path && o.Dialog().Name == d.Name && o.Dialog().Version == d.Version {
+			return other
+		}
+	}
+	return ""
+}
+
+func conflictError(sm *StateMachine, other string) error {
+	return fmt.Errorf("dialog %q version %q is already defined in %q", sm.Dialog().Name, sm.Dialog().Version, other)
+}
+
+// Lint analyzes every dialog file in the directory without loading any of
+// them. Besides each file's analyzer diagnostics it reports files that fail
+// to parse (invalid-yaml) and dialog versions defined by more than one file
+// (duplicate-version). The error is an *AnalysisError when LoadAll would
+// reject a file, honoring strict mode.
+func (l *Loader) Lint() (Diagnostics, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dialog dir %q: %w", l.dir, err)
+	}
+
+	var all Diagnostics
+	defined := make(map[[2]string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !isDialogFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(l.dir, entry.Name())
+		d, diags, err := AnalyzeFile(path)
+		if err != nil {
+			all = append(all, Diagnostic{
+				Severity: SeverityError,
+				Code:     "invalid-yaml",
+				File:     path,
+				Message:  err.Error(),
+			})
+			continue
+		}
+		all = append(all, diags...)
+
+		key := [2]string{d.Name, d.Version}
+		if other, ok := defined[key]; ok {
+			all = append(all, Diagnostic{
+				Severity: SeverityError,
+				Code:     "duplicate-version",
+				Dialog:   d.Name,
+				File:     path,
+				Message:  fmt.Sprintf("dialog %q version %q is already defined in %q", d.Name, d.Version, other),
+			})
+			continue
+		}
+		defined[key] = path
+	}
+	return all, all.Err(l.strict)
+}
+
+func isDialogFile(name string) bool {
+	ext := filepath.Ext(name)
+	return (ext == ".yaml" || ext == ".yml") && !isScenarioFile(name)
 }
 
 // Get returns a loaded state machine by dialog name.
@@ -66,7 +235,52 @@ func (l *Loader) Get(name string) (*StateMachine, bool) {
 	return sm, ok
 }
 
-// All returns all loaded state machines.
+// GetVersion returns a loaded state machine by dialog name and version.
+func (l *Loader) GetVersion(name, version string) (*StateMachine, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	sm, ok := l.versions[name][version]
+	return sm, ok
+}
+
+// Versions returns the loaded versions of a dialog, lowest first.
+func (l *Loader) Versions(name string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := make([]string, 0, len(l.versions[name]))
+	for v := range l.versions[name] {
+		versions = append(versions, v)
+	}
+	sortVersions(versions)
+	return versions
+}
+
+// Select picks the version a new session of a dialog runs. An empty weights
+// map selects the default version; otherwise a version is drawn at random
+// in proportion to its weight. Every weighted version must be loaded.
+func (l *Loader) Select(name string, weights map[string]uint32) (*StateMachine, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	byVersion, ok := l.versions[name]
+	if !ok {
+		return nil, fmt.Errorf("dialog %q not found", name)
+	}
+	if len(weights) == 0 {
+		return l.dialogs[name], nil
+	}
+	for v := range weights {
+		if _, ok := byVersion[v]; !ok {
+			return nil, fmt.Errorf("dialog %q version %q not found", name, v)
+		}
+	}
+	v, err := pickWeighted(weights)
+	if err != nil {
+		return nil, fmt.Errorf("dialog %q: %w", name, err)
+	}
+	return byVersion[v], nil
+}
+
+// All returns the default version of every loaded dialog.
 func (l *Loader) All() map[string]*StateMachine {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -77,61 +291,44 @@ func (l *Loader) All() map[string]*StateMachine {
 	return result
 }
 
+// AllVersions returns every loaded version of every dialog.
+func (l *Loader) AllVersions() []*StateMachine {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var result []*StateMachine
+	for _, byVersion := range l.versions {
+		for _, sm := range byVersion {
+			result = append(result, sm)
+		}
+	}
+	return result
+}
+
+// loadFile analyzes a dialog file and builds its state machine. Warnings are
+// logged, or fail the load in strict mode.
 func (l *Loader) loadFile(path string) (*StateMachine, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-
-	var d Dialog
-	if err := yaml.Unmarshal(data, &d); err != nil {
-		return nil, fmt.Errorf("parse YAML: %w", err)
+	d, diags, err := AnalyzeSource(data, path)
+	if err != nil {
+		return nil, err
 	}
-
 	if d.Name == "" {
 		d.Name = filepath.Base(path)
 	}
-
-	sm := NewStateMachine(&d)
-	if err := sm.Validate(); err != nil {
+	if err := diags.Err(l.strict); err != nil {
 		return nil, err
 	}
-
-	return sm, nil
-}
-
-// WatchAndReload starts watching the dialog directory for changes and reloads.
-// This blocks until the done channel is closed.
-func (l *Loader) WatchAndReload(done <-chan struct{}) error {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return fmt.Errorf("create watcher: %w", err)
-	}
-	defer watcher.Close()
-
-	if err := watcher.Add(l.dir); err != nil {
-		return fmt.Errorf("watch dir %q: %w", l.dir, err)
+	for _, w := range diags.Warnings() {
+		slog.Warn("dialog analysis warning", slog.String("diagnostic", w.String()))
 	}
 
-	for {
-		select {
-		case <-done:
-			return nil
-		case event, ok := <-watcher.Events:
-			if !ok {
-				return nil
-			}
-			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-				ext := filepath.Ext(event.Name)
-				if ext == ".yaml" || ext == ".yml" {
-					l.LoadAll()
-				}
-			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				return nil
-			}
-			return err
-		}
+	sm := NewStateMachine(d)
+	sm.origin = Origin{Source: SourceFile, File: path, Definition: string(data)}
+	if info, err := os.Stat(path); err == nil {
+		sm.origin.CreatedAt = info.ModTime()
 	}
+	return sm, nil
 }
@@ -1,6 +1,7 @@
 package dialog
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -106,3 +107,50 @@ func TestLoaderEmptyDir(t *testing.T) {
 		t.Errorf("loaded %d dialogs, want 0", len(dialogs))
 	}
 }
+
+func TestLoaderLint(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{
+		"a.yaml":      versionedDialog("1.0", "a"),
+		"b.yaml":      versionedDialog("1.0", "b"),
+		"broken.yaml": "name: [\n",
+		"orphan.yaml": `name: orphan
+initial_state: start
+states:
+  start:
+    terminal: true
+  unused:
+    terminal: true
+`,
+	})
+
+	diags, err := NewLoader(dir).Lint()
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) {
+		t.Fatalf("Lint error = %v, want *AnalysisError", err)
+	}
+	codes := make(map[string]string)
+	for _, d := range diags {
+		codes[filepath.Base(d.File)] = d.Code
+	}
+	want := map[string]string{
+		"b.yaml":      "duplicate-version",
+		"broken.yaml": "invalid-yaml",
+		"orphan.yaml": "unreachable-state",
+	}
+	if len(diags) != len(want) {
+		t.Errorf("diagnostics = %v", diags)
+	}
+	for file, code := range want {
+		if codes[file] != code {
+			t.Errorf("%s: code = %q, want %q", file, codes[file], code)
+		}
+	}
+	if len(analysisErr.Diagnostics) != 2 {
+		t.Errorf("failing diagnostics = %v, want the two errors", analysisErr.Diagnostics)
+	}
+
+	if _, err := NewLoader(filepath.Join(dir, "missing")).Lint(); err == nil || errors.As(err, &analysisErr) {
+		t.Errorf("Lint of missing dir = %v, want read error", err)
+	}
+}
@@ -0,0 +1,176 @@
+package dialog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// fuzzyMinWordLen is the shortest word fuzzy matching applies to; shorter
+// words ("no", "yes") must match exactly.
+const fuzzyMinWordLen = 4
+
+// regexCache caches compiled regexes for match blocks and regexMatch.
+var regexCache = newLRUCache[*regexp.Regexp](maxCachedRegexes)
+
+// Match selects a transition by the content of the event text instead of a
+// template condition. Keywords are compared case- and punctuation-insensitively
+// as whole words; a keyword may also be a multi-word phrase.
+type Match struct {
+	Keywords []string `yaml:"keywords" json:"keywords,omitempty"`
+	// Synonyms maps a keyword to alternative phrases that count as that keyword.
+	Synonyms map[string][]string `yaml:"synonyms" json:"synonyms,omitempty"`
+	// Regex is matched case-insensitively against the raw event text. Named
+	// capture groups are stored as session variables when the transition fires.
+	Regex string `yaml:"regex" json:"regex,omitempty"`
+	// Fuzzy is the maximum edit distance tolerated per keyword word.
+	Fuzzy int `yaml:"fuzzy" json:"fuzzy,omitempty"`
+}
+
+// validate checks the match block can be evaluated.
+func (m *Match) validate() error {
+	if len(m.Keywords) == 0 && m.Regex == "" {
+		return fmt.Errorf("match requires keywords or regex")
+	}
+	if m.Fuzzy < 0 {
+		return fmt.Errorf("match fuzzy must not be negative")
+	}
+	if m.Regex != "" {
+		if _, err := compileMatchRegex(m.Regex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Score reports how well text matches: 1 for a regex match or an exact
+// keyword, less for a fuzzy keyword match, and ok=false for no match.
+// Captures holds the regex's named groups.
+func (m *Match) Score(text string) (score float64, captures map[string]string, ok bool, err error) {
+	if m.Regex != "" {
+		re, err := compileMatchRegex(m.Regex)
+		if err != nil {
+			return 0, nil, false, err
+		}
+		if sub := re.FindStringSubmatch(text); sub != nil {
+			captures = make(map[string]string)
+			for i, name := range re.SubexpNames() {
+				if name != "" && i < len(sub) {
+					captures[name] = sub[i]
+				}
+			}
+			return 1, captures, true, nil
+		}
+	}
+
+	words := normalizeWords(text)
+	for _, kw := range m.Keywords {
+		for _, phrase := range append([]string{kw}, m.Synonyms[kw]...) {
+			if s, found := matchPhrase(words, normalizeWords(phrase), m.Fuzzy); found && (!ok || s > score) {
+				score, ok = s, true
+			}
+		}
+	}
+	return score, nil, ok, nil
+}
+
+func compileMatchRegex(expr string) (*regexp.Regexp, error) {
+	re, err := compileRegex("(?i)" + expr)
+	if err != nil {
+		return nil, fmt.Errorf("compile match regex %q: %w", expr, err)
+	}
+	return re, nil
+}
+
+// normalizeWords lowercases text and splits it into words, treating every
+// rune that is not a letter or digit as a separator.
+func normalizeWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchPhrase looks for phrase as a contiguous run of words, allowing up to
+// fuzzy edits per word, and scores the best occurrence by the fraction of
+// phrase characters that matched exactly.
+func matchPhrase(words, phrase []string, fuzzy int) (float64, bool) {
+	if len(phrase) == 0 || len(phrase) > len(words) {
+		return 0, false
+	}
+	total := 0
+	for _, w := range phrase {
+		total += len([]rune(w))
+	}
+
+	best, found := 0.0, false
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		dist, ok := 0, true
+		for j, w := range phrase {
+			d := wordDistance(words[i+j], w, fuzzy)
+			if d < 0 {
+				ok = false
+				break
+			}
+			dist += d
+		}
+		if !ok {
+			continue
+		}
+		if s := 1 - float64(dist)/float64(total); !found || s > best {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// wordDistance returns the edit distance between word and want if it is
+// within the fuzzy allowance, or -1 otherwise.
+func wordDistance(word, want string, fuzzy int) int {
+	if word == want {
+		return 0
+	}
+	if fuzzy == 0 || len([]rune(want)) < fuzzyMinWordLen {
+		return -1
+	}
+	if d := levenshtein(word, want); d <= fuzzy {
+		return d
+	}
+	return -1
+}
+
+// levenshtein computes the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// eventText returns event data (speech text or a DTMF rune) as a string.
+func eventText(ev any) string {
+	switch d := ev.(type) {
+	case string:
+		return d
+	case rune:
+		return string(d)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", d)
+	}
+}
@@ -0,0 +1,136 @@
+package dialog
+
+import "testing"
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		match   Match
+		text    string
+		wantOK  bool
+		wantMin float64
+		wantMax float64
+	}{
+		{
+			name:    "keyword ignores case and punctuation",
+			match:   Match{Keywords: []string{"billing"}},
+			text:    "Uh, BILLING, please!",
+			wantOK:  true,
+			wantMin: 1, wantMax: 1,
+		},
+		{
+			name:   "keyword must be a whole word",
+			match:  Match{Keywords: []string{"bill"}},
+			text:   "billing please",
+			wantOK: false,
+		},
+		{
+			name:    "phrase",
+			match:   Match{Keywords: []string{"speak to an agent"}},
+			text:    "can I speak to an agent?",
+			wantOK:  true,
+			wantMin: 1, wantMax: 1,
+		},
+		{
+			name:    "synonym",
+			match:   Match{Keywords: []string{"yes"}, Synonyms: map[string][]string{"yes": {"yeah", "of course"}}},
+			text:    "Of course.",
+			wantOK:  true,
+			wantMin: 1, wantMax: 1,
+		},
+		{
+			name:    "fuzzy",
+			match:   Match{Keywords: []string{"account"}, Fuzzy: 1},
+			text:    "my acount balance",
+			wantOK:  true,
+			wantMin: 0.8, wantMax: 0.9,
+		},
+		{
+			name:   "fuzzy beyond distance",
+			match:  Match{Keywords: []string{"account"}, Fuzzy: 1},
+			text:   "my acout balance",
+			wantOK: false,
+		},
+		{
+			name:   "fuzzy skips short words",
+			match:  Match{Keywords: []string{"no"}, Fuzzy: 2},
+			text:   "so",
+			wantOK: false,
+		},
+		{
+			name:    "regex",
+			match:   Match{Regex: `order (?P<order_id>\d+)`},
+			text:    "Order 1234 please",
+			wantOK:  true,
+			wantMin: 1, wantMax: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, _, ok, err := tt.match.Score(tt.text)
+			if err != nil {
+				t.Fatalf("Score: %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (score < tt.wantMin || score > tt.wantMax) {
+				t.Errorf("score = %v, want in [%v, %v]", score, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestEvaluateTransitionsRanksMatches(t *testing.T) {
+	d := &Dialog{
+		Name:         "match-test",
+		InitialState: "menu",
+		States: map[string]State{
+			"menu": {
+				Transitions: []Transition{
+					{Event: "speech", Target: "fallback"},
+					{Event: "speech", Match: &Match{Keywords: []string{"account balance"}}, Target: "balance"},
+					{Event: "speech", Match: &Match{Keywords: []string{"account"}, Fuzzy: 2}, Target: "accounts"},
+					{Event: "speech", Match: &Match{Regex: `order (?P<order_id>\d+)`}, Target: "orders"},
+				},
+			},
+			"fallback": {Terminal: true},
+			"accounts": {Terminal: true},
+			"balance":  {Terminal: true},
+			"orders":   {Terminal: true},
+		},
+	}
+	sm := NewStateMachine(d)
+	if err := sm.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	state, _ := sm.GetState("menu")
+
+	tests := []struct {
+		text    string
+		want    string
+		orderID string
+	}{
+		{text: "my Account Balance, please", want: "balance"},
+		{text: "my acount balance", want: "accounts"},
+		{text: "something else", want: "fallback"},
+		{text: "where is order 981?", want: "orders", orderID: "981"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			session := NewSession("s1", d.Name, "menu")
+			session.SetLastEvent(tt.text)
+			target, _, err := sm.EvaluateTransitions(state, "speech", session)
+			if err != nil {
+				t.Fatalf("EvaluateTransitions: %v", err)
+			}
+			if target != tt.want {
+				t.Errorf("target = %q, want %q", target, tt.want)
+			}
+			if got := session.GetVariable("order_id"); got != tt.orderID {
+				t.Errorf("order_id = %q, want %q", got, tt.orderID)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package dialog
+
+import (
+	"encoding/json"
+
+	"github.com/pitabwire/frame/data"
+)
+
+// SessionRecord is the persisted form of a Session in dialog_sessions.
+type SessionRecord struct {
+	data.BaseModel
+
+	DialogName    string         `gorm:"type:varchar(255);not null" json:"dialog_name"`
+	DialogVersion string         `gorm:"type:varchar(100)"          json:"dialog_version,omitempty"`
+	CurrentState  string         `gorm:"type:varchar(255);not null" json:"current_state"`
+	Variables     VariablesJSON  `gorm:"type:jsonb;default:'{}'"    json:"variables"`
+	History       HistoryJSON    `gorm:"type:jsonb;default:'[]'"    json:"history"`
+	Transcript    TranscriptJSON `gorm:"type:jsonb;default:'[]'"    json:"transcript"`
+	RoomID        string         `gorm:"type:varchar(50)"           json:"room_id,omitempty"`
+	PeerID        string         `gorm:"type:varchar(50)"           json:"peer_id,omitempty"`
+	IsActive      bool           `gorm:"default:true"               json:"is_active"`
+
+	// Visits, Attempts and Digits keep gather progress across a resume.
+	Visits   CountersJSON `gorm:"type:jsonb;default:'{}'" json:"visits"`
+	Attempts CountersJSON `gorm:"type:jsonb;default:'{}'" json:"attempts"`
+	Digits   *DigitsJSON  `gorm:"type:jsonb"              json:"digits,omitempty"`
+}
+
+func (SessionRecord) TableName() string { return "dialog_sessions" }
+
+// NewSessionRecord snapshots session into a record ready to be persisted.
+func NewSessionRecord(session *Session, roomID, peerID string) *SessionRecord {
+	rec := &SessionRecord{
+		DialogName:    session.DialogName,
+		DialogVersion: session.GetDialogVersion(),
+		CurrentState:  session.GetCurrentState(),
+		Variables:     session.CopyVariables(),
+		History:       session.CopyHistory(),
+		Transcript:    session.CopyTranscript(),
+		Visits:        session.CopyVisits(),
+		Attempts:      session.CopyAttempts(),
+		RoomID:        roomID,
+		PeerID:        peerID,
+		IsActive:      true,
+	}
+	if c, ok := session.DigitCollection(); ok {
+		rec.Digits = (*DigitsJSON)(&c)
+	}
+	rec.ID = session.ID
+	rec.CreatedAt = session.StartTime
+	return rec
+}
+
+// Session rebuilds a live session from the record. The session's start time
+// is the time the record was created, so session TTLs span restarts.
+func (r *SessionRecord) Session() *Session {
+	s := NewSession(r.ID, r.DialogName, r.CurrentState)
+	s.DialogVersion = r.DialogVersion
+	for k, v := range r.Variables {
+		s.Variables[k] = v
+	}
+	s.History = append(s.History, r.History...)
+	s.Transcript = append(s.Transcript, r.Transcript...)
+	for k, v := range r.Visits {
+		s.Visits[k] = v
+	}
+	for k, v := range r.Attempts {
+		s.Attempts[k] = v
+	}
+	if r.Digits != nil {
+		c := DigitCollection(*r.Digits)
+		s.digits = &c
+	}
+	if !r.CreatedAt.IsZero() {
+		s.StartTime = r.CreatedAt
+	}
+	return s
+}
+
+// DefinitionRecord is a dialog version managed through the API, stored in
+// dialog_definitions. Definition holds the YAML as submitted. At most one
+// version of a dialog is active; it is the default for new sessions.
+type DefinitionRecord struct {
+	data.BaseModel
+
+	Name          string `gorm:"type:varchar(255);not null" json:"name"`
+	DialogVersion string `gorm:"type:varchar(100);not null" json:"dialog_version"`
+	Definition    string `gorm:"type:text;not null"         json:"definition"`
+	IsActive      bool   `gorm:"default:false"              json:"is_active"`
+}
+
+func (DefinitionRecord) TableName() string { return "dialog_definitions" }
+
+// VariablesJSON is a custom GORM type for JSONB storage of session variables.
+type VariablesJSON map[string]string
+
+func (v VariablesJSON) Value() (interface{}, error) {
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *VariablesJSON) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, v)
+	case string:
+		return json.Unmarshal([]byte(s), v)
+	default:
+		*v = VariablesJSON{}
+		return nil
+	}
+}
+
+// HistoryJSON is a custom GORM type for JSONB storage of state history.
+type HistoryJSON []StateRecord
+
+func (h HistoryJSON) Value() (interface{}, error) {
+	if h == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(h)
+}
+
+func (h *HistoryJSON) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, h)
+	case string:
+		return json.Unmarshal([]byte(s), h)
+	default:
+		*h = HistoryJSON{}
+		return nil
+	}
+}
+
+// TranscriptJSON is a custom GORM type for JSONB storage of the transcript.
+type TranscriptJSON []Turn
+
+func (t TranscriptJSON) Value() (interface{}, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *TranscriptJSON) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, t)
+	case string:
+		return json.Unmarshal([]byte(s), t)
+	default:
+		*t = TranscriptJSON{}
+		return nil
+	}
+}
+
+// CountersJSON is a custom GORM type for JSONB storage of per-state counters.
+type CountersJSON map[string]int
+
+func (c CountersJSON) Value() (interface{}, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *CountersJSON) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, c)
+	case string:
+		return json.Unmarshal([]byte(s), c)
+	default:
+		*c = CountersJSON{}
+		return nil
+	}
+}
+
+// DigitsJSON is a custom GORM type for JSONB storage of the digit collection
+// in progress.
+type DigitsJSON DigitCollection
+
+func (d DigitsJSON) Value() (interface{}, error) {
+	return json.Marshal(d)
+}
+
+func (d *DigitsJSON) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, d)
+	case string:
+		return json.Unmarshal([]byte(s), d)
+	default:
+		*d = DigitsJSON{}
+		return nil
+	}
+}
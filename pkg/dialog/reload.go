@@ -0,0 +1,211 @@
+package dialog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/voicetyped/voicetyped/pkg/events"
+)
+
+// DefaultReloadDebounce is how long WatchAndReload waits after the last
+// change to a file before reloading it.
+const DefaultReloadDebounce = 250 * time.Millisecond
+
+// WatchAndReload watches the dialog directory and reloads each dialog file
+// that is written, created, removed or renamed, once its changes have settled
+// for the debounce interval. A file that fails to reload keeps its last good
+// definition; a removed file unloads its dialog version. It blocks until ctx
+// is cancelled.
+func (l *Loader) WatchAndReload(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(l.dir); err != nil {
+		return fmt.Errorf("watch dir %q: %w", l.dir, err)
+	}
+
+	pending := make(map[string]*time.Timer)
+	settled := make(chan string)
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		for _, t := range pending {
+			t.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !isDialogFile(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			path := event.Name
+			if t, ok := pending[path]; ok {
+				t.Reset(l.debounce)
+				continue
+			}
+			pending[path] = time.AfterFunc(l.debounce, func() {
+				select {
+				case settled <- path:
+				case <-stop:
+				}
+			})
+
+		case path := <-settled:
+			delete(pending, path)
+			l.reloadFile(ctx, path)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("dialog watcher error", slog.String("dir", l.dir), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// reloadFile reloads a single dialog file, or unloads it if it no longer
+// exists, and reports the outcome as an event.
+func (l *Loader) reloadFile(ctx context.Context, path string) {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		l.mu.Lock()
+		prev, ok := l.files[path]
+		if ok {
+			l.replaceFile(path, nil)
+		}
+		l.mu.Unlock()
+
+		if ok {
+			l.emitRemoved(ctx, path, prev)
+		}
+		return
+	}
+
+	sm, err := l.loadFile(path)
+	if err == nil {
+		err = l.install(ctx, path, sm)
+	}
+	if err != nil {
+		slog.Warn("dialog reload failed, keeping last good version",
+			slog.String("file", path), slog.String("error", err.Error()))
+		l.emit(ctx, events.DialogReloadFailed, &events.DialogReloadFailedData{
+			File:        path,
+			Error:       err.Error(),
+			Diagnostics: eventDiagnostics(err),
+		})
+		return
+	}
+
+	d := sm.Dialog()
+	slog.Info("dialog reloaded", slog.String("file", path),
+		slog.String("dialog", d.Name), slog.String("version", d.Version))
+	l.emit(ctx, events.DialogReloaded, &events.DialogReloadedData{
+		File:          path,
+		DialogName:    d.Name,
+		DialogVersion: d.Version,
+	})
+}
+
+// install makes sm the definition loaded from path. When another file defines
+// the same dialog version but no longer exists, as after a rename whose old
+// name has not been reloaded yet, that file is unloaded first.
+func (l *Loader) install(ctx context.Context, path string, sm *StateMachine) error {
+	l.mu.Lock()
+	other := conflictingFile(l.files, path, sm)
+	var moved *StateMachine
+	if other != "" {
+		if _, err := os.Stat(other); errors.Is(err, fs.ErrNotExist) {
+			moved = l.files[other]
+			l.replaceFile(other, nil)
+		}
+	}
+	if other == "" || moved != nil {
+		l.replaceFile(path, sm)
+	}
+	l.mu.Unlock()
+
+	if moved != nil {
+		l.emitRemoved(ctx, other, moved)
+		return nil
+	}
+	if other != "" {
+		return conflictError(sm, other)
+	}
+	return nil
+}
+
+// replaceFile swaps in the state machine loaded from path, or removes it when
+// sm is nil, and reindexes. files is replaced rather than modified so that
+// LoadAll can read a snapshot without holding the lock. l.mu must be held.
+func (l *Loader) replaceFile(path string, sm *StateMachine) {
+	files := make(map[string]*StateMachine, len(l.files)+1)
+	for p, f := range l.files {
+		files[p] = f
+	}
+	if sm == nil {
+		delete(files, path)
+	} else {
+		files[path] = sm
+	}
+	l.files = files
+	l.index()
+}
+
+func (l *Loader) emitRemoved(ctx context.Context, path string, sm *StateMachine) {
+	d := sm.Dialog()
+	slog.Info("dialog unloaded", slog.String("file", path),
+		slog.String("dialog", d.Name), slog.String("version", d.Version))
+	l.emit(ctx, events.DialogReloaded, &events.DialogReloadedData{
+		File:          path,
+		DialogName:    d.Name,
+		DialogVersion: d.Version,
+		Removed:       true,
+	})
+}
+
+func (l *Loader) emit(ctx context.Context, eventType events.EventType, data any) {
+	if l.publisher == nil {
+		return
+	}
+	if err := l.publisher.Emit(ctx, eventType, "", data); err != nil {
+		slog.Warn("emitting dialog reload event failed",
+			slog.String("event_type", string(eventType)), slog.String("error", err.Error()))
+	}
+}
+
+// eventDiagnostics returns the analyzer diagnostics carried by err, if any.
+func eventDiagnostics(err error) []events.DialogDiagnostic {
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) {
+		return nil
+	}
+	out := make([]events.DialogDiagnostic, 0, len(analysisErr.Diagnostics))
+	for _, d := range analysisErr.Diagnostics {
+		out = append(out, events.DialogDiagnostic{
+			Severity: string(d.Severity),
+			Code:     d.Code,
+			Path:     d.Path,
+			Line:     d.Line,
+			Column:   d.Column,
+			Message:  d.Message,
+		})
+	}
+	return out
+}
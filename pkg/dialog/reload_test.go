@@ -0,0 +1,163 @@
+package dialog
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/events"
+)
+
+func TestLoadAllSkipsInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{
+		"good.yaml": versionedDialog("1.0", "hi"),
+		"bad.yaml":  "name: bad\ninitial_state: missing\n",
+	})
+
+	loader := NewLoader(dir)
+	dialogs, err := loader.LoadAll()
+	if err == nil {
+		t.Error("expected error for invalid file")
+	}
+	if _, ok := dialogs["versioned"]; !ok {
+		t.Errorf("valid dialog not loaded: %v", dialogs)
+	}
+	if _, ok := loader.Get("bad"); ok {
+		t.Error("invalid dialog loaded")
+	}
+}
+
+func TestLoadAllKeepsLastGoodVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{"dialog.yaml": versionedDialog("1.0", "hi")})
+	loader := NewLoader(dir)
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	writeDialogs(t, dir, map[string]string{"dialog.yaml": "name: versioned\ninitial_state: [\n"})
+	if _, err := loader.LoadAll(); err == nil {
+		t.Fatal("expected error for invalid YAML")
+	}
+	if sm, ok := loader.Get("versioned"); !ok || sm.Dialog().Version != "1.0" {
+		t.Errorf("last good version not kept: %v, %v", sm, ok)
+	}
+}
+
+func TestWatchAndReload(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{"dialog.yaml": versionedDialog("1.0", "hi")})
+
+	pub := events.NewPublisher(nil, "dialog", "")
+	envelopes := pub.Subscribe("test", 16)
+	defer pub.Unsubscribe("test")
+
+	loader := NewLoader(dir, ReloadEvents(pub), ReloadDebounce(20*time.Millisecond))
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	watchDone := make(chan error, 1)
+	go func() { watchDone <- loader.WatchAndReload(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-watchDone; err != nil {
+			t.Errorf("WatchAndReload: %v", err)
+		}
+	}()
+	// Give the watcher time to register the directory.
+	time.Sleep(100 * time.Millisecond)
+
+	next := func(want events.EventType) json.RawMessage {
+		t.Helper()
+		select {
+		case env := <-envelopes:
+			if env.Type != want {
+				t.Fatalf("event %s (%s), want %s", env.Type, env.Data, want)
+			}
+			return env.Data
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %s event", want)
+		}
+		return nil
+	}
+	path := filepath.Join(dir, "dialog.yaml")
+
+	// An invalid edit is rejected and the last good version stays loaded.
+	writeDialogs(t, dir, map[string]string{"dialog.yaml": `name: versioned
+version: "1.1"
+initial_state: start
+states:
+  start:
+    timeout: "forever"
+    terminal: true
+`})
+	var failed events.DialogReloadFailedData
+	if err := json.Unmarshal(next(events.DialogReloadFailed), &failed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if failed.File != path || len(failed.Diagnostics) != 1 || failed.Diagnostics[0].Code != "invalid-timeout" || failed.Diagnostics[0].Line != 6 {
+		t.Errorf("reload_failed = %+v", failed)
+	}
+	if sm, _ := loader.Get("versioned"); sm.Dialog().Version != "1.0" {
+		t.Errorf("version after failed reload = %q, want 1.0", sm.Dialog().Version)
+	}
+
+	// Several quick writes are debounced into a single reload.
+	for _, greeting := range []string{"one", "two", "three"} {
+		writeDialogs(t, dir, map[string]string{"dialog.yaml": versionedDialog("2.0", greeting)})
+	}
+	var reloaded events.DialogReloadedData
+	if err := json.Unmarshal(next(events.DialogReloaded), &reloaded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if reloaded.DialogName != "versioned" || reloaded.DialogVersion != "2.0" || reloaded.Removed {
+		t.Errorf("reloaded = %+v", reloaded)
+	}
+	sm, _ := loader.Get("versioned")
+	if got := sm.Dialog().States["start"].OnEnter[0].Params["text"]; got != "three" {
+		t.Errorf("greeting = %q, want three", got)
+	}
+	if _, ok := loader.GetVersion("versioned", "1.0"); ok {
+		t.Error("replaced version 1.0 still loaded")
+	}
+
+	// Renaming unloads the old file and loads the new one.
+	renamed := filepath.Join(dir, "renamed.yaml")
+	if err := os.Rename(path, renamed); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	got := map[string]events.DialogReloadedData{}
+	for range 2 {
+		var data events.DialogReloadedData
+		if err := json.Unmarshal(next(events.DialogReloaded), &data); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got[data.File] = data
+	}
+	if !got[path].Removed || got[renamed].Removed || got[renamed].DialogVersion != "2.0" {
+		t.Errorf("rename events = %+v", got)
+	}
+	if _, ok := loader.Get("versioned"); !ok {
+		t.Error("dialog not loaded after rename")
+	}
+
+	// Removing the file unloads the dialog.
+	if err := os.Remove(renamed); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := json.Unmarshal(next(events.DialogReloaded), &reloaded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reloaded.Removed || reloaded.File != renamed {
+		t.Errorf("remove event = %+v", reloaded)
+	}
+	if _, ok := loader.Get("versioned"); ok {
+		t.Error("dialog still loaded after remove")
+	}
+}
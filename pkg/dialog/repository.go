@@ -0,0 +1,132 @@
+package dialog
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/pitabwire/frame/datastore/pool"
+)
+
+// Repository persists dialog sessions to the dialog_sessions table and
+// API-managed dialog definitions to dialog_definitions.
+type Repository struct {
+	pool pool.Pool
+}
+
+// NewRepository creates a new dialog repository.
+func NewRepository(pool pool.Pool) *Repository {
+	return &Repository{pool: pool}
+}
+
+func (r *Repository) db(ctx context.Context, readOnly bool) *gorm.DB {
+	return r.pool.DB(ctx, readOnly)
+}
+
+// SaveSession inserts a session record, replacing any existing row with the
+// same ID. The row is marked active.
+func (r *Repository) SaveSession(ctx context.Context, rec *SessionRecord) error {
+	rec.IsActive = true
+	rec.ModifiedAt = time.Now()
+	return r.db(ctx, false).Clauses(clause.OnConflict{UpdateAll: true}).Create(rec).Error
+}
+
+// UpdateSession writes the current state, variables, history, transcript and
+// gather progress of an active session. Rows already marked inactive are left untouched.
+func (r *Repository) UpdateSession(ctx context.Context, rec *SessionRecord) error {
+	return r.db(ctx, false).
+		Model(&SessionRecord{}).
+		Where("id = ? AND is_active = ?", rec.ID, true).
+		Updates(map[string]any{
+			"dialog_version": rec.DialogVersion,
+			"current_state":  rec.CurrentState,
+			"variables":      rec.Variables,
+			"history":        rec.History,
+			"transcript":     rec.Transcript,
+			"visits":         rec.Visits,
+			"attempts":       rec.Attempts,
+			"digits":         rec.Digits,
+			"modified_at":    time.Now(),
+		}).Error
+}
+
+// MarkInactive flags a session as no longer live.
+func (r *Repository) MarkInactive(ctx context.Context, id string) error {
+	return r.db(ctx, false).
+		Model(&SessionRecord{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"is_active":   false,
+			"modified_at": time.Now(),
+		}).Error
+}
+
+// ListActive returns all sessions still marked active.
+func (r *Repository) ListActive(ctx context.Context) ([]SessionRecord, error) {
+	var records []SessionRecord
+	err := r.db(ctx, true).
+		Where("is_active = ?", true).
+		Order("created_at").
+		Find(&records).Error
+	return records, err
+}
+
+// ListDefinitions returns every stored dialog version, oldest first. It
+// reads from the primary: the loader lists definitions right after writing
+// them, and a lagging replica would miss the write.
+func (r *Repository) ListDefinitions(ctx context.Context) ([]DefinitionRecord, error) {
+	var records []DefinitionRecord
+	err := r.db(ctx, false).
+		Order("name, created_at").
+		Find(&records).Error
+	return records, err
+}
+
+// CreateDefinition stores a new dialog version. When the record is active the
+// dialog's other versions are deactivated in the same transaction.
+func (r *Repository) CreateDefinition(ctx context.Context, rec *DefinitionRecord) error {
+	return r.db(ctx, false).Transaction(func(tx *gorm.DB) error {
+		if rec.IsActive {
+			if err := deactivateDefinitions(tx, rec.Name); err != nil {
+				return err
+			}
+		}
+		return tx.Create(rec).Error
+	})
+}
+
+// ActivateDefinition makes a stored version the active version of its dialog.
+func (r *Repository) ActivateDefinition(ctx context.Context, name, version string) error {
+	return r.db(ctx, false).Transaction(func(tx *gorm.DB) error {
+		if err := deactivateDefinitions(tx, name); err != nil {
+			return err
+		}
+		return tx.Model(&DefinitionRecord{}).
+			Where("name = ? AND dialog_version = ?", name, version).
+			Updates(map[string]any{
+				"is_active":   true,
+				"modified_at": time.Now(),
+			}).Error
+	})
+}
+
+// DeleteDefinitions deletes one stored version of a dialog, or every version
+// when version is empty.
+func (r *Repository) DeleteDefinitions(ctx context.Context, name, version string) error {
+	q := r.db(ctx, false).Where("name = ?", name)
+	if version != "" {
+		q = q.Where("dialog_version = ?", version)
+	}
+	return q.Delete(&DefinitionRecord{}).Error
+}
+
+func deactivateDefinitions(tx *gorm.DB, name string) error {
+	return tx.Model(&DefinitionRecord{}).
+		Where("name = ? AND is_active = ?", name, true).
+		Updates(map[string]any{
+			"is_active":   false,
+			"modified_at": time.Now(),
+		}).Error
+}
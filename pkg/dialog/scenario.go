@@ -0,0 +1,494 @@
+package dialog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/voicetyped/voicetyped/pkg/hooks"
+)
+
+// scenarioEpoch is where a scenario's virtual clock starts unless it sets clock.
+var scenarioEpoch = time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+// ScenarioFile is a set of test scenarios for one dialog, stored next to it
+// as <dialog>.test.yaml. The loader ignores scenario files.
+type ScenarioFile struct {
+	// Dialog is the dialog file under test, relative to the scenario file.
+	// It defaults to the scenario file's name without ".test".
+	Dialog string `yaml:"dialog"`
+	// HooksFile is a hook registry file, relative to the scenario file, for
+	// call_hook actions naming hooks their dialog does not define. Like the
+	// dialog service's HOOKS_FILE, see hooks.LoadRegistry.
+	HooksFile string     `yaml:"hooks_file"`
+	Scenarios []Scenario `yaml:"scenarios"`
+
+	registry hooks.Registry
+}
+
+// Scenario is a scripted call: the inputs a caller sends and what the dialog
+// is expected to do after each of them.
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Variables seed the session, like StartDialogRequest.variables.
+	Variables map[string]string `yaml:"variables"`
+	// Clock is the RFC 3339 start time of the virtual clock seen by the
+	// now template function and state timeouts.
+	Clock string `yaml:"clock"`
+	// Hooks are stub responses by URL, used for call_hook actions whose
+	// step does not queue its own.
+	Hooks map[string]HookStub `yaml:"hooks"`
+	// Start is checked after the dialog enters its initial state.
+	Start *Expectation   `yaml:"start"`
+	Steps []ScenarioStep `yaml:"steps"`
+}
+
+// ScenarioStep delivers one input to the dialog and checks the outcome.
+// Exactly one of Say, DTMF, Event, Wait, Timeout and Hangup must be set.
+type ScenarioStep struct {
+	// Say is a final speech result.
+	Say *string `yaml:"say"`
+	// DTMF is one or more digits.
+	DTMF string `yaml:"dtmf"`
+	// Event is any other event type, e.g. tts_complete.
+	Event string `yaml:"event"`
+	// Wait advances the virtual clock, firing every state timeout that
+	// falls due in the meantime.
+	Wait string `yaml:"wait"`
+	// Timeout advances the virtual clock to the current state's timeout.
+	Timeout bool `yaml:"timeout"`
+	// Hangup ends the session, running the dialog's on_hangup actions. It
+	// may follow the step that reached a terminal state.
+	Hangup bool `yaml:"hangup"`
+	// Hooks are stub responses for the call_hook actions run by this step,
+	// in call order. Every queued stub must be used.
+	Hooks  []HookStub  `yaml:"hooks"`
+	Expect Expectation `yaml:"expect"`
+}
+
+// HookStub is a canned hook response. Response uses the JSON field names of
+// a hook response (variables, data, actions, next_state).
+type HookStub struct {
+	// URL, when set on a queued stub, is the URL the hook must be called with.
+	URL string `yaml:"url"`
+	// Response is the response body: a hook protocol response, or any JSON
+	// object for a call_hook action with a response_map.
+	Response map[string]any `yaml:"response"`
+	// Error fails the call, as an unreachable endpoint would.
+	Error string `yaml:"error"`
+}
+
+// Expectation is checked after a step. Unset fields are not checked.
+type Expectation struct {
+	State    string `yaml:"state"`
+	Terminal *bool  `yaml:"terminal"`
+	// Actions are the directives the step produced, in order. Only the
+	// listed params are compared; an empty list expects no directives.
+	Actions   *[]Action         `yaml:"actions"`
+	Variables map[string]string `yaml:"variables"`
+}
+
+// ScenarioResult is the outcome of running one scenario.
+type ScenarioResult struct {
+	Name     string
+	Failures []string
+}
+
+// Passed reports whether every expectation of the scenario was met.
+func (r ScenarioResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+func isScenarioFile(name string) bool {
+	return strings.HasSuffix(name, ".test.yaml") || strings.HasSuffix(name, ".test.yml")
+}
+
+// FindScenarioFiles expands paths into scenario files: files are kept as
+// they are and directories are searched, non-recursively, for *.test.yaml.
+func FindScenarioFiles(paths ...string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("read dir %q: %w", p, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && isScenarioFile(entry.Name()) {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// LoadScenarioFile reads a scenario file and loads the dialog it tests.
+// Dialogs with analyzer errors are rejected; warnings are ignored.
+func LoadScenarioFile(path string) (*ScenarioFile, *StateMachine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read scenarios: %w", err)
+	}
+	var sf ScenarioFile
+	if err := yaml.Unmarshal(data, &sf); err != nil {
+		return nil, nil, fmt.Errorf("parse scenarios %q: %w", path, err)
+	}
+	for i, sc := range sf.Scenarios {
+		if sc.Name == "" {
+			sf.Scenarios[i].Name = fmt.Sprintf("scenario %d", i+1)
+		}
+	}
+
+	dialogPath := sf.Dialog
+	if dialogPath == "" {
+		dialogPath = strings.Replace(filepath.Base(path), ".test.", ".", 1)
+	}
+	if !filepath.IsAbs(dialogPath) {
+		dialogPath = filepath.Join(filepath.Dir(path), dialogPath)
+	}
+	d, diags, err := AnalyzeFile(dialogPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := diags.Err(false); err != nil {
+		return nil, nil, err
+	}
+	if sf.HooksFile != "" {
+		hooksPath := sf.HooksFile
+		if !filepath.IsAbs(hooksPath) {
+			hooksPath = filepath.Join(filepath.Dir(path), hooksPath)
+		}
+		if sf.registry, err = hooks.LoadRegistry(hooksPath); err != nil {
+			return nil, nil, err
+		}
+	}
+	return &sf, NewStateMachine(d), nil
+}
+
+// RunScenarioFile runs every scenario in a scenario file.
+func RunScenarioFile(ctx context.Context, path string) ([]ScenarioResult, error) {
+	sf, sm, err := LoadScenarioFile(path)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ScenarioResult, 0, len(sf.Scenarios))
+	for _, sc := range sf.Scenarios {
+		results = append(results, RunScenario(ctx, sm, sc, UseHookRegistry(sf.registry)))
+	}
+	return results, nil
+}
+
+// RunScenario plays a scenario against sm on a fresh session, with an engine
+// configured by opts. Hooks are answered by the scenario's stubs and time
+// only moves when a step says so; nothing leaves the process.
+func RunScenario(ctx context.Context, sm *StateMachine, sc Scenario, opts ...EngineOption) ScenarioResult {
+	r := &scenarioRun{
+		result:  ScenarioResult{Name: sc.Name},
+		session: NewSession("scenario", sm.Dialog().Name, sm.Dialog().InitialState),
+		hooks:   &stubHooks{byURL: sc.Hooks},
+	}
+	r.engine = NewEngine(map[string]*StateMachine{sm.Dialog().Name: sm}, nil, nil, append(opts, UseHooks(r.hooks))...)
+
+	r.now = scenarioEpoch
+	if sc.Clock != "" {
+		t, err := time.Parse(time.RFC3339, sc.Clock)
+		if err != nil {
+			r.failf("clock: %v", err)
+			return r.result
+		}
+		r.now = t
+	}
+	r.session.clock = func() time.Time { return r.now }
+	r.session.StartTime = r.now
+	r.session.Pin(sm)
+	if err := r.session.InitVariables(sc.Variables); err != nil {
+		r.failf("variables: %v", err)
+		return r.result
+	}
+
+	res, err := r.engine.Start(ctx, r.session)
+	if err == nil {
+		err = r.arm(res)
+	}
+	if err != nil {
+		r.failf("start: %v", err)
+		return r.result
+	}
+	r.check("start", sc.Start, res.Directives)
+	if r.stopped(stepAt(sc.Steps, 0)) {
+		return r.result
+	}
+
+	for i, step := range sc.Steps {
+		label := fmt.Sprintf("step %d (%s)", i+1, step.describe())
+		directives, err := r.step(ctx, step)
+		if err != nil {
+			r.failf("%s: %v", label, err)
+			return r.result
+		}
+		r.check(label, &step.Expect, directives)
+		if r.stopped(stepAt(sc.Steps, i+1)) {
+			return r.result
+		}
+	}
+	return r.result
+}
+
+// scenarioRun is the state of a single RunScenario call.
+type scenarioRun struct {
+	result  ScenarioResult
+	engine  *Engine
+	session *Session
+	hooks   *stubHooks
+	// now is the virtual clock; deadline is when the current state times
+	// out, or zero.
+	now      time.Time
+	deadline time.Time
+	done     bool
+}
+
+func (r *scenarioRun) failf(format string, args ...any) {
+	r.result.Failures = append(r.result.Failures, fmt.Sprintf(format, args...))
+}
+
+// stopped reports whether the scenario cannot continue with next: the dialog
+// ended and next is not a hangup, or a hook stub mismatch left the session in
+// an unknown state.
+func (r *scenarioRun) stopped(next *ScenarioStep) bool {
+	for _, f := range r.hooks.failures {
+		r.failf("%s", f)
+	}
+	stop := (r.done && (next == nil || !next.Hangup)) || len(r.hooks.failures) > 0
+	r.hooks.failures = nil
+	return stop
+}
+
+// step delivers a step's input and returns the directives it produced.
+func (r *scenarioRun) step(ctx context.Context, step ScenarioStep) ([]Action, error) {
+	if n := step.inputs(); n != 1 {
+		return nil, fmt.Errorf("step must have exactly one of say, dtmf, event, wait, timeout and hangup, has %d", n)
+	}
+	r.hooks.queued = step.Hooks
+	defer r.hooks.checkUsed()
+
+	switch {
+	case step.Say != nil:
+		return r.handle(ctx, Event{Type: EventSpeech, Data: *step.Say})
+	case step.DTMF != "":
+		return r.handle(ctx, Event{Type: EventDTMF, Data: step.DTMF})
+	case step.Event != "":
+		return r.handle(ctx, Event{Type: step.Event})
+	case step.Timeout:
+		if r.deadline.IsZero() {
+			return nil, fmt.Errorf("state %q has no timeout", r.session.GetCurrentState())
+		}
+		r.now = r.deadline
+		return r.handle(ctx, Event{Type: EventTimeout})
+	case step.Hangup:
+		res, err := r.engine.End(ctx, r.session)
+		if err != nil {
+			return nil, err
+		}
+		r.done, r.deadline = true, time.Time{}
+		return res.Directives, nil
+	}
+
+	dur, err := time.ParseDuration(step.Wait)
+	if err != nil {
+		return nil, fmt.Errorf("wait: %w", err)
+	}
+	end := r.now.Add(dur)
+	var directives []Action
+	for !r.done && !r.deadline.IsZero() && !r.deadline.After(end) {
+		r.now = r.deadline
+		d, err := r.handle(ctx, Event{Type: EventTimeout})
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, d...)
+	}
+	r.now = end
+	return directives, nil
+}
+
+func stepAt(steps []ScenarioStep, i int) *ScenarioStep {
+	if i < len(steps) {
+		return &steps[i]
+	}
+	return nil
+}
+
+// handle processes ev and rearms the state timeout the way Engine.Run does.
+func (r *scenarioRun) handle(ctx context.Context, ev Event) ([]Action, error) {
+	res, err := r.engine.HandleEvent(ctx, r.session, ev)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.arm(res); err != nil {
+		return nil, err
+	}
+	return res.Directives, nil
+}
+
+// arm records whether res ended the dialog and sets the deadline of the state
+// it left the session in.
+func (r *scenarioRun) arm(res *StepResult) error {
+	r.done = res.Terminal
+	r.deadline = time.Time{}
+	if res.Terminal {
+		return nil
+	}
+	_, state, err := r.engine.resolve(r.session)
+	if err != nil {
+		return err
+	}
+	if dur := timeoutFor(r.session, state); dur > 0 {
+		r.deadline = r.now.Add(dur)
+	}
+	return nil
+}
+
+// check compares the session and the directives of a step with want.
+func (r *scenarioRun) check(label string, want *Expectation, directives []Action) {
+	if want == nil {
+		return
+	}
+	if want.State != "" {
+		if got := r.session.GetCurrentState(); got != want.State {
+			r.failf("%s: state = %q, want %q", label, got, want.State)
+		}
+	}
+	if want.Terminal != nil && r.done != *want.Terminal {
+		r.failf("%s: terminal = %t, want %t", label, r.done, *want.Terminal)
+	}
+	if want.Actions != nil {
+		if !matchActions(directives, *want.Actions) {
+			r.failf("%s: actions = %s, want %s", label, formatActions(directives), formatActions(*want.Actions))
+		}
+	}
+	vars := r.session.CopyVariables()
+	for _, k := range sortedKeys(want.Variables) {
+		if got, ok := vars[k]; !ok || got != want.Variables[k] {
+			r.failf("%s: variable %q = %q, want %q", label, k, got, want.Variables[k])
+		}
+	}
+}
+
+func matchActions(got, want []Action) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i, w := range want {
+		if got[i].Type != w.Type {
+			return false
+		}
+		for k, v := range w.Params {
+			if p, ok := got[i].Params[k]; !ok || p != v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func formatActions(actions []Action) string {
+	parts := make([]string, 0, len(actions))
+	for _, a := range actions {
+		var params []string
+		for _, k := range sortedKeys(a.Params) {
+			params = append(params, fmt.Sprintf("%s=%q", k, a.Params[k]))
+		}
+		parts = append(parts, a.Type+"{"+strings.Join(params, " ")+"}")
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (s ScenarioStep) inputs() int {
+	n := 0
+	for _, set := range []bool{s.Say != nil, s.DTMF != "", s.Event != "", s.Wait != "", s.Timeout, s.Hangup} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+func (s ScenarioStep) describe() string {
+	switch {
+	case s.Say != nil:
+		return fmt.Sprintf("say %q", *s.Say)
+	case s.DTMF != "":
+		return "dtmf " + s.DTMF
+	case s.Event != "":
+		return "event " + s.Event
+	case s.Wait != "":
+		return "wait " + s.Wait
+	case s.Timeout:
+		return "timeout"
+	case s.Hangup:
+		return "hangup"
+	}
+	return "no input"
+}
+
+// stubHooks answers call_hook actions from a scenario's stubs.
+type stubHooks struct {
+	queued   []HookStub
+	byURL    map[string]HookStub
+	failures []string
+}
+
+func (h *stubHooks) Execute(_ context.Context, cfg hooks.HookConfig, _ hooks.HookRequest) (*hooks.HookResponse, error) {
+	var stub HookStub
+	switch s, ok := h.byURL[cfg.URL]; {
+	case len(h.queued) > 0:
+		stub, h.queued = h.queued[0], h.queued[1:]
+		if stub.URL != "" && stub.URL != cfg.URL {
+			h.failures = append(h.failures, fmt.Sprintf("hook called with %q, stub expects %q", cfg.URL, stub.URL))
+		}
+	case ok:
+		stub = s
+	default:
+		h.failures = append(h.failures, fmt.Sprintf("no hook stub for %q", cfg.URL))
+		return nil, fmt.Errorf("no hook stub for %q", cfg.URL)
+	}
+
+	if stub.Error != "" {
+		return nil, errors.New(stub.Error)
+	}
+	data, err := json.Marshal(stub.Response)
+	if err != nil {
+		return nil, fmt.Errorf("marshal hook stub: %w", err)
+	}
+	if cfg.RawResponse {
+		return &hooks.HookResponse{Raw: data, Data: stub.Response}, nil
+	}
+	var resp hooks.HookResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		h.failures = append(h.failures, fmt.Sprintf("invalid hook stub response for %q: %v", cfg.URL, err))
+		return nil, fmt.Errorf("unmarshal hook stub: %w", err)
+	}
+	return &resp, nil
+}
+
+// checkUsed records a failure for queued stubs no hook call consumed.
+func (h *stubHooks) checkUsed() {
+	if n := len(h.queued); n > 0 {
+		h.failures = append(h.failures, fmt.Sprintf("%d queued hook stubs not used", n))
+	}
+	h.queued = nil
+}
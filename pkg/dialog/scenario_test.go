@@ -0,0 +1,235 @@
+package dialog
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const scenarioDialog = `name: scenario-test
+initial_state: menu
+states:
+  menu:
+    on_enter:
+      - type: play_tts
+        params:
+          text: "{{ if lt now.Hour 12 }}Good morning{{ else }}Good afternoon{{ end }}"
+    transitions:
+      - event: speech
+        target: lookup
+    timeout: "5s"
+    timeout_next: retry
+  retry:
+    transitions:
+      - event: speech
+        target: lookup
+    timeout: "5s"
+    timeout_next: done
+  lookup:
+    on_enter:
+      - type: call_hook
+        params:
+          url: "https://example.com/lookup"
+    transitions:
+      - event: hook_result
+        target: found
+      - event: hook_error
+        target: done
+  found:
+    on_enter:
+      - type: play_tts
+        params:
+          text: "Hello {{ .Variables.name }}"
+    transitions:
+      - event: tts_complete
+        target: done
+  done:
+    terminal: true
+`
+
+func runScenarioYAML(t *testing.T, scenario string) ScenarioResult {
+	t.Helper()
+	sm := NewStateMachine(parseDialog(t, scenarioDialog))
+	var sc Scenario
+	if err := yaml.Unmarshal([]byte(scenario), &sc); err != nil {
+		t.Fatalf("parse scenario: %v", err)
+	}
+	return RunScenario(t.Context(), sm, sc)
+}
+
+func parseDialog(t *testing.T, content string) *Dialog {
+	t.Helper()
+	var d Dialog
+	if err := yaml.Unmarshal([]byte(content), &d); err != nil {
+		t.Fatalf("parse dialog: %v", err)
+	}
+	return &d
+}
+
+func TestRunScenario(t *testing.T) {
+	res := runScenarioYAML(t, `
+clock: "2025-06-01T15:00:00Z"
+start:
+  actions:
+    - type: play_tts
+      params: {text: "Good afternoon"}
+steps:
+  - say: "it's me"
+    hooks:
+      - url: https://example.com/lookup
+        response:
+          variables: {name: Ada}
+    expect:
+      state: found
+      actions:
+        - type: play_tts
+          params: {text: "Hello Ada"}
+      variables: {name: Ada}
+  - event: tts_complete
+    expect:
+      state: done
+      terminal: true
+`)
+	if !res.Passed() {
+		t.Errorf("failures: %v", res.Failures)
+	}
+}
+
+func TestRunScenarioVirtualClock(t *testing.T) {
+	// A wait longer than both timeouts fires them in turn.
+	res := runScenarioYAML(t, `
+start:
+  actions:
+    - type: play_tts
+      params: {text: "Good morning"}
+steps:
+  - wait: 4s
+    expect: {state: menu}
+  - wait: 1s
+    expect: {state: retry}
+  - say: "hello"
+    hooks: [{error: "down"}]
+    expect: {state: done, terminal: true}
+`)
+	if !res.Passed() {
+		t.Errorf("failures: %v", res.Failures)
+	}
+
+	res = runScenarioYAML(t, `
+steps:
+  - wait: 1m
+    expect: {state: done, terminal: true}
+`)
+	if !res.Passed() {
+		t.Errorf("failures: %v", res.Failures)
+	}
+}
+
+func TestRunScenarioFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario string
+		want     string
+	}{
+		{
+			name: "wrong state",
+			scenario: `
+steps:
+  - timeout: true
+    expect: {state: done}
+`,
+			want: `step 1 (timeout): state = "retry", want "done"`,
+		},
+		{
+			name: "wrong actions",
+			scenario: `
+start:
+  actions: [{type: hangup}]
+`,
+			want: `start: actions = [play_tts{text="Good morning"}], want [hangup{}]`,
+		},
+		{
+			name: "missing hook stub",
+			scenario: `
+steps:
+  - say: "hi"
+`,
+			want: `no hook stub for "https://example.com/lookup"`,
+		},
+		{
+			name: "unused hook stub",
+			scenario: `
+steps:
+  - timeout: true
+    hooks: [{response: {}}]
+`,
+			want: "1 queued hook stubs not used",
+		},
+		{
+			name: "two inputs",
+			scenario: `
+steps:
+  - say: "hi"
+    dtmf: "1"
+`,
+			want: "exactly one of say, dtmf, event, wait, timeout and hangup",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runScenarioYAML(t, tt.scenario)
+			if res.Passed() {
+				t.Fatal("scenario passed, want failure")
+			}
+			if !strings.Contains(strings.Join(res.Failures, "\n"), tt.want) {
+				t.Errorf("failures = %v, want %q", res.Failures, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunScenarioHangup(t *testing.T) {
+	sm := NewStateMachine(parseDialog(t, `name: hangup
+initial_state: menu
+on_hangup:
+  - type: call_hook
+    params: {url: "https://example.com/wrapup"}
+  - type: set_variable
+    params: {ended_in: "{{ .Session.CurrentState }}"}
+states:
+  menu:
+    transitions:
+      - event: speech
+        target: done
+  done:
+    terminal: true
+`))
+	var sc Scenario
+	if err := yaml.Unmarshal([]byte(`
+steps:
+  - say: "bye"
+    expect: {state: done, terminal: true}
+  - hangup: true
+    hooks: [{url: "https://example.com/wrapup", response: {}}]
+    expect:
+      actions: []
+      variables: {ended_in: done}
+`), &sc); err != nil {
+		t.Fatalf("parse scenario: %v", err)
+	}
+	if res := RunScenario(t.Context(), sm, sc); !res.Passed() {
+		t.Errorf("failures: %v", res.Failures)
+	}
+}
+
+func TestLoaderSkipsScenarioFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{
+		"dialog.yaml":      versionedDialog("1.0", "hi"),
+		"dialog.test.yaml": "scenarios: []\n",
+	})
+	if _, err := NewLoader(dir).LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+}
@@ -21,14 +21,35 @@ type Session struct {
 	mu         sync.RWMutex
 	maxHistory int
 
-	ID           string
-	DialogName   string
-	CurrentState string
-	Variables    map[string]string
-	History      []StateRecord
-	StartTime    time.Time
-	LastEvent    any
-	LastResult   map[string]any
+	ID         string
+	DialogName string
+	// DialogVersion is the version of the dialog the session is pinned to.
+	DialogVersion string
+	CurrentState  string
+	Variables     map[string]string
+	History       []StateRecord
+	StartTime     time.Time
+	LastEvent     any
+	LastResult    map[string]any
+	// Visits counts entries into each state; Attempts counts failed gather
+	// attempts in each state since it was last entered.
+	Visits   map[string]int
+	Attempts map[string]int
+	// Intents is the top-ranked intent classification of the last utterance.
+	Intents []IntentScore
+	// Transcript is the ordered log of utterances, prompts, DTMF keys and
+	// hook calls.
+	Transcript []Turn
+
+	digits *DigitCollection
+	// clock, when set, replaces time.Now for timestamps and the now
+	// template function. It must be set before the session is used.
+	clock func() time.Time
+	// machine is the state machine the session runs; reloading or adding
+	// dialog versions does not affect a pinned session.
+	machine *StateMachine
+	// ended is set once the session's on_hangup actions have run.
+	ended bool
 }
 
 // NewSession creates a new call session.
@@ -40,6 +61,8 @@ func NewSession(id, dialogName, initialState string) *Session {
 		Variables:    make(map[string]string),
 		StartTime:    time.Now(),
 		LastResult:   make(map[string]any),
+		Visits:       make(map[string]int),
+		Attempts:     make(map[string]int),
 		maxHistory:   DefaultMaxHistory,
 	}
 }
@@ -60,7 +83,7 @@ func (s *Session) RecordTransition(from, to, trigger string) {
 		FromState: from,
 		ToState:   to,
 		Trigger:   trigger,
-		Timestamp: time.Now(),
+		Timestamp: s.now(),
 	})
 	s.CurrentState = to
 }
@@ -79,6 +102,41 @@ func (s *Session) GetVariable(key string) string {
 	return s.Variables[key]
 }
 
+// Pin binds the session to sm for the rest of its life and records the
+// dialog version it runs.
+func (s *Session) Pin(sm *StateMachine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.machine = sm
+	s.DialogVersion = sm.Dialog().Version
+}
+
+// markEnded marks the session as ended and reports whether it was not
+// already, so that end-of-session handling runs once.
+func (s *Session) markEnded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false
+	}
+	s.ended = true
+	return true
+}
+
+// Machine returns the state machine the session is pinned to, or nil.
+func (s *Session) Machine() *StateMachine {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.machine
+}
+
+// GetDialogVersion returns the version of the dialog the session runs.
+func (s *Session) GetDialogVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.DialogVersion
+}
+
 // GetCurrentState returns the current state name.
 func (s *Session) GetCurrentState() string {
 	s.mu.RLock()
@@ -144,3 +202,102 @@ func (s *Session) CopyHistory() []StateRecord {
 	copy(cp, s.History)
 	return cp
 }
+
+// RecordVisit counts an entry into state and resets its attempt counter.
+func (s *Session) RecordVisit(state string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Visits[state]++
+	delete(s.Attempts, state)
+	return s.Visits[state]
+}
+
+// IncrementAttempts counts a failed attempt in state and returns the total.
+func (s *Session) IncrementAttempts(state string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attempts[state]++
+	return s.Attempts[state]
+}
+
+// CopyVisits returns a snapshot of the per-state visit counters.
+func (s *Session) CopyVisits() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := make(map[string]int, len(s.Visits))
+	for k, v := range s.Visits {
+		cp[k] = v
+	}
+	return cp
+}
+
+// CopyAttempts returns a snapshot of the per-state attempt counters.
+func (s *Session) CopyAttempts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := make(map[string]int, len(s.Attempts))
+	for k, v := range s.Attempts {
+		cp[k] = v
+	}
+	return cp
+}
+
+// SetIntents sets the intent ranking of the last utterance.
+func (s *Session) SetIntents(scores []IntentScore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Intents = scores
+}
+
+// CopyIntents returns a snapshot of the intent ranking of the last utterance.
+func (s *Session) CopyIntents() []IntentScore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := make([]IntentScore, len(s.Intents))
+	copy(cp, s.Intents)
+	return cp
+}
+
+// StartDigitCollection begins buffering DTMF digits, replacing any collection
+// already in progress.
+func (s *Session) StartDigitCollection(c *DigitCollection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.digits = c
+}
+
+// DigitCollection returns a snapshot of the digit collection in progress.
+func (s *Session) DigitCollection() (DigitCollection, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.digits == nil {
+		return DigitCollection{}, false
+	}
+	return *s.digits, true
+}
+
+// AppendDigit adds a digit to the collection in progress and returns the
+// digits collected so far.
+func (s *Session) AppendDigit(d string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.digits == nil {
+		return ""
+	}
+	s.digits.Digits += d
+	return s.digits.Digits
+}
+
+// StopDigitCollection ends the digit collection in progress, if any.
+func (s *Session) StopDigitCollection() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.digits = nil
+}
+
+func (s *Session) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+	return time.Now()
+}
@@ -5,29 +5,73 @@ import (
 	"fmt"
 	"io"
 	"strings"
-	"sync"
 	"text/template"
+	"time"
 )
 
 const maxTemplateOutput = 64 * 1024
 
 // templateCache caches parsed templates to avoid re-parsing on every call.
-var templateCache sync.Map
+var templateCache = newLRUCache[*template.Template](maxCachedTemplates)
 
 // templateCtx is the data available in Go template expressions.
 type templateCtx struct {
-	Session   *Session
-	Event     any
-	Variables map[string]string
+	Session sessionView
+	Event   any
+	// Variables holds declared variables converted to their type and the
+	// others as strings.
+	Variables map[string]any
 	Result    map[string]any
+	Visits    map[string]int
+	Attempts  map[string]int
+	// Intent and IntentScore are the top-ranked intent of the last utterance;
+	// Intents holds the top IntentTopN.
+	Intent      string
+	IntentScore float64
+	Intents     []IntentScore
+	// Transcript is the session's turn log, oldest first.
+	Transcript []Turn
 }
 
 func newTemplateCtx(session *Session) templateCtx {
-	return templateCtx{
-		Session:   session,
-		Event:     session.GetLastEvent(),
-		Variables: session.CopyVariables(),
-		Result:    session.GetLastResult(),
+	intents := session.CopyIntents()
+	ctx := templateCtx{
+		Session:    newSessionView(session),
+		Event:      session.GetLastEvent(),
+		Variables:  typedVariables(session),
+		Result:     session.GetLastResult(),
+		Visits:     session.CopyVisits(),
+		Attempts:   session.CopyAttempts(),
+		Intents:    intents,
+		Transcript: session.CopyTranscript(),
+	}
+	if len(intents) > 0 {
+		ctx.Intent = intents[0].Name
+		ctx.IntentScore = intents[0].Score
+	}
+	return ctx
+}
+
+// sessionView is the read-only snapshot of a session that templates see as
+// .Session. Variables may only change through actions, which enforce their
+// declarations.
+type sessionView struct {
+	ID            string
+	DialogName    string
+	DialogVersion string
+	CurrentState  string
+	StartTime     time.Time
+	History       []StateRecord
+}
+
+func newSessionView(session *Session) sessionView {
+	return sessionView{
+		ID:            session.ID,
+		DialogName:    session.DialogName,
+		DialogVersion: session.GetDialogVersion(),
+		CurrentState:  session.GetCurrentState(),
+		StartTime:     session.StartTime,
+		History:       session.CopyHistory(),
 	}
 }
 
@@ -79,17 +123,30 @@ func (lw *limitWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// parseTemplate parses a template with the function library, caching the result.
+func parseTemplate(tmplStr string) (*template.Template, error) {
+	if tmpl, ok := templateCache.get(tmplStr); ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New("").Funcs(templateFuncs).Parse(tmplStr)
+	if err != nil {
+		return nil, err
+	}
+	templateCache.add(tmplStr, tmpl)
+	return tmpl, nil
+}
+
 func renderTemplate(tmplStr string, session *Session) (string, error) {
-	var tmpl *template.Template
-	if cached, ok := templateCache.Load(tmplStr); ok {
-		tmpl = cached.(*template.Template)
-	} else {
-		var err error
-		tmpl, err = template.New("").Parse(tmplStr)
-		if err != nil {
+	tmpl, err := parseTemplate(tmplStr)
+	if err != nil {
+		return "", err
+	}
+	if session.clock != nil {
+		// The cached template is shared, so bind the session's clock to a copy.
+		if tmpl, err = tmpl.Clone(); err != nil {
 			return "", err
 		}
-		templateCache.Store(tmplStr, tmpl)
+		tmpl.Funcs(template.FuncMap{"now": session.clock})
 	}
 
 	var buf bytes.Buffer
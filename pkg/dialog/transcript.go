@@ -0,0 +1,125 @@
+package dialog
+
+import (
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/hooks"
+)
+
+// Transcript turn kinds.
+const (
+	TurnCaller = "caller"
+	TurnSystem = "system"
+	TurnDTMF   = "dtmf"
+	TurnHook   = "hook"
+	TurnTool   = "tool"
+)
+
+// DefaultMaxTranscript is the maximum number of transcript turns before
+// eviction.
+const DefaultMaxTranscript = 500
+
+// Turn is one entry in a session's transcript.
+type Turn struct {
+	// Kind is caller for an utterance, system for a prompt, dtmf for keys
+	// pressed, hook for a hook call and tool for a tool call of an llm_turn.
+	Kind string `json:"kind"`
+	// Text is the utterance, the prompt as rendered, the DTMF keys or the
+	// result of a tool call.
+	Text string `json:"text,omitempty"`
+	// Confidence is the recognition confidence of an utterance, if known.
+	Confidence float32 `json:"confidence,omitempty"`
+	// Hook is the hook definition called, or URL the inline hook URL
+	// called without its credentials and query. Error is why the call
+	// failed, if it did.
+	Hook  string `json:"hook,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Tool is the tool called, with the model's ID for the call and the
+	// arguments it passed as JSON.
+	Tool       string `json:"tool,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Arguments  string `json:"arguments,omitempty"`
+	// State is the state the session was in.
+	State     string    `json:"state"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecordTurn appends t to the transcript, stamped with the current state and
+// time. Evicts oldest 10% of turns when the transcript cap is reached.
+func (s *Session) RecordTurn(t Turn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.Transcript) >= DefaultMaxTranscript {
+		s.Transcript = s.Transcript[DefaultMaxTranscript/10:]
+	}
+	t.State = s.CurrentState
+	t.Timestamp = s.now()
+	s.Transcript = append(s.Transcript, t)
+}
+
+// CopyTranscript returns a snapshot of the transcript.
+func (s *Session) CopyTranscript() []Turn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := make([]Turn, len(s.Transcript))
+	copy(cp, s.Transcript)
+	return cp
+}
+
+// recordInput adds the caller's speech or DTMF keys to the transcript.
+func recordInput(session *Session, ev Event) {
+	switch ev.Type {
+	case EventSpeech:
+		session.RecordTurn(Turn{Kind: TurnCaller, Text: eventText(ev.Data), Confidence: ev.Confidence})
+	case EventDTMF:
+		session.RecordTurn(Turn{Kind: TurnDTMF, Text: eventText(ev.Data)})
+	}
+}
+
+// recordPrompt adds the text of a play_tts directive to the transcript.
+func recordPrompt(session *Session, directive Action) {
+	if directive.Type == "play_tts" && directive.Params["text"] != "" {
+		session.RecordTurn(Turn{Kind: TurnSystem, Text: directive.Params["text"]})
+	}
+}
+
+// recordHookCall adds a hook call and its outcome to the transcript. The
+// call's URL is never recorded as is: hooks receive the transcript.
+func recordHookCall(session *Session, call *hookCall, err error) {
+	t := Turn{Kind: TurnHook, Hook: call.name}
+	if call.name == "" {
+		t.URL = hooks.Endpoint(call.cfg.URL)
+	}
+	if err != nil {
+		t.Error = err.Error()
+	}
+	session.RecordTurn(t)
+}
+
+// recordToolCall adds a tool call of an llm_turn and its result to the
+// transcript.
+func recordToolCall(session *Session, c toolCall) {
+	session.RecordTurn(Turn{Kind: TurnTool, Tool: c.name, ToolCallID: c.id, Arguments: c.args, Text: c.result})
+}
+
+// hookTranscript returns the transcript in the form sent to hooks.
+func hookTranscript(session *Session) []hooks.TranscriptTurn {
+	transcript := session.CopyTranscript()
+	out := make([]hooks.TranscriptTurn, 0, len(transcript))
+	for _, t := range transcript {
+		out = append(out, hooks.TranscriptTurn{
+			Kind:       t.Kind,
+			Text:       t.Text,
+			Confidence: t.Confidence,
+			Hook:       t.Hook,
+			URL:        t.URL,
+			Error:      t.Error,
+			Tool:       t.Tool,
+			Arguments:  t.Arguments,
+			State:      t.State,
+			Timestamp:  t.Timestamp,
+		})
+	}
+	return out
+}
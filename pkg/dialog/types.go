@@ -1,28 +1,59 @@
 package dialog
 
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/voicetyped/voicetyped/pkg/hooks"
+)
+
 // Dialog is a YAML-mappable dialog definition.
 type Dialog struct {
 	Name         string            `yaml:"name"          json:"name"`
 	Version      string            `yaml:"version"       json:"version"`
 	Description  string            `yaml:"description"   json:"description"`
-	Variables    map[string]string `yaml:"variables"     json:"variables"`
+	// Variables declares the session variables, see Variable.
+	Variables    map[string]Variable `yaml:"variables"     json:"variables"`
+	Intents      map[string]Intent `yaml:"intents"       json:"intents,omitempty"`
+	// Tools declares the functions the model may call in llm_turn actions.
+	Tools        map[string]Tool   `yaml:"tools"         json:"tools,omitempty"`
+	// Hooks defines the hooks call_hook actions may name with their hook
+	// param, with secrets referenced rather than inlined.
+	Hooks        hooks.Registry    `yaml:"hooks"         json:"hooks,omitempty"`
 	InitialState string           `yaml:"initial_state" json:"initial_state"`
 	States       map[string]State  `yaml:"states"        json:"states"`
+	// GlobalTransitions apply in every non-terminal state, after the
+	// state's own transitions, unless the state ignores them.
+	GlobalTransitions []Transition `yaml:"global_transitions" json:"global_transitions,omitempty"`
+	// OnHangup runs when the session ends; OnError runs when a step fails.
+	OnHangup []Action `yaml:"on_hangup" json:"on_hangup,omitempty"`
+	OnError  []Action `yaml:"on_error"  json:"on_error,omitempty"`
 }
 
 // State represents a single state in the dialog FSM.
 type State struct {
+	// Type is empty for plain states or "gather" for prompt-and-collect states.
+	Type        string       `yaml:"type"          json:"type,omitempty"`
+	Gather      *Gather      `yaml:"gather"        json:"gather,omitempty"`
 	OnEnter     []Action     `yaml:"on_enter"      json:"on_enter,omitempty"`
 	Transitions []Transition `yaml:"transitions"   json:"transitions,omitempty"`
 	Timeout     string       `yaml:"timeout"       json:"timeout,omitempty"`
 	TimeoutNext string       `yaml:"timeout_next"  json:"timeout_next,omitempty"`
 	Terminal    bool         `yaml:"terminal"      json:"terminal,omitempty"`
+	// BargeIn lets caller speech interrupt the prompts played in this state.
+	// A play_tts or play_audio action can override it with a barge_in param.
+	BargeIn bool `yaml:"barge_in" json:"barge_in,omitempty"`
+	// IgnoreGlobalTransitions opts the state out of the dialog's global
+	// transitions, e.g. while collecting an account number that may contain 0.
+	IgnoreGlobalTransitions bool `yaml:"ignore_global_transitions" json:"ignore_global_transitions,omitempty"`
 }
 
 // Transition defines a condition under which the FSM moves to a new state.
 type Transition struct {
 	Event     string   `yaml:"event"     json:"event"`
 	Condition string   `yaml:"condition" json:"condition,omitempty"`
+	Match     *Match   `yaml:"match"     json:"match,omitempty"`
+	Intent    string   `yaml:"intent"    json:"intent,omitempty"`
+	MinScore  float64  `yaml:"min_score" json:"min_score,omitempty"`
 	Target    string   `yaml:"target"    json:"target"`
 	Actions   []Action `yaml:"actions"   json:"actions,omitempty"`
 }
@@ -32,3 +63,38 @@ type Action struct {
 	Type   string            `yaml:"type"   json:"type"`
 	Params map[string]string `yaml:"params" json:"params,omitempty"`
 }
+
+// UnmarshalYAML accepts params nested one level deep, as call_hook's headers
+// and response_map are, and flattens them to dotted keys: headers: {X-Tenant:
+// acme} becomes the param headers.X-Tenant.
+func (a *Action) UnmarshalYAML(node *yaml.Node) error {
+	var raw struct {
+		Type   string               `yaml:"type"`
+		Params map[string]yaml.Node `yaml:"params"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	*a = Action{Type: raw.Type}
+	for k, v := range raw.Params {
+		if a.Params == nil {
+			a.Params = make(map[string]string, len(raw.Params))
+		}
+		if v.Kind != yaml.MappingNode {
+			var s string
+			if err := v.Decode(&s); err != nil {
+				return err
+			}
+			a.Params[k] = s
+			continue
+		}
+		var nested map[string]string
+		if err := v.Decode(&nested); err != nil {
+			return err
+		}
+		for nk, nv := range nested {
+			a.Params[k+"."+nk] = nv
+		}
+	}
+	return nil
+}
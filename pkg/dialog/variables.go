@@ -0,0 +1,353 @@
+package dialog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variable types.
+const (
+	VarTypeString = "string"
+	VarTypeInt    = "int"
+	VarTypeBool   = "bool"
+	VarTypeList   = "list"
+	VarTypeMap    = "map"
+)
+
+// ErrReadonlyVariable is returned when the dialog or a hook assigns a
+// readonly variable.
+var ErrReadonlyVariable = errors.New("variable is readonly")
+
+// Variable declares a session variable. Session variables are stored as
+// strings, lists and maps as JSON; templates see declared variables
+// converted to their type: string, int, bool, []any or map[string]any.
+// In YAML a string variable may be declared by its default alone,
+// `name: value`.
+type Variable struct {
+	// Type is string (the default), int, bool, list or map.
+	Type    string `yaml:"type"    json:"type,omitempty"`
+	Default any    `yaml:"default" json:"default,omitempty"`
+	// Pattern is a regular expression the whole stored value must match.
+	Pattern string `yaml:"pattern" json:"pattern,omitempty"`
+	// Readonly variables keep their default or the value the session was
+	// started with; set_variable, hooks and input cannot assign them.
+	Readonly bool `yaml:"readonly" json:"readonly,omitempty"`
+}
+
+// UnmarshalYAML accepts a declaration or a plain default value.
+func (v *Variable) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		var def string
+		if err := node.Decode(&def); err != nil {
+			return err
+		}
+		*v = Variable{Default: def}
+		return nil
+	}
+	type plain Variable
+	return node.Decode((*plain)(v))
+}
+
+func (v Variable) kind() string {
+	if v.Type == "" {
+		return VarTypeString
+	}
+	return v.Type
+}
+
+// zeroValues are the stored values of variables declared without a default.
+var zeroValues = map[string]string{
+	VarTypeString: "",
+	VarTypeInt:    "0",
+	VarTypeBool:   "false",
+	VarTypeList:   "[]",
+	VarTypeMap:    "{}",
+}
+
+// initial returns the stored form of the variable's default, or of its type's
+// zero value. The zero value is not checked against the pattern.
+func (v Variable) initial() (string, error) {
+	if v.Default == nil {
+		s, ok := zeroValues[v.kind()]
+		if !ok {
+			return "", fmt.Errorf("unknown type %q", v.Type)
+		}
+		return s, nil
+	}
+	return v.encode(v.Default)
+}
+
+// encode converts value to the variable's stored form, checking its type and
+// pattern. Strings are parsed for the other types, so values rendered by
+// set_variable templates and typed hook JSON are both accepted.
+func (v Variable) encode(value any) (string, error) {
+	var (
+		s   string
+		err error
+	)
+	switch v.kind() {
+	case VarTypeString:
+		str, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("want a string, got %s", describeValue(value))
+		}
+		s = str
+	case VarTypeInt:
+		var n int64
+		n, err = toInt(value)
+		s = strconv.FormatInt(n, 10)
+	case VarTypeBool:
+		var b bool
+		b, err = toBool(value)
+		s = strconv.FormatBool(b)
+	case VarTypeList:
+		s, err = encodeJSON[[]any](value, "list")
+	case VarTypeMap:
+		s, err = encodeJSON[map[string]any](value, "map")
+	default:
+		return "", fmt.Errorf("unknown type %q", v.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if v.Pattern != "" {
+		re, err := compileVariablePattern(v.Pattern)
+		if err != nil {
+			return "", err
+		}
+		if !re.MatchString(s) {
+			return "", fmt.Errorf("%q does not match pattern %q", s, v.Pattern)
+		}
+	}
+	return s, nil
+}
+
+// decode converts a stored value to the type templates see. A value that does
+// not parse, e.g. one persisted before the variable was declared, stays a
+// string.
+func (v Variable) decode(s string) any {
+	switch v.kind() {
+	case VarTypeInt:
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+	case VarTypeBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case VarTypeList:
+		var list []any
+		if err := json.Unmarshal([]byte(s), &list); err == nil {
+			return list
+		}
+	case VarTypeMap:
+		var m map[string]any
+		if err := json.Unmarshal([]byte(s), &m); err == nil {
+			return m
+		}
+	}
+	return s
+}
+
+func compileVariablePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := compileRegex(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("compile pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func toInt(value any) (int64, error) {
+	switch n := value.(type) {
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("want an int, got %q", n)
+		}
+		return i, nil
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, fmt.Errorf("int %d out of range", n)
+		}
+		return int64(n), nil
+	case float64:
+		if n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+			return 0, fmt.Errorf("want an int, got %v", n)
+		}
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	}
+	return 0, fmt.Errorf("want an int, got %s", describeValue(value))
+}
+
+func toBool(value any) (bool, error) {
+	switch b := value.(type) {
+	case bool:
+		return b, nil
+	case string:
+		v, err := strconv.ParseBool(strings.TrimSpace(b))
+		if err != nil {
+			return false, fmt.Errorf("want a bool, got %q", b)
+		}
+		return v, nil
+	}
+	return false, fmt.Errorf("want a bool, got %s", describeValue(value))
+}
+
+// encodeJSON returns the JSON of a list or map value, which may also be
+// given as a JSON string.
+func encodeJSON[T []any | map[string]any](value any, typ string) (string, error) {
+	switch v := value.(type) {
+	case T:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("encode %s: %w", typ, err)
+		}
+		return string(b), nil
+	case string:
+		var parsed T
+		if err := json.Unmarshal([]byte(v), &parsed); err != nil || parsed == nil {
+			return "", fmt.Errorf("want a %s, got %q", typ, v)
+		}
+		b, _ := json.Marshal(parsed)
+		return string(b), nil
+	}
+	return "", fmt.Errorf("want a %s, got %s", typ, describeValue(value))
+}
+
+// describeValue names the JSON type of a value for error messages.
+func describeValue(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a bool"
+	case int, int64, uint64, float64, json.Number:
+		return "a number"
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "a map"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// encodeUndeclared returns the stored form of a variable the dialog does not
+// declare: strings as they are, null as empty and anything else as JSON.
+func encodeUndeclared(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// declaredVariable returns the declaration of a session variable in the
+// dialog the session is pinned to.
+func declaredVariable(session *Session, name string) (Variable, bool) {
+	sm := session.Machine()
+	if sm == nil {
+		return Variable{}, false
+	}
+	v, ok := sm.dialog.Variables[name]
+	return v, ok
+}
+
+// encodeVariable returns the stored form of a value the dialog or a hook
+// assigns to a session variable, enforcing the variable's declaration.
+func encodeVariable(session *Session, name string, value any) (string, error) {
+	decl, ok := declaredVariable(session, name)
+	if !ok {
+		return encodeUndeclared(value)
+	}
+	if decl.Readonly {
+		return "", fmt.Errorf("%w: %q", ErrReadonlyVariable, name)
+	}
+	s, err := decl.encode(value)
+	if err != nil {
+		return "", fmt.Errorf("variable %q: %w", name, err)
+	}
+	return s, nil
+}
+
+// assign sets a session variable on behalf of the dialog or a hook.
+func assign(session *Session, name string, value any) error {
+	s, err := encodeVariable(session, name, value)
+	if err != nil {
+		return err
+	}
+	session.SetVariable(name, s)
+	return nil
+}
+
+// InitVariables sets the variables a session starts with, e.g. those passed
+// to StartDialog, converting declared ones to their stored form. Readonly
+// variables may be set this way. The session must be pinned for the dialog's
+// declarations to apply.
+func (s *Session) InitVariables(vars map[string]string) error {
+	for _, name := range sortedKeys(vars) {
+		value := vars[name]
+		if decl, ok := declaredVariable(s, name); ok {
+			enc, err := decl.encode(value)
+			if err != nil {
+				return fmt.Errorf("variable %q: %w", name, err)
+			}
+			value = enc
+		}
+		s.SetVariable(name, value)
+	}
+	return nil
+}
+
+// setDefaults gives every declared variable the session does not have yet
+// its initial value.
+func setDefaults(session *Session, sm *StateMachine) error {
+	vars := session.CopyVariables()
+	for name, decl := range sm.dialog.Variables {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		value, err := decl.initial()
+		if err != nil {
+			return fmt.Errorf("variable %q: %w", name, err)
+		}
+		session.SetVariable(name, value)
+	}
+	return nil
+}
+
+// typedVariables returns the session variables as templates see them:
+// declared variables converted to their type, others as strings.
+func typedVariables(session *Session) map[string]any {
+	vars := session.CopyVariables()
+	out := make(map[string]any, len(vars))
+	for name, value := range vars {
+		if decl, ok := declaredVariable(session, name); ok {
+			out[name] = decl.decode(value)
+		} else {
+			out[name] = value
+		}
+	}
+	return out
+}
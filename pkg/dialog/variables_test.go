@@ -0,0 +1,199 @@
+package dialog
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestVariableEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		decl    Variable
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{name: "string", decl: Variable{}, value: "Ada", want: "Ada"},
+		{name: "string from number", decl: Variable{}, value: 42.0, wantErr: true},
+		{name: "int from string", decl: Variable{Type: VarTypeInt}, value: " 007 ", want: "7"},
+		{name: "int from json", decl: Variable{Type: VarTypeInt}, value: 42.0, want: "42"},
+		{name: "int from yaml", decl: Variable{Type: VarTypeInt}, value: 42, want: "42"},
+		{name: "fractional int", decl: Variable{Type: VarTypeInt}, value: 4.2, wantErr: true},
+		{name: "int from word", decl: Variable{Type: VarTypeInt}, value: "many", wantErr: true},
+		{name: "bool", decl: Variable{Type: VarTypeBool}, value: true, want: "true"},
+		{name: "bool from string", decl: Variable{Type: VarTypeBool}, value: "false", want: "false"},
+		{name: "bool from number", decl: Variable{Type: VarTypeBool}, value: 1.0, wantErr: true},
+		{name: "list", decl: Variable{Type: VarTypeList}, value: []any{"a", 1.0}, want: `["a",1]`},
+		{name: "list from json string", decl: Variable{Type: VarTypeList}, value: `[1, 2]`, want: `[1,2]`},
+		{name: "list from map", decl: Variable{Type: VarTypeList}, value: map[string]any{}, wantErr: true},
+		{name: "map", decl: Variable{Type: VarTypeMap}, value: map[string]any{"name": "Ada"}, want: `{"name":"Ada"}`},
+		{name: "map from null", decl: Variable{Type: VarTypeMap}, value: "null", wantErr: true},
+		{name: "pattern", decl: Variable{Pattern: `\d{4}`}, value: "1234", want: "1234"},
+		{name: "pattern matches whole value", decl: Variable{Pattern: `\d{4}`}, value: "12345", wantErr: true},
+		{name: "pattern on int", decl: Variable{Type: VarTypeInt, Pattern: `[1-5]`}, value: 7.0, wantErr: true},
+		{name: "unknown type", decl: Variable{Type: "float"}, value: "1.5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.decl.encode(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encode(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("encode(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+const typedDialogYAML = `name: typed
+initial_state: menu
+variables:
+  caller: ""
+  count: {type: int, default: 1}
+  vip: {type: bool}
+  tags: {type: list, default: [a, b]}
+  profile: {type: map}
+  account: {type: string, pattern: '\d{4}'}
+  tier: {type: string, default: gold, readonly: true}
+states:
+  menu:
+    on_enter:
+      - type: play_tts
+        params:
+          text: '{{ if gt .Variables.count 0 }}count {{ .Variables.count }}{{ end }} {{ index .Variables.tags 1 }} {{ .Variables.tier }} {{ if .Variables.vip }}vip{{ else }}regular{{ end }}'
+    transitions:
+      - event: speech
+        target: lookup
+        actions:
+          - type: set_variable
+            params: {count: "{{ len .Variables.tags }}"}
+  lookup:
+    on_enter:
+      - type: call_hook
+        params: {url: "https://example.com/lookup"}
+    transitions:
+      - event: hook_result
+        target: done
+      - event: hook_error
+        target: failed
+  done:
+    on_enter:
+      - type: play_tts
+        params: {text: "{{ .Variables.profile.name }} {{ .Variables.count }} {{ .Variables.vip }}"}
+    terminal: true
+  failed:
+    terminal: true
+`
+
+func TestTypedVariables(t *testing.T) {
+	sm := NewStateMachine(parseDialog(t, typedDialogYAML))
+	tests := []struct {
+		name     string
+		scenario string
+	}{
+		{
+			name: "typed hook variables",
+			scenario: `
+start:
+  actions: [{type: play_tts, params: {text: "count 1 b gold regular"}}]
+  variables: {count: "1", vip: "false", tags: '["a","b"]', profile: "{}", account: "", tier: gold}
+steps:
+  - say: "hello"
+    hooks:
+      - response:
+          variables: {count: 5, vip: true, profile: {name: Ada}, caller: "+1555"}
+    expect:
+      state: done
+      actions: [{type: play_tts, params: {text: "Ada 5 true"}}]
+      variables: {count: "5", vip: "true", profile: '{"name":"Ada"}', caller: "+1555"}
+`,
+		},
+		{
+			name: "set_variable converts",
+			scenario: `
+steps:
+  - say: "hello"
+    hooks: [{error: "down"}]
+    expect: {state: failed, variables: {count: "2"}}
+`,
+		},
+	}
+	for _, rejected := range []string{`{tier: silver}`, `{count: "many"}`, `{account: "12"}`, `{vip: "maybe"}`} {
+		tests = append(tests, struct {
+			name     string
+			scenario string
+		}{
+			name: "rejected " + rejected,
+			scenario: `
+steps:
+  - say: "hello"
+    hooks: [{response: {variables: ` + rejected + `}}]
+    expect: {state: failed, variables: {tier: gold, count: "2", account: ""}}
+`,
+		})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sc Scenario
+			if err := yaml.Unmarshal([]byte(tt.scenario), &sc); err != nil {
+				t.Fatalf("parse scenario: %v", err)
+			}
+			if res := RunScenario(t.Context(), sm, sc); !res.Passed() {
+				t.Errorf("failures: %v", res.Failures)
+			}
+		})
+	}
+}
+
+func TestSetVariableEnforcesSchema(t *testing.T) {
+	for _, tt := range []struct {
+		params map[string]string
+		want   error
+	}{
+		{params: map[string]string{"tier": "silver"}, want: ErrReadonlyVariable},
+		{params: map[string]string{"count": "{{ .Event }}"}},
+	} {
+		d := parseDialog(t, typedDialogYAML)
+		menu := d.States["menu"]
+		menu.Transitions[0].Actions = []Action{{Type: "set_variable", Params: tt.params}}
+		d.States["menu"] = menu
+
+		engine := NewEngine(map[string]*StateMachine{d.Name: NewStateMachine(d)}, nil, nil)
+		session := NewSession("s1", d.Name, d.InitialState)
+		if _, err := engine.Start(t.Context(), session); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		_, err := engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "lots"})
+		if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+			t.Errorf("set_variable %v: got %v, want error %v", tt.params, err, tt.want)
+		}
+		if session.GetCurrentState() != "menu" {
+			t.Errorf("state after rejected set_variable = %q, want menu", session.GetCurrentState())
+		}
+	}
+}
+
+func TestSessionInitVariables(t *testing.T) {
+	sm := NewStateMachine(parseDialog(t, typedDialogYAML))
+	session := NewSession("s1", "typed", "menu")
+	session.Pin(sm)
+
+	if err := session.InitVariables(map[string]string{"tier": "platinum", "count": "07", "other": "x"}); err != nil {
+		t.Fatalf("InitVariables: %v", err)
+	}
+	if got := session.CopyVariables(); got["tier"] != "platinum" || got["count"] != "7" || got["other"] != "x" {
+		t.Errorf("variables = %v", got)
+	}
+	if err := session.InitVariables(map[string]string{"vip": "maybe"}); err == nil {
+		t.Error("InitVariables accepted a non-bool for a bool variable")
+	}
+
+	data, _ := json.Marshal(typedVariables(session))
+	if string(data) != `{"count":7,"other":"x","tier":"platinum"}` {
+		t.Errorf("typed variables = %s", data)
+	}
+}
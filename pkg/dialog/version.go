@@ -0,0 +1,79 @@
+package dialog
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// randIntN draws the weighted version split; tests replace it.
+var randIntN = rand.IntN
+
+// compareVersions orders dialog versions such as "1.2" and "1.10" by their
+// dot-separated parts, numerically where both parts are numbers. A leading
+// "v" is ignored and the empty version sorts first.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+}
+
+// latestVersion returns the highest version in byVersion.
+func latestVersion(byVersion map[string]*StateMachine) string {
+	var latest string
+	first := true
+	for v := range byVersion {
+		if first || compareVersions(v, latest) > 0 {
+			latest, first = v, false
+		}
+	}
+	return latest
+}
+
+// pickWeighted draws a version with probability proportional to its weight.
+func pickWeighted(weights map[string]uint32) (string, error) {
+	versions := make([]string, 0, len(weights))
+	var total int
+	for v, w := range weights {
+		versions = append(versions, v)
+		total += int(w)
+	}
+	if total == 0 {
+		return "", fmt.Errorf("version weights must not all be zero")
+	}
+	sortVersions(versions)
+
+	n := randIntN(total)
+	for _, v := range versions {
+		n -= int(weights[v])
+		if n < 0 {
+			return v, nil
+		}
+	}
+	return versions[len(versions)-1], nil
+}
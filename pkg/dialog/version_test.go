@@ -0,0 +1,181 @@
+package dialog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func versionedDialog(version, greeting string) string {
+	return `name: versioned
+version: "` + version + `"
+initial_state: start
+states:
+  start:
+    on_enter:
+      - type: play_tts
+        params:
+          text: "` + greeting + `"
+    transitions:
+      - event: speech
+        target: done
+  done:
+    terminal: true
+`
+}
+
+func writeDialogs(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.2", "1.10", -1},
+		{"2", "1.9", 1},
+		{"v1.1", "1.0", 1},
+		{"1.0", "1.0.1", -1},
+		{"", "0.1", -1},
+		{"1.0-beta", "1.0-rc", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLoaderVersions(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{
+		"v1.yaml":  versionedDialog("1.9", "one"),
+		"v2.yaml":  versionedDialog("1.10", "two"),
+		"old.yaml": versionedDialog("0.1", "old"),
+	})
+	loader := NewLoader(dir)
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	if got := strings.Join(loader.Versions("versioned"), ","); got != "0.1,1.9,1.10" {
+		t.Errorf("versions = %s", got)
+	}
+	sm, ok := loader.Get("versioned")
+	if !ok || sm.Dialog().Version != "1.10" {
+		t.Errorf("default version = %+v", sm)
+	}
+	if sm, ok := loader.GetVersion("versioned", "1.9"); !ok || sm.Dialog().Version != "1.9" {
+		t.Errorf("GetVersion(1.9) = %v, %v", sm, ok)
+	}
+	if _, ok := loader.GetVersion("versioned", "3.0"); ok {
+		t.Error("GetVersion(3.0) found a version")
+	}
+	if n := len(loader.AllVersions()); n != 3 {
+		t.Errorf("AllVersions returned %d machines, want 3", n)
+	}
+}
+
+func TestLoaderDuplicateVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{
+		"a.yaml": versionedDialog("1.0", "a"),
+		"b.yaml": versionedDialog("1.0", "b"),
+	})
+	if _, err := NewLoader(dir).LoadAll(); err == nil {
+		t.Error("expected error for duplicate version")
+	}
+}
+
+func TestLoaderSelect(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{
+		"v1.yaml": versionedDialog("1.0", "one"),
+		"v2.yaml": versionedDialog("2.0", "two"),
+	})
+	loader := NewLoader(dir)
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	defer func(orig func(int) int) { randIntN = orig }(randIntN)
+	weights := map[string]uint32{"1.0": 90, "2.0": 10}
+	for draw, want := range map[int]string{0: "1.0", 89: "1.0", 90: "2.0", 99: "2.0"} {
+		randIntN = func(n int) int {
+			if n != 100 {
+				t.Fatalf("randIntN(%d), want 100", n)
+			}
+			return draw
+		}
+		sm, err := loader.Select("versioned", weights)
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		if got := sm.Dialog().Version; got != want {
+			t.Errorf("draw %d selected %s, want %s", draw, got, want)
+		}
+	}
+
+	if sm, err := loader.Select("versioned", nil); err != nil || sm.Dialog().Version != "2.0" {
+		t.Errorf("Select without weights = %v, %v; want default 2.0", sm, err)
+	}
+	for _, weights := range []map[string]uint32{{"3.0": 1}, {"1.0": 0}} {
+		if _, err := loader.Select("versioned", weights); err == nil {
+			t.Errorf("Select(%v): expected error", weights)
+		}
+	}
+}
+
+func TestSessionPinnedAcrossReload(t *testing.T) {
+	dir := t.TempDir()
+	writeDialogs(t, dir, map[string]string{"dialog.yaml": versionedDialog("1.0", "before")})
+	loader := NewLoader(dir)
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	engine := NewEngineWithSource(loader, nil, nil)
+
+	session := NewSession("s1", "versioned", "start")
+	if _, err := engine.Start(t.Context(), session); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// Replace the dialog with a version lacking the session's next state.
+	writeDialogs(t, dir, map[string]string{"dialog.yaml": `name: versioned
+version: "2.0"
+initial_state: other
+states:
+  other:
+    terminal: true
+`})
+	if _, err := loader.LoadAll(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	res, err := engine.HandleEvent(t.Context(), session, Event{Type: EventSpeech, Data: "hi"})
+	if err != nil {
+		t.Fatalf("HandleEvent after reload: %v", err)
+	}
+	if res.CurrentState != "done" || !res.Terminal {
+		t.Errorf("result = %+v, want terminal done", res)
+	}
+	if session.GetDialogVersion() != "1.0" {
+		t.Errorf("session version = %q, want 1.0", session.GetDialogVersion())
+	}
+
+	fresh := NewSession("s2", "versioned", "other")
+	if _, err := engine.Start(t.Context(), fresh); err != nil {
+		t.Fatalf("Start new session: %v", err)
+	}
+	if fresh.GetDialogVersion() != "2.0" {
+		t.Errorf("new session version = %q, want 2.0", fresh.GetDialogVersion())
+	}
+}
@@ -33,6 +33,7 @@ func NewPublisher(queueMgr queue.Manager, source string, queueRef string) *Publi
 }
 
 // Emit publishes a typed event to the event bus and fans out to local subscribers.
+// A publisher without a queue manager only delivers to local subscribers.
 func (p *Publisher) Emit(ctx context.Context, eventType EventType, sessionID string, data interface{}) error {
 	envelope := Envelope{
 		ID:        xid.New().String(),
@@ -60,6 +61,9 @@ func (p *Publisher) Emit(ctx context.Context, eventType EventType, sessionID str
 	}
 	p.subMu.RUnlock()
 
+	if p.queueMgr == nil {
+		return nil
+	}
 	return p.queueMgr.Publish(ctx, p.queueRef, envelope)
 }
 
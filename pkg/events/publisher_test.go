@@ -62,8 +62,10 @@ func TestEventTypeConstants(t *testing.T) {
 		SpeechPartial, SpeechFinal,
 		DTMFReceived, StateTransition,
 		ActionExecuted, HookResult, HookError,
+		HookCircuitOpened, HookCircuitClosed,
 		TTSStarted, TTSCompleted,
 		SystemError, WebhookTest,
+		DialogReloaded, DialogReloadFailed,
 	}
 
 	seen := make(map[EventType]bool)
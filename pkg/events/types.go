@@ -25,6 +25,12 @@ const (
 	TrackPublished   EventType = "track.published"
 	TrackUnpublished EventType = "track.unpublished"
 	SpeakerChanged   EventType = "speaker.changed"
+
+	HookCircuitOpened EventType = "hook.circuit_opened"
+	HookCircuitClosed EventType = "hook.circuit_closed"
+
+	DialogReloaded     EventType = "dialog.reloaded"
+	DialogReloadFailed EventType = "dialog.reload_failed"
 )
 
 // Envelope is the standard event wrapper published to the event bus.
@@ -82,8 +88,9 @@ type DTMFData struct {
 type StateTransitionData struct {
 	FromState    string `json:"from_state"`
 	ToState      string `json:"to_state"`
-	TriggerEvent string `json:"trigger_event"`
-	DialogName   string `json:"dialog_name"`
+	TriggerEvent  string `json:"trigger_event"`
+	DialogName    string `json:"dialog_name"`
+	DialogVersion string `json:"dialog_version,omitempty"`
 }
 
 // ActionExecutedData is the payload for action.executed events.
@@ -105,10 +112,33 @@ type HookErrorData struct {
 	Error   string `json:"error"`
 }
 
+// HookCircuitData is the payload for hook.circuit_opened and
+// hook.circuit_closed events. HookURL is the hook URL without its query.
+type HookCircuitData struct {
+	HookURL string `json:"hook_url"`
+	// ResetAfterMs is how long an opened circuit fails calls before letting
+	// one through to test the endpoint.
+	ResetAfterMs int64 `json:"reset_after_ms,omitempty"`
+}
+
+// DialogErrorData is the payload for error events raised by a failed dialog
+// step. Handled is set when the dialog's on_error actions ran.
+type DialogErrorData struct {
+	DialogName string `json:"dialog_name"`
+	State      string `json:"state"`
+	Error      string `json:"error"`
+	Handled    bool   `json:"handled"`
+}
+
 // TTSEventData is the payload for tts.started and tts.completed events.
 type TTSEventData struct {
 	Text  string `json:"text"`
 	Voice string `json:"voice,omitempty"`
+	// BargeIn is set when caller speech may interrupt the prompt.
+	BargeIn bool `json:"barge_in,omitempty"`
+	// Interrupted is set on tts.completed when caller speech stopped the
+	// prompt before it finished; it is always false on tts.started.
+	Interrupted bool `json:"interrupted"`
 }
 
 // WebhookTestData is the payload for webhook.test events.
@@ -116,3 +146,31 @@ type WebhookTestData struct {
 	WebhookID string `json:"webhook_id"`
 	Message   string `json:"message"`
 }
+
+// DialogReloadedData is the payload for dialog.reloaded events.
+type DialogReloadedData struct {
+	File          string `json:"file"`
+	DialogName    string `json:"dialog_name,omitempty"`
+	DialogVersion string `json:"dialog_version,omitempty"`
+	// Removed is set when the file was deleted or renamed away and its
+	// dialog version unloaded.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// DialogReloadFailedData is the payload for dialog.reload_failed events. The
+// last good version of the file, if any, stays loaded.
+type DialogReloadFailedData struct {
+	File        string             `json:"file"`
+	Error       string             `json:"error"`
+	Diagnostics []DialogDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// DialogDiagnostic is a dialog analyzer finding.
+type DialogDiagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
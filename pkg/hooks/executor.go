@@ -9,8 +9,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/voicetyped/voicetyped/pkg/circuitbreaker"
 	"github.com/voicetyped/voicetyped/pkg/events"
 	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
 )
@@ -20,11 +23,22 @@ type Executor struct {
 	httpClient     *http.Client
 	publisher      *events.Publisher
 	validateOpts   []urlvalidation.Option
+
+	// tokens caches OAuth2 tokens across sessions; mtls holds the clients
+	// presenting client certificates.
+	tokens *tokenCache
+	mtls   mtlsClients
+
+	// mu guards the circuit breakers per URL and request slots per host.
+	mu        sync.Mutex
+	limits    Limits
+	breakers  map[string]*circuitbreaker.Breaker
+	hostSlots map[string]chan struct{}
 }
 
 // NewExecutor creates a new hook executor.
 func NewExecutor(publisher *events.Publisher, validateOpts ...urlvalidation.Option) *Executor {
-	return &Executor{
+	e := &Executor{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
@@ -35,54 +49,192 @@ func NewExecutor(publisher *events.Publisher, validateOpts ...urlvalidation.Opti
 		},
 		publisher:    publisher,
 		validateOpts: validateOpts,
+		tokens:       newTokenCache(),
 	}
+	e.SetLimits(Limits{})
+	return e
 }
 
-// Execute calls the hook endpoint and returns the response.
+// Defaults for calls that do not set a timeout or retry backoff.
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultRetryBackoff = 200 * time.Millisecond
+	maxRetryBackoff     = 5 * time.Second
+)
+
+// Execute calls the hook endpoint and returns the response. Failed attempts
+// are retried as cfg.Retries allows, and calls to a URL whose circuit is
+// open fail with ErrCircuitOpen.
 func (e *Executor) Execute(ctx context.Context, cfg HookConfig, req HookRequest) (*HookResponse, error) {
 	if err := urlvalidation.ValidateWebhookURL(cfg.URL, e.validateOpts...); err != nil {
 		return nil, fmt.Errorf("hook URL validation: %w", err)
 	}
+	cfg, err := cfg.resolveSecrets()
+	if err != nil {
+		e.emitError(ctx, cfg, req, err)
+		return nil, err
+	}
 
-	body, err := json.Marshal(req)
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	if cfg.AuthType == "hmac" && !hasBody(method) {
+		return nil, errHMACWithoutBody(method)
+	}
+	var body []byte
+	if hasBody(method) {
+		body, err = json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("marshal hook request: %w", err)
+		}
+	} else {
+		cfg.URL = withSessionQuery(cfg.URL, req)
+	}
+
+	backoff := cfg.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	var (
+		status   int
+		respBody []byte
+	)
+	cb := e.breaker(cfg.URL)
+	for attempt := 0; ; attempt++ {
+		done, ok := cb.Allow()
+		if !ok {
+			err = fmt.Errorf("%w: %s", ErrCircuitOpen, Endpoint(cfg.URL))
+			break
+		}
+		var retryable bool
+		status, respBody, retryable, err = e.attempt(ctx, cfg, done, method, body)
+		if err == nil || !retryable || attempt >= cfg.Retries {
+			break
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w (retry cancelled: %w)", err, ctx.Err())
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("marshal hook request: %w", err)
+		e.emitError(ctx, cfg, req, err)
+		return nil, err
+	}
+
+	var hookResp HookResponse
+	if cfg.RawResponse {
+		if len(respBody) > 0 {
+			var v any
+			if err := json.Unmarshal(respBody, &v); err != nil {
+				return nil, fmt.Errorf("unmarshal hook response: %w", err)
+			}
+			hookResp.Data, _ = v.(map[string]any)
+		}
+		hookResp.Raw = respBody
+	} else if err := json.Unmarshal(respBody, &hookResp); err != nil {
+		return nil, fmt.Errorf("unmarshal hook response: %w", err)
+	}
+
+	if e.publisher != nil {
+		_ = e.publisher.Emit(ctx, events.HookResult, req.SessionID, &events.HookResultData{
+			HookURL:    RedactURL(cfg.URL),
+			StatusCode: status,
+			Response:   hookResp.Data,
+		})
+	}
+
+	return &hookResp, nil
+}
+
+func (e *Executor) emitError(ctx context.Context, cfg HookConfig, req HookRequest, err error) {
+	if e.publisher == nil {
+		return
 	}
+	_ = e.publisher.Emit(ctx, events.HookError, req.SessionID, &events.HookErrorData{
+		HookURL: RedactURL(cfg.URL),
+		Error:   err.Error(),
+	})
+}
 
-	timeout := time.Duration(cfg.TimeoutSec) * time.Second
+// attempt makes one request and returns the status and body of a 2xx
+// response. On failure it reports whether the call is worth retrying. Whether
+// the endpoint answered is reported to the circuit breaker through done.
+func (e *Executor) attempt(ctx context.Context, cfg HookConfig, done func(circuitbreaker.Outcome), method string, body []byte) (int, []byte, bool, error) {
+	// Failures before the request is sent, or of the call itself such as
+	// the caller hanging up, say nothing about the endpoint.
+	outcome := circuitbreaker.Abandoned
+	defer func() { done(outcome) }()
+	parent := ctx
+	timeout := cfg.Timeout
 	if timeout <= 0 {
-		timeout = 10 * time.Second
+		timeout = time.Duration(cfg.TimeoutSec) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
 	}
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, cfg.URL, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("create hook request: %w", err)
+		return 0, nil, false, fmt.Errorf("create hook request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
+	client := e.httpClient
 	switch cfg.AuthType {
 	case "bearer":
 		httpReq.Header.Set("Authorization", "Bearer "+cfg.AuthSecret)
 	case "hmac":
 		sig := hmacSign(cfg.AuthSecret, body)
 		httpReq.Header.Set("X-Hook-Signature", sig)
+	case "oauth2":
+		token, retryable, err := e.token(ctx, cfg.OAuth2)
+		if err != nil {
+			return 0, nil, retryable, fmt.Errorf("hook auth: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	case "mtls":
+		if client, err = e.mtls.client(e.httpClient, cfg.TLS); err != nil {
+			return 0, nil, false, fmt.Errorf("hook auth: %w", err)
+		}
 	}
 
 	for k, v := range cfg.Headers {
 		httpReq.Header.Set(k, v)
 	}
 
-	resp, err := e.httpClient.Do(httpReq)
+	release, err := e.acquireHost(ctx, httpReq.URL.Host)
 	if err != nil {
-		if e.publisher != nil {
-			_ = e.publisher.Emit(ctx, events.HookError, req.SessionID, &events.HookErrorData{
-				HookURL: cfg.URL,
-				Error:   err.Error(),
-			})
+		return 0, nil, true, fmt.Errorf("hook host %s at concurrency limit: %w", httpReq.URL.Host, err)
+	}
+	defer release()
+
+	recordFailure := func() {
+		if parent.Err() == nil {
+			outcome = circuitbreaker.Failure
 		}
-		return nil, fmt.Errorf("hook request failed: %w", err)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		recordFailure()
+		// The error quotes the URL, query tokens included.
+		if ue, ok := err.(*url.Error); ok {
+			ue.URL = Endpoint(ue.URL)
+		}
+		return 0, nil, true, fmt.Errorf("hook request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -90,34 +242,51 @@ func (e *Executor) Execute(ctx context.Context, cfg HookConfig, req HookRequest)
 	// Drain remainder for connection reuse.
 	io.Copy(io.Discard, resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read hook response: %w", err)
+		recordFailure()
+		return 0, nil, true, fmt.Errorf("read hook response: %w", err)
+	}
+	if resp.StatusCode >= 500 {
+		recordFailure()
+	} else {
+		outcome = circuitbreaker.Success
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		errMsg := fmt.Sprintf("hook returned HTTP %d: %s", resp.StatusCode, string(respBody))
-		if e.publisher != nil {
-			_ = e.publisher.Emit(ctx, events.HookError, req.SessionID, &events.HookErrorData{
-				HookURL: cfg.URL,
-				Error:   errMsg,
-			})
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if resp.StatusCode == http.StatusUnauthorized && cfg.AuthType == "oauth2" {
+			// The token may have been revoked; a retry fetches a new one.
+			e.tokens.invalidate(cfg.OAuth2)
+			retryable = true
 		}
-		return nil, fmt.Errorf("%s", errMsg)
+		return resp.StatusCode, nil, retryable, fmt.Errorf("hook returned HTTP %d: %s", resp.StatusCode, string(respBody))
 	}
+	return resp.StatusCode, respBody, false, nil
+}
 
-	var hookResp HookResponse
-	if err := json.Unmarshal(respBody, &hookResp); err != nil {
-		return nil, fmt.Errorf("unmarshal hook response: %w", err)
-	}
+// hasBody reports whether requests with method carry the hook request as
+// their body. GET, HEAD and DELETE carry no payload; their URL does.
+func hasBody(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete
+}
 
-	if e.publisher != nil {
-		_ = e.publisher.Emit(ctx, events.HookResult, req.SessionID, &events.HookResultData{
-			HookURL:    cfg.URL,
-			StatusCode: resp.StatusCode,
-			Response:   hookResp.Data,
-		})
+// withSessionQuery adds the session ID and state of req to the query of a
+// hook URL, for requests that cannot carry the hook request as their body.
+func withSessionQuery(rawURL string, req HookRequest) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
 	}
+	q := u.Query()
+	q.Set("session_id", req.SessionID)
+	q.Set("state", req.State)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
 
-	return &hookResp, nil
+// errHMACWithoutBody rejects hmac auth for a method without a body: the
+// signature of an empty body would be the same on every request.
+func errHMACWithoutBody(method string) error {
+	return fmt.Errorf("auth_type hmac signs the request body, which %s requests do not have", method)
 }
 
 func hmacSign(secret string, payload []byte) string {
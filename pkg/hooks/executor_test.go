@@ -2,9 +2,12 @@ package hooks
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
 )
@@ -25,7 +28,7 @@ func TestExecutorSuccess(t *testing.T) {
 		}
 
 		resp := HookResponse{
-			Variables: map[string]string{"intent": "greeting"},
+			Variables: map[string]any{"intent": "greeting"},
 			Data:      map[string]any{"confidence": 0.95},
 		}
 		json.NewEncoder(w).Encode(resp)
@@ -95,3 +98,150 @@ func TestExecutorHTTPError(t *testing.T) {
 		t.Error("expected error for HTTP 500")
 	}
 }
+
+func TestExecutorMethodAndHeaders(t *testing.T) {
+	var gotMethod, gotTenant, gotQuery string
+	var gotBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotTenant = r.Header.Get("X-Tenant")
+		gotQuery = r.URL.RawQuery
+		gotBody, _ = io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(HookResponse{})
+	}))
+	defer ts.Close()
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	cfg := HookConfig{
+		URL:     ts.URL + "?account=42",
+		Method:  http.MethodGet,
+		Headers: map[string]string{"X-Tenant": "acme"},
+	}
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1", State: "lookup"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if gotMethod != http.MethodGet {
+		t.Errorf("method = %q, want GET", gotMethod)
+	}
+	if gotTenant != "acme" {
+		t.Errorf("X-Tenant = %q, want %q", gotTenant, "acme")
+	}
+	if len(gotBody) != 0 {
+		t.Errorf("GET body = %q, want none", gotBody)
+	}
+	if want := "account=42&session_id=s1&state=lookup"; gotQuery != want {
+		t.Errorf("GET query = %q, want %q", gotQuery, want)
+	}
+}
+
+func TestExecutorRejectsHMACWithoutBody(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(HookResponse{})
+	}))
+	defer ts.Close()
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		cfg := HookConfig{URL: ts.URL, Method: method, AuthType: "hmac", AuthSecret: "s3cret"}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate %s with hmac: want error", method)
+		}
+		if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err == nil || !strings.Contains(err.Error(), "hmac") {
+			t.Errorf("Execute %s with hmac: error = %v, want the hmac error", method, err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("endpoint called %d times, want never", calls)
+	}
+
+	// POST bodies are signed.
+	cfg := HookConfig{URL: ts.URL, AuthType: "hmac", AuthSecret: "s3cret"}
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err != nil {
+		t.Errorf("Execute POST with hmac: %v", err)
+	}
+}
+
+func TestExecutorRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		retries   int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "recovers", statuses: []int{503, 502, 200}, retries: 2, wantCalls: 3},
+		{name: "exhausted", statuses: []int{503, 503, 503}, retries: 1, wantCalls: 2, wantErr: true},
+		{name: "rate limited", statuses: []int{429, 200}, retries: 1, wantCalls: 2},
+		{name: "client error", statuses: []int{400, 200}, retries: 3, wantCalls: 1, wantErr: true},
+		{name: "no retries", statuses: []int{500, 200}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(calls, len(tt.statuses)-1)]
+				calls++
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(HookResponse{})
+			}))
+			defer ts.Close()
+
+			exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+			cfg := HookConfig{URL: ts.URL, Retries: tt.retries, RetryBackoff: time.Millisecond}
+			_, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Execute error = %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestExecutorTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	cfg := HookConfig{URL: ts.URL, Timeout: 20 * time.Millisecond}
+	start := time.Now()
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Execute took %v, want about the 20ms timeout", elapsed)
+	}
+}
+
+func TestExecutorRawResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"customer":{"name":"Alice"},"next_state":"ignored"}`))
+	}))
+	defer ts.Close()
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	resp, err := exec.Execute(t.Context(), HookConfig{URL: ts.URL, RawResponse: true}, HookRequest{SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if resp.NextState != "" {
+		t.Errorf("next_state = %q, want raw response left undecoded", resp.NextState)
+	}
+	if string(resp.Raw) != `{"customer":{"name":"Alice"},"next_state":"ignored"}` {
+		t.Errorf("Raw = %s", resp.Raw)
+	}
+	if _, ok := resp.Data["customer"]; !ok {
+		t.Errorf("Data = %v, want the response object", resp.Data)
+	}
+}
@@ -0,0 +1,146 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/circuitbreaker"
+	"github.com/voicetyped/voicetyped/pkg/events"
+)
+
+// Defaults for Limits fields left zero.
+const (
+	DefaultFailureThreshold     = 5
+	DefaultResetTimeout         = 30 * time.Second
+	DefaultMaxConcurrentPerHost = 20
+)
+
+// maxEndpoints bounds the circuit breakers and host slots an Executor keeps.
+const maxEndpoints = 10000
+
+// ErrCircuitOpen is returned for calls to a hook URL whose circuit breaker is
+// open.
+var ErrCircuitOpen = errors.New("hook circuit open")
+
+// Limits protects hook endpoints, and the calls waiting on them, from each
+// other.
+type Limits struct {
+	// FailureThreshold is how many consecutive attempts to a URL failing
+	// with a network error, timeout or HTTP 5xx open its circuit. Calls to
+	// it then fail at once with ErrCircuitOpen until ResetTimeout has
+	// passed. Then a single trial call is let through while the others keep
+	// failing: success closes the circuit, failure opens it again, and a
+	// trial that never reached the endpoint lets the next call try.
+	FailureThreshold int
+	ResetTimeout     time.Duration
+	// MaxConcurrentPerHost bounds the requests in flight to one host. A call
+	// over the limit waits for a slot within its timeout.
+	MaxConcurrentPerHost int
+}
+
+// SetLimits replaces the executor's limits. Zero fields take the defaults.
+// It must be called before the executor is used.
+func (e *Executor) SetLimits(l Limits) {
+	if l.FailureThreshold <= 0 {
+		l.FailureThreshold = DefaultFailureThreshold
+	}
+	if l.ResetTimeout <= 0 {
+		l.ResetTimeout = DefaultResetTimeout
+	}
+	if l.MaxConcurrentPerHost <= 0 {
+		l.MaxConcurrentPerHost = DefaultMaxConcurrentPerHost
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.limits = l
+	e.breakers = make(map[string]*circuitbreaker.Breaker)
+	e.hostSlots = make(map[string]chan struct{})
+}
+
+// Endpoint is the URL a circuit breaker guards: the hook URL without its
+// query, fragment and credentials. It is also how a hook URL is shown where
+// secrets must not appear.
+func Endpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// breaker returns the circuit breaker of a hook URL.
+func (e *Executor) breaker(rawURL string) *circuitbreaker.Breaker {
+	key := Endpoint(rawURL)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if cb, ok := e.breakers[key]; ok {
+		return cb
+	}
+
+	// Evict an arbitrary entry if at capacity.
+	if len(e.breakers) >= maxEndpoints {
+		for k := range e.breakers {
+			delete(e.breakers, k)
+			break
+		}
+	}
+
+	resetTimeout := e.limits.ResetTimeout
+	cb := circuitbreaker.New(circuitbreaker.Config{
+		FailureThreshold:    e.limits.FailureThreshold,
+		ResetTimeout:        resetTimeout,
+		HalfOpenMaxAttempts: 1,
+		OnStateChange: func(from, to string) {
+			switch {
+			case from == circuitbreaker.StateClosed && to == circuitbreaker.StateOpen:
+				e.emitCircuit(events.HookCircuitOpened, &events.HookCircuitData{
+					HookURL:      key,
+					ResetAfterMs: resetTimeout.Milliseconds(),
+				})
+			case to == circuitbreaker.StateClosed:
+				e.emitCircuit(events.HookCircuitClosed, &events.HookCircuitData{HookURL: key})
+			}
+		},
+	})
+	e.breakers[key] = cb
+	return cb
+}
+
+// emitCircuit publishes a circuit event. It belongs to no session, and
+// outlives the call whose attempt changed the circuit's state.
+func (e *Executor) emitCircuit(eventType events.EventType, data *events.HookCircuitData) {
+	if e.publisher == nil {
+		return
+	}
+	_ = e.publisher.Emit(context.Background(), eventType, "", data)
+}
+
+// acquireHost waits for a request slot of host and returns the function
+// releasing it.
+func (e *Executor) acquireHost(ctx context.Context, host string) (func(), error) {
+	e.mu.Lock()
+	slots, ok := e.hostSlots[host]
+	if !ok {
+		if len(e.hostSlots) >= maxEndpoints {
+			for k := range e.hostSlots {
+				delete(e.hostSlots, k)
+				break
+			}
+		}
+		slots = make(chan struct{}, e.limits.MaxConcurrentPerHost)
+		e.hostSlots[host] = slots
+	}
+	e.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
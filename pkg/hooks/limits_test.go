@@ -0,0 +1,207 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/events"
+	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
+)
+
+func TestExecutorCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	pub := events.NewPublisher(nil, "dialog", "")
+	envelopes := pub.Subscribe("test", 64)
+	defer pub.Unsubscribe("test")
+	nextCircuit := func(want events.EventType) events.HookCircuitData {
+		t.Helper()
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case env := <-envelopes:
+				if env.Type != events.HookCircuitOpened && env.Type != events.HookCircuitClosed {
+					continue
+				}
+				if env.Type != want {
+					t.Fatalf("event %s, want %s", env.Type, want)
+				}
+				var data events.HookCircuitData
+				if err := json.Unmarshal(env.Data, &data); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				return data
+			case <-timeout:
+				t.Fatalf("no %s event", want)
+			}
+		}
+	}
+
+	exec := NewExecutor(pub, urlvalidation.AllowPrivateIPs())
+	exec.SetLimits(Limits{FailureThreshold: 2, ResetTimeout: 50 * time.Millisecond})
+	cfg := HookConfig{URL: ts.URL + "/lookup?account=42"}
+
+	for range 2 {
+		if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Execute error = %v, want the HTTP 503", err)
+		}
+	}
+	opened := nextCircuit(events.HookCircuitOpened)
+	if opened.HookURL != ts.URL+"/lookup" || opened.ResetAfterMs != 50 {
+		t.Errorf("circuit_opened = %+v", opened)
+	}
+
+	// The open circuit fails calls without reaching the endpoint, whatever
+	// their query.
+	cfg.URL = ts.URL + "/lookup?account=43"
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s2"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Execute error = %v, want ErrCircuitOpen", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+
+	// Other URLs have their own circuit.
+	if _, err := exec.Execute(t.Context(), HookConfig{URL: ts.URL + "/other"}, HookRequest{SessionID: "s3"}); errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Execute error = %v, want the other URL called", err)
+	}
+
+	// After the reset timeout a successful call closes the circuit.
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s4"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if closed := nextCircuit(events.HookCircuitClosed); closed.HookURL != ts.URL+"/lookup" {
+		t.Errorf("circuit_closed = %+v", closed)
+	}
+}
+
+func TestExecutorClientErrorsKeepCircuitClosed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	exec.SetLimits(Limits{FailureThreshold: 1})
+	for range 3 {
+		if _, err := exec.Execute(t.Context(), HookConfig{URL: ts.URL}, HookRequest{SessionID: "s1"}); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Execute error = %v, want the HTTP 404", err)
+		}
+	}
+}
+
+func TestExecutorHostConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	exec.SetLimits(Limits{MaxConcurrentPerHost: 2})
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Go(func() {
+			if _, err := exec.Execute(t.Context(), HookConfig{URL: ts.URL}, HookRequest{SessionID: "s1"}); err != nil {
+				t.Errorf("Execute: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrent requests = %d, want 2", p)
+	}
+
+	// A call that gets no slot within its timeout fails.
+	release := make(chan struct{})
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer blocked.Close()
+	defer close(release)
+	exec.SetLimits(Limits{MaxConcurrentPerHost: 1})
+	go exec.Execute(t.Context(), HookConfig{URL: blocked.URL}, HookRequest{SessionID: "s1"})
+	time.Sleep(20 * time.Millisecond)
+	if _, err := exec.Execute(t.Context(), HookConfig{URL: blocked.URL, Timeout: 20 * time.Millisecond}, HookRequest{SessionID: "s2"}); err == nil {
+		t.Error("expected error at the concurrency limit")
+	}
+}
+
+func TestExecutorCircuitTrial(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		entered <- struct{}{}
+		<-release
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	exec.SetLimits(Limits{FailureThreshold: 1, ResetTimeout: 20 * time.Millisecond})
+	cfg := HookConfig{URL: ts.URL}
+	exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"})
+	failing.Store(false)
+	time.Sleep(30 * time.Millisecond)
+
+	// A trial whose call is cancelled leaves the trial to the next call.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := exec.Execute(ctx, cfg, HookRequest{SessionID: "s2"}); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("cancelled trial error = %v, want the cancellation", err)
+	}
+
+	// One trial at a time: other calls fail fast while it is in flight.
+	trialDone := make(chan error, 1)
+	go func() {
+		_, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s3"})
+		trialDone <- err
+	}()
+	<-entered
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s4"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Execute during trial error = %v, want ErrCircuitOpen", err)
+	}
+	close(release)
+	if err := <-trialDone; err != nil {
+		t.Fatalf("trial: %v", err)
+	}
+
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s5"}); err != nil {
+		t.Errorf("Execute after trial: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package hooks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TLSConfig configures auth_type mtls: the client certificate presented to
+// the hook endpoint, and optionally the CA that signed the endpoint's
+// certificate when it is not publicly trusted.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file"  json:"key_file"`
+	CAFile   string `yaml:"ca_file"   json:"ca_file,omitempty"`
+}
+
+func (c *TLSConfig) validate() error {
+	switch {
+	case c == nil:
+		return fmt.Errorf("auth_type mtls requires tls settings")
+	case c.CertFile == "" || c.KeyFile == "":
+		return fmt.Errorf("tls cert_file and key_file are required")
+	}
+	return nil
+}
+
+// key identifies the transport built for a config.
+func (c *TLSConfig) key() string {
+	return strings.Join([]string{c.CertFile, c.KeyFile, c.CAFile}, "\x00")
+}
+
+// stamp identifies the current contents of the config's files by their
+// modification times and sizes, so a rotated file is noticed.
+func (c *TLSConfig) stamp() (string, error) {
+	var b strings.Builder
+	for _, path := range []string{c.CertFile, c.KeyFile, c.CAFile} {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%d/%d;", fi.ModTime().UnixNano(), fi.Size())
+	}
+	return b.String(), nil
+}
+
+// load reads the client certificate and the CA into a TLS client config.
+func (c *TLSConfig) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %w", err)
+	}
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s holds no PEM certificates", c.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg, nil
+}
+
+// mtlsClients holds one HTTP client per client certificate, each with its own
+// transport, so connections presenting different certificates are never
+// shared. Certificates are read when a hook first uses them, and read again
+// when one of the files changes.
+type mtlsClients struct {
+	mu      sync.Mutex
+	clients map[string]*mtlsClient
+}
+
+type mtlsClient struct {
+	*http.Client
+	stamp string
+}
+
+// client returns the HTTP client presenting cfg's certificate, building
+// it on top of base.
+func (m *mtlsClients) client(base *http.Client, cfg *TLSConfig) (*http.Client, error) {
+	stamp, err := cfg.stamp()
+	if err != nil {
+		return nil, fmt.Errorf("tls files: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.clients[cfg.key()]
+	if ok && old.stamp == stamp {
+		return old.Client, nil
+	}
+
+	tlsCfg, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+	var transport *http.Transport
+	if t, ok := base.Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.TLSClientConfig = tlsCfg
+
+	c := &http.Client{Timeout: base.Timeout, Transport: transport}
+	if m.clients == nil {
+		m.clients = make(map[string]*mtlsClient)
+	}
+	m.clients[cfg.key()] = &mtlsClient{Client: c, stamp: stamp}
+	if ok {
+		// Calls in flight keep their connections; idle ones would present
+		// the old certificate.
+		old.CloseIdleConnections()
+	}
+	return c, nil
+}
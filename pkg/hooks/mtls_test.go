@@ -0,0 +1,149 @@
+package hooks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
+)
+
+// writeClientCert writes a self-signed client certificate for cn and its key
+// to dir.
+func writeClientCert(t *testing.T, dir, cn string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile, cert
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecutorMTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeClientCert(t, dir, "voicetyped")
+
+	var gotCN string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.Write([]byte(`{}`))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", ts.Certificate().Raw)
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	cfg := HookConfig{
+		URL:      ts.URL,
+		AuthType: "mtls",
+		TLS:      &TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: caFile},
+	}
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if gotCN != "voicetyped" {
+		t.Errorf("client certificate CN = %q, want %q", gotCN, "voicetyped")
+	}
+
+	// Without a client certificate the handshake fails.
+	cfg = HookConfig{URL: ts.URL}
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err == nil {
+		t.Error("expected error without a client certificate")
+	}
+
+	cfg = HookConfig{URL: ts.URL, AuthType: "mtls", TLS: &TLSConfig{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}}
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err == nil {
+		t.Error("expected error for a missing certificate file")
+	}
+}
+
+func TestExecutorMTLSRotation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeClientCert(t, dir, "first")
+
+	var gotCN string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.Write([]byte(`{}`))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", ts.Certificate().Raw)
+
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	cfg := HookConfig{
+		URL:      ts.URL,
+		AuthType: "mtls",
+		TLS:      &TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: caFile},
+	}
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if gotCN != "first" {
+		t.Fatalf("client certificate CN = %q, want first", gotCN)
+	}
+
+	// A rotated certificate is presented by the next call.
+	writeClientCert(t, dir, "second")
+	later := time.Now().Add(time.Minute)
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s2"}); err != nil {
+		t.Fatalf("Execute after rotation: %v", err)
+	}
+	if gotCN != "second" {
+		t.Errorf("client certificate CN after rotation = %q, want second", gotCN)
+	}
+}
@@ -0,0 +1,175 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
+)
+
+// tokenRefreshMargin is how long before expiry a cached token is replaced,
+// so a token does not expire while a hook call is in flight.
+const tokenRefreshMargin = 30 * time.Second
+
+// defaultTokenLifetime applies to token responses without expires_in.
+const defaultTokenLifetime = 5 * time.Minute
+
+// OAuth2Config configures auth_type oauth2: an access token obtained with
+// the OAuth2 client credentials grant is sent as a bearer token. The client
+// authenticates to the token endpoint with HTTP basic auth.
+type OAuth2Config struct {
+	TokenURL     string    `yaml:"token_url"     json:"token_url"`
+	ClientID     string    `yaml:"client_id"     json:"client_id"`
+	ClientSecret SecretRef `yaml:"client_secret" json:"client_secret"`
+	Scopes       []string  `yaml:"scopes"        json:"scopes,omitempty"`
+	// Audience is sent as the audience parameter some providers require.
+	Audience string `yaml:"audience" json:"audience,omitempty"`
+}
+
+func (c *OAuth2Config) validate() error {
+	switch {
+	case c == nil:
+		return fmt.Errorf("auth_type oauth2 requires oauth2 settings")
+	case c.TokenURL == "":
+		return fmt.Errorf("oauth2 token_url is required")
+	case c.ClientID == "":
+		return fmt.Errorf("oauth2 client_id is required")
+	case c.ClientSecret.IsZero():
+		return fmt.Errorf("oauth2 client_secret must reference an env or file secret")
+	case c.ClientSecret.Env != "" && c.ClientSecret.File != "":
+		return fmt.Errorf("oauth2 client_secret sets both env and file")
+	}
+	return nil
+}
+
+// key identifies the token a config obtains. Hooks with the same client and
+// scopes share it.
+func (c *OAuth2Config) key() string {
+	return strings.Join([]string{c.TokenURL, c.ClientID, c.Audience, strings.Join(c.Scopes, " ")}, "\x00")
+}
+
+// tokenCache holds client credentials tokens for all sessions of an
+// Executor. Concurrent calls needing the same expired token wait for a
+// single token request.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*cachedToken
+	now    func() time.Time
+}
+
+type cachedToken struct {
+	// mu is held while the token is fetched.
+	mu        sync.Mutex
+	value     string
+	refreshAt time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{tokens: make(map[string]*cachedToken), now: time.Now}
+}
+
+func (c *tokenCache) entry(key string) *cachedToken {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tokens[key]
+	if !ok {
+		t = &cachedToken{}
+		c.tokens[key] = t
+	}
+	return t
+}
+
+// invalidate drops the cached token, e.g. after the hook rejected it.
+func (c *tokenCache) invalidate(cfg *OAuth2Config) {
+	t := c.entry(cfg.key())
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.value = ""
+}
+
+// token returns a cached token for cfg, requesting a new one when there is
+// none or it is about to expire. It reports whether a failure is worth
+// retrying.
+func (e *Executor) token(ctx context.Context, cfg *OAuth2Config) (string, bool, error) {
+	t := e.tokens.entry(cfg.key())
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && e.tokens.now().Before(t.refreshAt) {
+		return t.value, false, nil
+	}
+
+	value, lifetime, retryable, err := e.requestToken(ctx, cfg)
+	if err != nil {
+		return "", retryable, err
+	}
+	t.value = value
+	t.refreshAt = e.tokens.now().Add(max(lifetime-tokenRefreshMargin, lifetime/2))
+	return value, false, nil
+}
+
+// requestToken performs the client credentials grant.
+func (e *Executor) requestToken(ctx context.Context, cfg *OAuth2Config) (string, time.Duration, bool, error) {
+	if err := urlvalidation.ValidateWebhookURL(cfg.TokenURL, e.validateOpts...); err != nil {
+		return "", 0, false, fmt.Errorf("oauth2 token URL validation: %w", err)
+	}
+	secret, err := cfg.ClientSecret.Resolve()
+	if err != nil {
+		return "", 0, false, fmt.Errorf("oauth2 client_secret: %w", err)
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	if cfg.Audience != "" {
+		form.Set("audience", cfg.Audience)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, false, fmt.Errorf("create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(secret))
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return "", 0, true, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, true, fmt.Errorf("read token response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return "", 0, retryable, fmt.Errorf("token endpoint returned HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", 0, false, fmt.Errorf("unmarshal token response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", 0, false, fmt.Errorf("token response has no access_token")
+	}
+	if tok.TokenType != "" && !strings.EqualFold(tok.TokenType, "bearer") {
+		return "", 0, false, fmt.Errorf("unsupported token_type %q", tok.TokenType)
+	}
+	lifetime := defaultTokenLifetime
+	if tok.ExpiresIn > 0 {
+		lifetime = time.Duration(tok.ExpiresIn) * time.Second
+	}
+	return tok.AccessToken, lifetime, false, nil
+}
@@ -0,0 +1,150 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
+)
+
+// fakeIdP is a token endpoint issuing numbered tokens.
+type fakeIdP struct {
+	mu     sync.Mutex
+	issued int
+	form   map[string]string
+	user   string
+	pass   string
+}
+
+func (p *fakeIdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r.ParseForm()
+	p.user, p.pass, _ = r.BasicAuth()
+	p.form = map[string]string{}
+	for k := range r.PostForm {
+		p.form[k] = r.PostForm.Get(k)
+	}
+	p.issued++
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": fmt.Sprintf("token-%d", p.issued),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func TestExecutorOAuth2(t *testing.T) {
+	idp := &fakeIdP{}
+	tokenServer := httptest.NewServer(idp)
+	defer tokenServer.Close()
+
+	var mu sync.Mutex
+	var gotAuth []string
+	reject := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+		if reject {
+			reject = false
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	t.Setenv("HOOK_TEST_CLIENT_SECRET", "s3cret")
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	now := time.Now()
+	exec.tokens.now = func() time.Time { return now }
+
+	cfg := HookConfig{
+		URL:          ts.URL,
+		AuthType:     "oauth2",
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+		OAuth2: &OAuth2Config{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "voicetyped",
+			ClientSecret: SecretRef{Env: "HOOK_TEST_CLIENT_SECRET"},
+			Scopes:       []string{"crm.read", "crm.write"},
+		},
+	}
+	call := func(session string) {
+		t.Helper()
+		if _, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: session}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+
+	// Sessions share the cached token.
+	call("s1")
+	call("s2")
+	if idp.issued != 1 {
+		t.Errorf("tokens issued = %d, want 1 shared by both sessions", idp.issued)
+	}
+	if idp.user != "voicetyped" || idp.pass != "s3cret" {
+		t.Errorf("token request basic auth = %q:%q", idp.user, idp.pass)
+	}
+	if idp.form["grant_type"] != "client_credentials" || idp.form["scope"] != "crm.read crm.write" {
+		t.Errorf("token request form = %v", idp.form)
+	}
+
+	// The token is replaced shortly before it expires.
+	now = now.Add(time.Hour - tokenRefreshMargin + time.Second)
+	call("s3")
+	if idp.issued != 2 {
+		t.Errorf("tokens issued = %d, want a refresh before expiry", idp.issued)
+	}
+
+	// A rejected token is dropped and the call retried with a new one.
+	reject = true
+	call("s4")
+	if idp.issued != 3 {
+		t.Errorf("tokens issued = %d, want a new token after 401", idp.issued)
+	}
+
+	want := []string{"Bearer token-1", "Bearer token-1", "Bearer token-2", "Bearer token-2", "Bearer token-3"}
+	if strings.Join(gotAuth, ",") != strings.Join(want, ",") {
+		t.Errorf("Authorization headers = %v, want %v", gotAuth, want)
+	}
+}
+
+func TestExecutorOAuth2TokenError(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer tokenServer.Close()
+	called := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer ts.Close()
+
+	t.Setenv("HOOK_TEST_CLIENT_SECRET", "wrong")
+	exec := NewExecutor(nil, urlvalidation.AllowPrivateIPs())
+	cfg := HookConfig{
+		URL:      ts.URL,
+		AuthType: "oauth2",
+		OAuth2: &OAuth2Config{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "voicetyped",
+			ClientSecret: SecretRef{Env: "HOOK_TEST_CLIENT_SECRET"},
+		},
+	}
+	_, err := exec.Execute(t.Context(), cfg, HookRequest{SessionID: "s1"})
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Execute error = %v, want the token endpoint error", err)
+	}
+	if called {
+		t.Error("hook called without a token")
+	}
+}
@@ -0,0 +1,184 @@
+package hooks
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxRetries bounds HookConfig.Retries.
+const MaxRetries = 5
+
+// methods are the HTTP methods a hook may be called with.
+var methods = map[string]bool{
+	"GET":    true,
+	"POST":   true,
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+}
+
+// SecretRef names where a secret is read from when a hook is called: an
+// environment variable or a file, such as a mounted Kubernetes or Docker
+// secret. Secrets referenced this way never reach dialog definitions,
+// session variables or events.
+type SecretRef struct {
+	Env  string `yaml:"env"  json:"env,omitempty"`
+	File string `yaml:"file" json:"file,omitempty"`
+}
+
+// IsZero reports whether the reference names no secret.
+func (r SecretRef) IsZero() bool {
+	return r.Env == "" && r.File == ""
+}
+
+// Resolve reads the secret. Trailing newlines of a secret file are dropped.
+func (r SecretRef) Resolve() (string, error) {
+	switch {
+	case r.Env != "" && r.File != "":
+		return "", fmt.Errorf("secret sets both env and file")
+	case r.Env != "":
+		v, ok := os.LookupEnv(r.Env)
+		if !ok {
+			return "", fmt.Errorf("secret env %s is not set", r.Env)
+		}
+		return v, nil
+	case r.File != "":
+		data, err := os.ReadFile(r.File)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return "", nil
+}
+
+// Validate checks the settings of a hook call. The URL is checked when the
+// hook is called.
+func (c HookConfig) Validate() error {
+	if c.Method != "" && !methods[c.Method] {
+		return fmt.Errorf("unsupported method %q", c.Method)
+	}
+	switch c.AuthType {
+	case "", "none", "bearer", "hmac":
+	case "oauth2":
+		if err := c.OAuth2.validate(); err != nil {
+			return err
+		}
+	case "mtls":
+		if err := c.TLS.validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown auth_type %q", c.AuthType)
+	}
+	if c.AuthType == "hmac" && !hasBody(c.Method) {
+		return errHMACWithoutBody(c.Method)
+	}
+	if c.Retries < 0 || c.Retries > MaxRetries {
+		return fmt.Errorf("retries must be 0 to %d, got %d", MaxRetries, c.Retries)
+	}
+	if c.Timeout < 0 || c.RetryBackoff < 0 || c.TimeoutSec < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+	if c.Secret.Env != "" && c.Secret.File != "" {
+		return fmt.Errorf("secret sets both env and file")
+	}
+	for name, ref := range c.SecretHeaders {
+		if ref.IsZero() || (ref.Env != "" && ref.File != "") {
+			return fmt.Errorf("secret_headers %q must set one of env and file", name)
+		}
+	}
+	return nil
+}
+
+// ValidateDefinition checks a named hook definition. Besides the checks of
+// Validate, a definition needs a URL and must reference its secret rather
+// than inline it.
+func (c HookConfig) ValidateDefinition() error {
+	if c.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.AuthSecret != "" {
+		return fmt.Errorf("auth_secret is inline; reference it with secret: {env: ...} or secret: {file: ...}")
+	}
+	return c.Validate()
+}
+
+// resolveSecrets returns cfg with its secret references read into AuthSecret
+// and Headers.
+func (c HookConfig) resolveSecrets() (HookConfig, error) {
+	if !c.Secret.IsZero() {
+		secret, err := c.Secret.Resolve()
+		if err != nil {
+			return c, fmt.Errorf("hook auth: %w", err)
+		}
+		c.AuthSecret = secret
+	}
+	if len(c.SecretHeaders) == 0 {
+		return c, nil
+	}
+	headers := make(map[string]string, len(c.Headers)+len(c.SecretHeaders))
+	for k, v := range c.Headers {
+		headers[k] = v
+	}
+	for k, ref := range c.SecretHeaders {
+		v, err := ref.Resolve()
+		if err != nil {
+			return c, fmt.Errorf("hook header %s: %w", k, err)
+		}
+		headers[k] = v
+	}
+	c.Headers = headers
+	return c, nil
+}
+
+// Registry holds named hook definitions, which call_hook actions reference
+// by name instead of spelling out the URL and credentials.
+type Registry map[string]HookConfig
+
+// LoadRegistry reads hook definitions from a YAML file with a top-level hooks
+// mapping from names to definitions:
+//
+//	hooks:
+//	  crm:
+//	    url: https://crm.example.com/lookup
+//	    auth_type: bearer
+//	    secret: {env: CRM_TOKEN}
+func LoadRegistry(path string) (Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Hooks Registry `yaml:"hooks"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse hooks %q: %w", path, err)
+	}
+	for name, def := range file.Hooks {
+		if err := def.ValidateDefinition(); err != nil {
+			return nil, fmt.Errorf("hooks %q: hook %q: %w", path, name, err)
+		}
+		// Client certificates are read again when they change, but must be
+		// usable from the start.
+		if def.AuthType == "mtls" {
+			if _, err := def.TLS.load(); err != nil {
+				return nil, fmt.Errorf("hooks %q: hook %q: %w", path, name, err)
+			}
+		}
+	}
+	return file.Hooks, nil
+}
+
+// RedactURL returns rawURL with any password replaced, for logs and events.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
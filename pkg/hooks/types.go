@@ -1,12 +1,35 @@
 package hooks
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // HookConfig describes how to call an external hook endpoint.
 type HookConfig struct {
 	URL        string            `yaml:"url"        json:"url"`
-	AuthType   string            `yaml:"auth_type"  json:"auth_type"`   // "bearer", "hmac", "none"
+	Method     string            `yaml:"method"     json:"method,omitempty"` // default POST
+	AuthType   string            `yaml:"auth_type"  json:"auth_type"`   // "bearer", "hmac", "oauth2", "mtls", "none"
 	AuthSecret string            `yaml:"auth_secret" json:"auth_secret"` // token or HMAC key
 	TimeoutSec int               `yaml:"timeout_sec" json:"timeout_sec"`
+	Timeout    time.Duration     `yaml:"timeout"    json:"timeout,omitempty"` // per attempt; overrides TimeoutSec
 	Headers    map[string]string `yaml:"headers"    json:"headers,omitempty"`
+	// Secret, when set, is read at call time into AuthSecret. SecretHeaders
+	// are headers whose values are read the same way.
+	Secret        SecretRef            `yaml:"secret"         json:"secret"`
+	SecretHeaders map[string]SecretRef `yaml:"secret_headers" json:"secret_headers,omitempty"`
+	// OAuth2 and TLS hold the settings of the oauth2 and mtls auth types.
+	OAuth2 *OAuth2Config `yaml:"oauth2" json:"oauth2,omitempty"`
+	TLS    *TLSConfig    `yaml:"tls"    json:"tls,omitempty"`
+	// Retries is how many times a call failing with a network error, HTTP 429
+	// or 5xx is retried, waiting RetryBackoff (default 200ms) before the
+	// first retry and twice as long before each further one.
+	Retries      int           `yaml:"retries"       json:"retries,omitempty"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" json:"retry_backoff,omitempty"`
+	// RawResponse returns the response body as is in HookResponse.Raw, with
+	// Data set when it is a JSON object, instead of decoding it as a
+	// HookResponse. For endpoints that do not speak the hook protocol.
+	RawResponse bool `yaml:"-" json:"-"`
 }
 
 // HookRequest is the payload sent to a hook endpoint.
@@ -15,16 +38,32 @@ type HookRequest struct {
 	State     string            `json:"state"`
 	Event     string            `json:"event"`
 	Variables map[string]string `json:"variables"`
-	Transcript string           `json:"transcript,omitempty"`
-	Digit     string            `json:"digit,omitempty"`
+	Transcript []TranscriptTurn `json:"transcript,omitempty"`
+	Digit     string            `json:"digit,omitempty"` // last DTMF key, if the last event was one
+}
+
+// TranscriptTurn is one entry of the session transcript sent to a hook.
+type TranscriptTurn struct {
+	Kind       string    `json:"kind"` // "caller", "system", "dtmf", "hook", "tool"
+	Text       string    `json:"text,omitempty"`
+	Confidence float32   `json:"confidence,omitempty"`
+	Hook       string    `json:"hook,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Tool       string    `json:"tool,omitempty"`
+	Arguments  string    `json:"arguments,omitempty"`
+	State      string    `json:"state"`
+	Timestamp  time.Time `json:"timestamp"`
 }
 
 // HookResponse is the expected response from a hook endpoint.
 type HookResponse struct {
 	Actions   []HookAction          `json:"actions,omitempty"`
-	Variables map[string]string     `json:"variables,omitempty"`
+	Variables map[string]any        `json:"variables,omitempty"`
 	Data      map[string]any        `json:"data,omitempty"`
 	NextState string                `json:"next_state,omitempty"`
+	// Raw is the response body of a RawResponse call.
+	Raw json.RawMessage `json:"-"`
 }
 
 // HookAction is a directive returned by a hook.
@@ -0,0 +1,169 @@
+// Package llm is a minimal client for OpenAI-compatible chat completion
+// endpoints, used by the dialog engine's llm_turn action.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// DefaultBaseURL is the OpenAI API, used when a client has no base URL.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// Message is one chat message. A tool message answers the call of an
+// assistant message with the ID ToolCallID.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Tool declares a function the model may call.
+type Tool struct {
+	Type     string   `json:"type"` // always "function"
+	Function Function `json:"function"`
+}
+
+// Function describes a callable function. Parameters is a JSON schema object.
+type Function struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model. Arguments is a JSON
+// object encoded as a string.
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// ChatRequest is the body of a chat completion request.
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Tools    []Tool    `json:"tools,omitempty"`
+}
+
+// ChatResponse is the body of a chat completion response.
+type ChatResponse struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+}
+
+// Choice is one completion of a chat response.
+type Choice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// Client calls the chat completions endpoint below a base URL.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithModel sets the model used by requests that name none.
+func WithModel(model string) Option {
+	return func(c *Client) {
+		c.model = model
+	}
+}
+
+// WithHTTPClient sets the HTTP client, e.g. to change the timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// NewClient creates a client for the API at baseURL, e.g.
+// https://api.openai.com/v1 or a local server's /v1. apiKey may be empty for
+// servers that do not require one.
+func NewClient(baseURL, apiKey string, opts ...Option) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Chat sends a chat completion request and returns the first choice's
+// message.
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*Message, error) {
+	if req.Model == "" {
+		req.Model = c.model
+	}
+	if req.Model == "" {
+		return nil, fmt.Errorf("llm: no model configured")
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal chat request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create chat request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("chat request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read chat response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("chat completions returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var chat ChatResponse
+	if err := json.Unmarshal(respBody, &chat); err != nil {
+		return nil, fmt.Errorf("unmarshal chat response: %w", err)
+	}
+	if len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("chat response has no choices")
+	}
+	return &chat.Choices[0].Message, nil
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientChat(t *testing.T) {
+	var got ChatRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"id": "c1", "choices": [{"index": 0, "finish_reason": "tool_calls", "message": {
+			"role": "assistant", "content": null,
+			"tool_calls": [{"id": "t1", "type": "function", "function": {"name": "transfer", "arguments": "{\"dept\":\"billing\"}"}}]
+		}}]}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL+"/v1/", "secret", WithModel("small"))
+	msg, err := c.Chat(t.Context(), ChatRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+		Tools:    []Tool{{Type: "function", Function: Function{Name: "transfer", Parameters: map[string]any{"type": "object"}}}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got.Model != "small" || len(got.Messages) != 1 || len(got.Tools) != 1 {
+		t.Errorf("request = %+v", got)
+	}
+	if msg.Content != "" || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != `{"dept":"billing"}` {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestClientChatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "http error", status: http.StatusTooManyRequests, body: `{"error": "slow down"}`, want: "HTTP 429"},
+		{name: "no choices", status: http.StatusOK, body: `{"choices": []}`, want: "no choices"},
+		{name: "bad json", status: http.StatusOK, body: `{`, want: "unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			_, err := NewClient(ts.URL, "", WithModel("small")).Chat(t.Context(), ChatRequest{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewClient("http://127.0.0.1:1", "").Chat(t.Context(), ChatRequest{}); err == nil || !strings.Contains(err.Error(), "no model") {
+		t.Errorf("err without model = %v", err)
+	}
+}
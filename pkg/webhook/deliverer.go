@@ -13,6 +13,7 @@ import (
 
 	"github.com/pitabwire/frame/workerpool"
 
+	"github.com/voicetyped/voicetyped/pkg/circuitbreaker"
 	"github.com/voicetyped/voicetyped/pkg/events"
 	"github.com/voicetyped/voicetyped/pkg/urlvalidation"
 )
@@ -38,7 +39,7 @@ type Deliverer struct {
 	validateOpts []urlvalidation.Option
 
 	mu       sync.Mutex
-	breakers map[string]*CircuitBreaker
+	breakers map[string]*circuitbreaker.Breaker
 }
 
 // NewDeliverer creates a new webhook deliverer.
@@ -56,11 +57,11 @@ func NewDeliverer(repo *Repository, cfg DelivererConfig, pool workerpool.WorkerP
 		config:       cfg,
 		pool:         pool,
 		validateOpts: validateOpts,
-		breakers:     make(map[string]*CircuitBreaker),
+		breakers:     make(map[string]*circuitbreaker.Breaker),
 	}
 }
 
-func (d *Deliverer) getOrCreateBreaker(webhookID string) *CircuitBreaker {
+func (d *Deliverer) getOrCreateBreaker(webhookID string) *circuitbreaker.Breaker {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -77,7 +78,7 @@ func (d *Deliverer) getOrCreateBreaker(webhookID string) *CircuitBreaker {
 		}
 	}
 
-	cb = NewCircuitBreaker(CircuitBreakerConfig{
+	cb = circuitbreaker.New(circuitbreaker.Config{
 		FailureThreshold:    d.config.CBFailThreshold,
 		ResetTimeout:        time.Duration(d.config.CBResetTimeoutSec) * time.Second,
 		HalfOpenMaxAttempts: 1,
@@ -102,13 +103,15 @@ func (d *Deliverer) deliverWithRetry(ctx context.Context, wh WebhookEndpoint, en
 
 	cb := d.getOrCreateBreaker(wh.ID)
 
-	if !cb.AllowRequest() {
+	done, ok := cb.Allow()
+	if !ok {
 		d.handleFailure(ctx, wh, env, attempt, "circuit open")
 		return
 	}
 
 	body, err := json.Marshal(env)
 	if err != nil {
+		done(circuitbreaker.Abandoned)
 		d.handleFailure(ctx, wh, env, attempt, fmt.Sprintf("marshal: %v", err))
 		return
 	}
@@ -117,6 +120,7 @@ func (d *Deliverer) deliverWithRetry(ctx context.Context, wh WebhookEndpoint, en
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
 	if err != nil {
+		done(circuitbreaker.Abandoned)
 		d.handleFailure(ctx, wh, env, attempt, fmt.Sprintf("create request: %v", err))
 		return
 	}
@@ -139,7 +143,7 @@ func (d *Deliverer) deliverWithRetry(ctx context.Context, wh WebhookEndpoint, en
 	}
 
 	if err != nil {
-		cb.RecordFailure()
+		done(circuitbreaker.Failure)
 		da.Status = "failed"
 		da.Error = err.Error()
 		if err := d.repo.RecordDelivery(ctx, da); err != nil {
@@ -158,7 +162,7 @@ func (d *Deliverer) deliverWithRetry(ctx context.Context, wh WebhookEndpoint, en
 	da.ResponseBody = string(respBody)
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		cb.RecordSuccess()
+		done(circuitbreaker.Success)
 		da.Status = "success"
 		if err := d.repo.RecordDelivery(ctx, da); err != nil {
 			slog.ErrorContext(ctx, "record delivery failed", slog.String("error", err.Error()))
@@ -166,7 +170,7 @@ func (d *Deliverer) deliverWithRetry(ctx context.Context, wh WebhookEndpoint, en
 		return
 	}
 
-	cb.RecordFailure()
+	done(circuitbreaker.Failure)
 	da.Status = "failed"
 	da.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
 	if err := d.repo.RecordDelivery(ctx, da); err != nil {